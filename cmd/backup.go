@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/backup"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"go.uber.org/zap"
+)
+
+// RunBackup asks the running statx instance for a backup through the admin
+// API, saves it and verifies the downloaded archive. The archive does not
+// include config.json; keep its secret_key with the backup, sealed TLS client
+// keys cannot be opened without it.
+func RunBackup(config *pkg.Config, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	output := flags.String("o", fmt.Sprintf("statx-backup-%s.tar.gz", time.Now().Format("20060102150405")), "archive to write")
+	server := flags.String("server", "http://localhost:"+config.ServerPort, "address of the running statx instance")
+	flags.Parse(args)
+
+	req, err := http.NewRequest(http.MethodPost, *server+"/api/admin/backup", nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+config.AdminToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error requesting backup: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("backup failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	f, err := os.OpenFile(*output, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %v", *output, err)
+	}
+
+	_, err = io.Copy(f, resp.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*output)
+		return fmt.Errorf("error writing %s: %v", *output, err)
+	}
+
+	manifest, err := backup.Verify(*output)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s (%d files, created %s)\n", *output, len(manifest.Files), manifest.CreatedAt.Format(time.RFC3339))
+	fmt.Println("The archive does not include secret_key from config.json, keep it with the backup.")
+	return nil
+}
+
+// RunRestore restores the data directories from an archive. statx has to be
+// stopped first, and has to run with the secret_key the backup was taken
+// under.
+func RunRestore(config *pkg.Config, logger *zap.SugaredLogger, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	verifyOnly := flags.Bool("verify", false, "only verify the archive")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: statx restore [-verify] <archive>")
+	}
+	archivePath := flags.Arg(0)
+
+	if *verifyOnly {
+		manifest, err := backup.Verify(archivePath)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s is valid (%d files, created %s)\n", archivePath, len(manifest.Files), manifest.CreatedAt.Format(time.RFC3339))
+		return nil
+	}
+
+	return backup.Restore(archivePath, config, logger)
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/recorder"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"go.uber.org/zap"
+)
+
+// RunBenchWrite measures how fast check results of many monitors can be
+// written, once appending straight to the TSDB and once through the write
+// pipeline, each against a fresh TSDB in a temporary directory. Like in the
+// uptime job every monitor writes from its own goroutine by default; a
+// pipeline writer waits for the commit of its samples, so with few writers
+// most time is spent waiting for the batch interval.
+func RunBenchWrite(config *pkg.Config, args []string) error {
+	flags := flag.NewFlagSet("bench-write", flag.ExitOnError)
+	monitors := flags.Int("monitors", 10000, "number of monitors")
+	rounds := flags.Int("rounds", 10, "checks per monitor")
+	concurrency := flags.Int("concurrency", 0, "concurrent writers, one per monitor when 0")
+	flags.Parse(args)
+	if *concurrency <= 0 {
+		*concurrency = *monitors
+	}
+
+	targets := make([]pkg.Target, *monitors)
+	for i := range targets {
+		targets[i] = pkg.Target{
+			ID:       fmt.Sprintf("bench-%05d", i),
+			Name:     fmt.Sprintf("bench %d", i),
+			Type:     "uptime",
+			URL:      fmt.Sprintf("https://bench-%d.example.com/health", i),
+			Interval: 30 * time.Second,
+			Labels:   map[string]string{"team": fmt.Sprintf("team-%d", i%20)},
+		}
+	}
+
+	fmt.Printf("%d monitors, %d checks each, %d writers\n", *monitors, *rounds, *concurrency)
+	for _, mode := range []string{"direct", "pipeline"} {
+		if err := benchWrite(config, mode, targets, *rounds, *concurrency); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func benchWrite(config *pkg.Config, mode string, targets []pkg.Target, rounds, concurrency int) error {
+	dir, err := os.MkdirTemp("", "statx-bench-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := tsdb.Open(dir, nil, nil, tsdb.DefaultOptions(), nil)
+	if err != nil {
+		return fmt.Errorf("error opening TSDB: %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := zap.NewNop().Sugar()
+	var appendable storage.Appendable = db
+	var pipeline recorder.WritePipeline
+	if mode == "pipeline" {
+		pipeline = recorder.NewWritePipeline(db, config, logger)
+		pipeline.Start(ctx)
+		appendable = pipeline
+	}
+
+	recorderService := recorder.NewRecorderService(appendable, nil, config, &http.Client{}, logger, nil, recorder.NewStatusTracker(), recorder.NewBroadcaster())
+
+	base := time.Now().Add(-time.Duration(rounds) * 30 * time.Second)
+	start := time.Now()
+	for round := 0; round < rounds; round++ {
+		checkedAt := base.Add(time.Duration(round) * 30 * time.Second)
+
+		var wg sync.WaitGroup
+		next := make(chan pkg.Target)
+		errs := make(chan error, concurrency)
+		for w := 0; w < concurrency; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for target := range next {
+					result := pkg.CheckResult{
+						CheckedAt:    checkedAt,
+						StatusCode:   200,
+						ResponseTime: 42,
+						Up:           true,
+						State:        pkg.StateUp,
+					}
+					if err := recorderService.WriteUpTimeRecord(ctx, target, result); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}()
+		}
+		for _, target := range targets {
+			next <- target
+		}
+		close(next)
+		wg.Wait()
+
+		select {
+		case err := <-errs:
+			return err
+		default:
+		}
+	}
+	if pipeline != nil {
+		pipeline.Flush()
+	}
+	elapsed := time.Since(start)
+
+	checks := len(targets) * rounds
+	samplesPerCheck := 4
+	commits := int64(checks)
+	if pipeline != nil {
+		_, commits = pipeline.Stats()
+	}
+
+	fmt.Printf("%-9s %8d checks in %-12s %10.0f checks/s %10.0f samples/s %8d commits\n",
+		mode, checks, elapsed.Round(time.Millisecond),
+		float64(checks)/elapsed.Seconds(), float64(checks*samplesPerCheck)/elapsed.Seconds(), commits)
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+// RunExport streams check history from the running statx instance to a file
+// or stdout.
+func RunExport(config *pkg.Config, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	output := flags.String("o", "-", "file to write, - for stdout")
+	monitors := flags.String("monitor", "", "comma separated monitor IDs, all monitors when empty")
+	format := flags.String("format", "csv", "csv or ndjson")
+	start := flags.String("start", "", "start of the range, RFC3339")
+	end := flags.String("end", "", "end of the range, RFC3339")
+	duration := flags.String("duration", "", "range ending now, e.g. 24h; overrides start and end")
+	server := flags.String("server", "http://localhost:"+config.ServerPort, "address of the running statx instance")
+	flags.Parse(args)
+
+	params := url.Values{}
+	params.Set("format", *format)
+	for name, value := range map[string]string{
+		"monitor_id": *monitors,
+		"start":      *start,
+		"end":        *end,
+		"duration":   *duration,
+	} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+
+	resp, err := http.Get(*server + "/api/export?" + params.Encode())
+	if err != nil {
+		return fmt.Errorf("error requesting export: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("export failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("error creating %s: %v", *output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("error writing export: %v", err)
+	}
+
+	return nil
+}
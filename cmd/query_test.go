@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// promResponse is the envelope of the Prometheus HTTP API.
+type promResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []any             `json:"value"`
+			Values [][]any           `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// query calls a Prometheus compatible endpoint. These are left out of the
+// OpenAPI validation, so their parameters and errors are only checked here.
+func (s *testServer) query(t *testing.T, path string, params url.Values) (int, promResponse) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path+"?"+params.Encode(), nil))
+
+	var resp promResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("GET %s: decoding %q: %v", path, rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+func TestPromQLQueries(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Now().Truncate(time.Second)
+
+	appender := s.tsdb.Appender(context.Background())
+	for i, value := range []float64{1, 0, 1} {
+		ts := now.Add(time.Duration(i-2) * time.Minute).UnixMilli()
+		if _, err := appender.Append(0, labels.FromStrings(labels.MetricName, "up", "monitor_id", "q"), ts, value); err != nil {
+			t.Fatalf("appending: %v", err)
+		}
+	}
+	if err := appender.Commit(); err != nil {
+		t.Fatalf("committing: %v", err)
+	}
+
+	unix := func(t time.Time) string { return fmt.Sprint(t.Unix()) }
+
+	code, resp := s.query(t, "/api/v1/query", url.Values{
+		"query": {`sum_over_time(up{monitor_id="q"}[5m])`},
+		"time":  {unix(now)},
+	})
+	if code != http.StatusOK || resp.Status != "success" || resp.Data.ResultType != "vector" {
+		t.Fatalf("query: %d %+v", code, resp)
+	}
+	if len(resp.Data.Result) != 1 || resp.Data.Result[0].Value[1] != "2" {
+		t.Errorf("query result = %+v, want one series with value 2", resp.Data.Result)
+	}
+
+	code, resp = s.query(t, "/api/v1/query_range", url.Values{
+		"query": {`up{monitor_id="q"}`},
+		"start": {unix(now.Add(-2 * time.Minute))},
+		"end":   {unix(now)},
+		"step":  {"60"},
+	})
+	if code != http.StatusOK || resp.Status != "success" || resp.Data.ResultType != "matrix" {
+		t.Fatalf("query_range: %d %+v", code, resp)
+	}
+	if len(resp.Data.Result) != 1 || len(resp.Data.Result[0].Values) != 3 || resp.Data.Result[0].Metric["monitor_id"] != "q" {
+		t.Errorf("query_range result = %+v, want one series with 3 points", resp.Data.Result)
+	}
+}
+
+func TestPromQLQueryErrors(t *testing.T) {
+	s := newTestServer(t)
+	end := time.Now()
+
+	tests := []struct {
+		name   string
+		path   string
+		params url.Values
+	}{
+		{
+			name:   "query with a bad expression",
+			path:   "/api/v1/query",
+			params: url.Values{"query": {`up{`}},
+		},
+		{
+			name:   "query_range with a bad expression",
+			path:   "/api/v1/query_range",
+			params: url.Values{"query": {`up{`}, "start": {fmt.Sprint(end.Add(-time.Hour).Unix())}, "end": {fmt.Sprint(end.Unix())}, "step": {"60"}},
+		},
+		{
+			name:   "query_range without a step",
+			path:   "/api/v1/query_range",
+			params: url.Values{"query": {`up`}, "start": {fmt.Sprint(end.Add(-time.Hour).Unix())}, "end": {fmt.Sprint(end.Unix())}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := s.query(t, tt.path, tt.params)
+			if code != http.StatusBadRequest || resp.Status != "error" || resp.ErrorType != "bad_data" || resp.Error == "" {
+				t.Errorf("got %d %+v, want 400 with errorType bad_data", code, resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/config_monitor"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/recorder"
+	"github.com/afrianjunior/statx/internal/testutil"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+func TestReceivedSeriesDoNotCountAsChecks(t *testing.T) {
+	ctx := context.Background()
+	config := testutil.Config(t)
+	config.RemoteWriteReceiver.Enabled = true
+	db := testutil.DB(t)
+	storage := testutil.TSDB(t, config)
+	router := NewRest(http.DefaultClient, storage, db, testutil.Logger(), config,
+		recorder.NewStatusTracker(), recorder.NewBroadcaster(), nil).(*rest).setupRouter()
+
+	id, err := config_monitor.NewConfigMonitorRepository(db).Insert(ctx, &pkg.ConfigMonitorDTO{Type: "generic", Name: "pushed"})
+	if err != nil {
+		t.Fatalf("inserting monitor: %v", err)
+	}
+
+	// an hour of successful checks, one a minute
+	now := time.Now()
+	appender := storage.Appender(ctx)
+	for i := 60; i > 0; i-- {
+		lset := labels.FromStrings(labels.MetricName, "up", "monitor_id", id, "type", "generic", "name", "pushed")
+		if _, err := appender.Append(0, lset, now.Add(-time.Duration(i)*time.Minute).UnixMilli(), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := appender.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	uptime := func() pkg.UptimeSummary {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/monitors/"+id+"/uptime?windows=24h", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("uptime: %d %s", rec.Code, rec.Body)
+		}
+		var resp struct {
+			Data []pkg.UptimeSummary `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) != 1 {
+			t.Fatalf("uptime windows = %+v", resp.Data)
+		}
+		return resp.Data[0]
+	}
+	before := uptime()
+
+	// a remote writer pushes an up series of its own for the monitor
+	var samples []prompb.Sample
+	for i := 60; i > 0; i-- {
+		samples = append(samples, prompb.Sample{Timestamp: now.Add(-time.Duration(i)*time.Minute + time.Second).UnixMilli(), Value: 0})
+	}
+	request := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: labels.MetricName, Value: "up"}},
+		Samples: samples,
+	}}}
+	data, err := request.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/write?monitor_id="+id, bytes.NewReader(snappy.Encode(nil, data))))
+	if rec.Code/100 != 2 {
+		t.Fatalf("remote write: %d %s", rec.Code, rec.Body)
+	}
+
+	after := uptime()
+	if before.Availability == nil || *before.Availability != 100 {
+		t.Fatalf("availability before the push = %v, want 100", before.Availability)
+	}
+	if after.Availability == nil || *after.Availability != 100 || after.DowntimeSeconds != 0 || after.Outages != 0 {
+		t.Fatalf("pushed up series changed the uptime: %+v", after)
+	}
+}
@@ -1,11 +1,33 @@
 package cmd
 
 import (
+	"context"
+	"crypto/subtle"
 	"database/sql"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/afrianjunior/statx/internal/backup"
+	"github.com/afrianjunior/statx/internal/badge"
+	"github.com/afrianjunior/statx/internal/check_error"
 	"github.com/afrianjunior/statx/internal/config_monitor"
+	"github.com/afrianjunior/statx/internal/content_change"
+	"github.com/afrianjunior/statx/internal/export"
 	"github.com/afrianjunior/statx/internal/exposer"
+	"github.com/afrianjunior/statx/internal/group"
+	"github.com/afrianjunior/statx/internal/maintenance"
+	"github.com/afrianjunior/statx/internal/metrics"
+	"github.com/afrianjunior/statx/internal/openapi"
+	"github.com/afrianjunior/statx/internal/overview"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/query"
+	"github.com/afrianjunior/statx/internal/recorder"
+	"github.com/afrianjunior/statx/internal/remote_write"
+	"github.com/afrianjunior/statx/internal/stream"
+	"github.com/afrianjunior/statx/internal/tag"
+	"github.com/afrianjunior/statx/internal/tls_config"
 	_ "github.com/glebarez/go-sqlite"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -18,8 +40,11 @@ type APIError struct {
 	Error string `json:"error"`
 }
 
+const shutdownTimeout = 10 * time.Second
+
 type Rest interface {
-	Start(port string)
+	// Start serves the API until ctx ends and open requests are done.
+	Start(ctx context.Context, port string)
 }
 
 type rest struct {
@@ -27,6 +52,10 @@ type rest struct {
 	tsdb       *tsdb.DB
 	db         *sql.DB
 	logger     *zap.SugaredLogger
+	config     *pkg.Config
+	statuses   recorder.StatusTracker
+	events     recorder.Broadcaster
+	monitors   config_monitor.Reconciler
 }
 
 func NewRest(
@@ -34,21 +63,43 @@ func NewRest(
 	tsdb *tsdb.DB,
 	db *sql.DB,
 	logger *zap.SugaredLogger,
+	config *pkg.Config,
+	statuses recorder.StatusTracker,
+	events recorder.Broadcaster,
+	monitors config_monitor.Reconciler,
 ) Rest {
 	return &rest{
 		httpClient: httpClient,
 		tsdb:       tsdb,
 		db:         db,
 		logger:     logger,
+		config:     config,
+		statuses:   statuses,
+		events:     events,
+		monitors:   monitors,
 	}
 }
 
-func (s *rest) Start(port string) {
-	router := s.setupRouter()
+func (s *rest) Start(ctx context.Context, port string) {
+	server := &http.Server{Addr: ":" + port, Handler: s.setupRouter()}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		// event streams never finish on their own, so do not wait forever
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			s.logger.Warnf("Error shutting down server: %v", err)
+		}
+	}()
+
 	s.logger.Infof("Starting server on port %s...", port)
-	if err := http.ListenAndServe(":"+port, router); err != nil {
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
 		s.logger.Fatalf("Server error: %v", err)
 	}
+	<-stopped
 }
 
 func (s *rest) setupRouter() *chi.Mux {
@@ -56,10 +107,29 @@ func (s *rest) setupRouter() *chi.Mux {
 
 	// Repositories
 	configMonitorRepository := config_monitor.NewConfigMonitorRepository(s.db)
+	tlsConfigRepository := tls_config.NewTLSConfigRepository(s.db)
+	contentChangeRepository := content_change.NewContentChangeRepository(s.db)
+	maintenanceRepository := maintenance.NewMaintenanceRepository(s.db)
+	tagRepository := tag.NewTagRepository(s.db)
+	groupRepository := group.NewGroupRepository(s.db)
+	checkErrorRepository := check_error.NewCheckErrorRepository(s.db)
 
 	// Services
-	configMonitorService := config_monitor.NewConfigService(configMonitorRepository)
-	exposerService := exposer.NewExposerService(s.tsdb)
+	configMonitorService := config_monitor.NewConfigService(configMonitorRepository, tlsConfigRepository, groupRepository, s.tsdb, s.config, s.statuses, s.monitors)
+	exposerService := exposer.NewExposerService(s.tsdb, checkErrorRepository)
+	tlsConfigService := tls_config.NewTLSConfigService(tlsConfigRepository, s.config)
+	contentChangeService := content_change.NewContentChangeService(contentChangeRepository)
+	maintenanceService := maintenance.NewMaintenanceService(maintenanceRepository)
+	tagService := tag.NewTagService(tagRepository)
+	groupService := group.NewGroupService(groupRepository, s.statuses)
+	backupService := backup.NewBackupService(s.tsdb, s.db, s.config, s.logger)
+	receiverService := remote_write.NewReceiverService(s.tsdb, configMonitorRepository, s.config)
+	queryService := query.NewQueryService(s.tsdb)
+	exportService := export.NewExportService(s.tsdb, checkErrorRepository)
+	overviewService := overview.NewOverviewService(configMonitorRepository, s.config.Targets, s.tsdb, s.statuses)
+	badgeService := badge.NewBadgeService(configMonitorRepository, exposerService, s.statuses)
+
+	document := openapi.NewDocument()
 
 	// Middleware
 	r.Use(middleware.Logger)
@@ -67,19 +137,117 @@ func (s *rest) setupRouter() *chi.Mux {
 	r.Use(middleware.RealIP)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Content-Type"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
+	r.Use(openapi.Validator(document, r))
+
+	r.Get("/metrics", metrics.Handler(s.statuses))
+
+	r.Route("/badge/{id}", func(r chi.Router) {
+		r.Get("/status.svg", badge.StatusHandler(badgeService))
+		r.Get("/uptime.svg", badge.UptimeHandler(badgeService))
+		r.Get("/latency.svg", badge.LatencyHandler(badgeService))
+	})
 
 	// API Routes
 	r.Route("/api", func(r chi.Router) {
+		r.Get("/openapi.json", openapi.Handler(document))
 		r.Get("/status", exposer.StatusHandler(exposerService))
+		r.Get("/stream", stream.StreamHandler(s.events))
+		r.Get("/export", export.ExportHandler(exportService, s.logger))
+		r.Get("/overview", overview.OverviewHandler(overviewService))
 		r.Post("/configs", config_monitor.MutationHandler(configMonitorService))
 		r.Get("/configs", config_monitor.ListHandler(configMonitorService))
+		r.Get("/configs/{id}", config_monitor.GetHandler(configMonitorService))
+		r.Put("/configs/{id}", config_monitor.UpdateHandler(configMonitorService))
+		r.Patch("/configs/{id}", config_monitor.PatchHandler(configMonitorService))
+		r.Delete("/configs/{id}", config_monitor.DeleteHandler(configMonitorService))
+		r.Post("/tags", tag.CreateHandler(tagService))
+		r.Get("/tags", tag.ListHandler(tagService))
+		r.Put("/tags/{id}", tag.UpdateHandler(tagService))
+		r.Delete("/tags/{id}", tag.DeleteHandler(tagService))
+		r.Post("/groups", group.CreateHandler(groupService))
+		r.Get("/groups", group.ListHandler(groupService))
+		r.Get("/groups/status", group.StatusListHandler(groupService))
+		r.Get("/groups/{id}", group.GetHandler(groupService))
+		r.Put("/groups/{id}", group.UpdateHandler(groupService))
+		r.Delete("/groups/{id}", group.DeleteHandler(groupService))
+		r.Get("/groups/{id}/status", group.StatusHandler(groupService))
+		r.Post("/tls-configs", tls_config.CreateHandler(tlsConfigService))
+		r.Get("/tls-configs", tls_config.ListHandler(tlsConfigService))
+		r.Get("/tls-configs/{id}", tls_config.GetHandler(tlsConfigService))
+		r.Get("/content-changes", content_change.ListHandler(contentChangeService))
+		r.Get("/monitors/{id}/uptime", exposer.UptimeHandler(exposerService, maintenanceService))
+		r.Post("/monitors/{id}/maintenance", maintenance.CreateHandler(maintenanceService))
+		r.Get("/monitors/{id}/maintenance", maintenance.ListHandler(maintenanceService))
+		r.Delete("/monitors/{id}/maintenance/{windowID}", maintenance.DeleteHandler(maintenanceService))
+
+		// Prometheus compatible query API
+		r.Get("/v1/query", query.QueryHandler(queryService))
+		r.Post("/v1/query", query.QueryHandler(queryService))
+		r.Get("/v1/query_range", query.QueryRangeHandler(queryService))
+		r.Post("/v1/query_range", query.QueryRangeHandler(queryService))
+		r.Get("/v1/series", query.SeriesHandler(queryService))
+		r.Post("/v1/series", query.SeriesHandler(queryService))
+		r.Get("/v1/labels", query.LabelNamesHandler(queryService))
+		r.Post("/v1/labels", query.LabelNamesHandler(queryService))
+		r.Get("/v1/label/{name}/values", query.LabelValuesHandler(queryService))
+
+		if s.config.RemoteWriteReceiver.Enabled {
+			r.With(s.requireBearer(s.config.RemoteWriteReceiver.BearerToken)).
+				Post("/v1/write", remote_write.WriteHandler(receiverService))
+		}
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(s.requireAdmin)
+			r.Post("/backup", backup.BackupHandler(backupService))
+		})
 	})
 
+	for _, route := range document.Undocumented(r) {
+		s.logger.Warnf("Route %s is missing from the OpenAPI document", route)
+	}
+
 	return r
 }
+
+// requireAdmin checks the bearer token against Config.AdminToken. Without a
+// configured token the admin routes are disabled.
+func (s *rest) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.config.AdminToken == "" {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: "admin api is disabled, set admin_token in the config",
+				Data:    nil,
+			}, http.StatusForbidden)
+			return
+		}
+
+		s.requireBearer(s.config.AdminToken)(next).ServeHTTP(w, r)
+	})
+}
+
+// requireBearer rejects requests without the given bearer token. An empty
+// token lets everything through.
+func (s *rest) requireBearer(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				pkg.JsonResponse(w, pkg.BaseResponse{
+					Success: false,
+					Message: "unauthorized",
+					Data:    nil,
+				}, http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/afrianjunior/statx/internal/config_monitor"
+	"github.com/afrianjunior/statx/internal/openapi"
+	"github.com/afrianjunior/statx/internal/recorder"
+	"github.com/afrianjunior/statx/internal/testutil"
+)
+
+// get returns the status code of a GET request, for endpoints that do not
+// answer with JSON.
+func (s *testServer) get(path string) int {
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code
+}
+
+func TestBadgeTokenIsWriteOnly(t *testing.T) {
+	s := newTestServer(t)
+
+	code, resp := s.do(t, http.MethodPost, "/api/configs", map[string]any{
+		"name": "badged", "type": "uptime", "url": "http://127.0.0.1:1", "interval": 3600,
+		"badge_enabled": true, "badge_token": "s3cret",
+	})
+	if code != http.StatusOK {
+		t.Fatalf("creating: %d %v", code, resp)
+	}
+	id := resp.Data.(string)
+
+	assertHidden := func(resp map[string]any) {
+		t.Helper()
+		if _, ok := resp["badge_token"]; ok {
+			t.Fatalf("badge_token is returned: %v", resp["badge_token"])
+		}
+		if resp["has_badge_token"] != true {
+			t.Fatalf("has_badge_token = %v, want true", resp["has_badge_token"])
+		}
+	}
+	_, resp = s.do(t, http.MethodGet, "/api/configs/"+id, nil)
+	assertHidden(resp.Data.(map[string]any))
+
+	var stored string
+	if err := s.db.QueryRow(`SELECT badge_token_hash FROM config_monitor WHERE id = ?`, id).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored == "" || stored == "s3cret" {
+		t.Fatalf("stored badge token = %q, want a hash", stored)
+	}
+	var plaintextColumns int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('config_monitor') WHERE name = 'badge_token'`).Scan(&plaintextColumns); err != nil || plaintextColumns != 0 {
+		t.Fatalf("the schema has a plaintext badge_token column: %d, %v", plaintextColumns, err)
+	}
+
+	for token, want := range map[string]int{"s3cret": http.StatusOK, "wrong": http.StatusForbidden, "": http.StatusForbidden} {
+		if got := s.get("/badge/" + id + "/status.svg?token=" + token); got != want {
+			t.Errorf("token %q: got %d, want %d", token, got, want)
+		}
+	}
+
+	// A PUT without badge_token keeps the token, an empty one removes it.
+	code, resp = s.do(t, http.MethodPut, "/api/configs/"+id, map[string]any{
+		"name": "badged", "type": "uptime", "url": "http://127.0.0.1:1", "interval": 3600, "badge_enabled": true,
+	})
+	if code != http.StatusOK {
+		t.Fatalf("updating: %d %v", code, resp)
+	}
+	assertHidden(resp.Data.(map[string]any))
+	if got := s.get("/badge/" + id + "/status.svg?token=wrong"); got != http.StatusForbidden {
+		t.Fatalf("token dropped by an update without badge_token: got %d", got)
+	}
+
+	code, resp = s.do(t, http.MethodPatch, "/api/configs/"+id, map[string]any{"badge_token": ""})
+	if code != http.StatusOK {
+		t.Fatalf("patching: %d %v", code, resp)
+	}
+	if resp.Data.(map[string]any)["has_badge_token"] != false {
+		t.Fatalf("empty badge_token did not remove the token: %v", resp.Data)
+	}
+	if got := s.get("/badge/" + id + "/status.svg"); got != http.StatusOK {
+		t.Fatalf("badge without token: got %d", got)
+	}
+}
+
+func TestUptimeBadgeWindowIsCapped(t *testing.T) {
+	s := newTestServer(t)
+
+	code, resp := s.do(t, http.MethodPost, "/api/configs", map[string]any{
+		"name": "badged", "type": "uptime", "url": "http://127.0.0.1:1", "interval": 3600, "badge_enabled": true,
+	})
+	if code != http.StatusOK {
+		t.Fatalf("creating: %d %v", code, resp)
+	}
+	id := resp.Data.(string)
+
+	for window, want := range map[string]int{"90d": http.StatusOK, "91d": http.StatusUnprocessableEntity, "100w": http.StatusUnprocessableEntity} {
+		if got := s.get("/badge/" + id + "/uptime.svg?window=" + window); got != want {
+			t.Errorf("window %s: got %d, want %d", window, got, want)
+		}
+	}
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	config := testutil.Config(t)
+	config.RemoteWriteReceiver.Enabled = true
+	config.AdminToken = "admin"
+	rest := NewRest(http.DefaultClient, testutil.TSDB(t, config), testutil.DB(t), testutil.Logger(), config,
+		recorder.NewStatusTracker(), recorder.NewBroadcaster(), nil).(*rest)
+
+	if missing := openapi.NewDocument().Undocumented(rest.setupRouter()); len(missing) > 0 {
+		t.Fatalf("routes missing from the OpenAPI document: %v", missing)
+	}
+}
+
+func TestInvalidBodiesGetValidationPayloads(t *testing.T) {
+	s := newTestServer(t)
+
+	code, resp := s.do(t, http.MethodPost, "/api/configs", `{"name":`)
+	if code != http.StatusBadRequest || resp.Success || resp.Message != "invalid req body" {
+		t.Fatalf("malformed JSON: %d %+v", code, resp)
+	}
+
+	code, resp = s.do(t, http.MethodPost, "/api/configs", map[string]any{
+		"name": strings.Repeat("é", config_monitor.MaxNameLength+1), "type": "ping", "max_retry": -1,
+	})
+	if code != http.StatusUnprocessableEntity || resp.Message != "validation failed" {
+		t.Fatalf("invalid monitor: %d %+v", code, resp)
+	}
+	fields := map[string]bool{}
+	for _, fieldErr := range resp.Data.([]any) {
+		fields[fieldErr.(map[string]any)["field"].(string)] = true
+	}
+	for _, field := range []string{"name", "type", "max_retry"} {
+		if !fields[field] {
+			t.Errorf("no error for %s: %v", field, resp.Data)
+		}
+	}
+
+	// the limit counts characters, not bytes
+	code, resp = s.do(t, http.MethodPost, "/api/configs", map[string]any{
+		"name": strings.Repeat("é", config_monitor.MaxNameLength), "type": "generic",
+	})
+	if code != http.StatusOK {
+		t.Fatalf("name of %d two byte characters: %d %+v", config_monitor.MaxNameLength, code, resp)
+	}
+}
+
+func TestPatchNullResetsField(t *testing.T) {
+	s := newTestServer(t)
+
+	code, resp := s.do(t, http.MethodPost, "/api/groups", map[string]any{"name": "team"})
+	if code != http.StatusOK {
+		t.Fatalf("creating group: %d %+v", code, resp)
+	}
+	groupID := resp.Data.(string)
+	code, resp = s.do(t, http.MethodPost, "/api/configs", map[string]any{
+		"name": "grouped", "type": "generic", "group_id": groupID, "labels": map[string]string{"env": "prod"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("creating monitor: %d %+v", code, resp)
+	}
+	id := resp.Data.(string)
+
+	code, resp = s.do(t, http.MethodPatch, "/api/configs/"+id, `{"group_id":null}`)
+	if code != http.StatusOK {
+		t.Fatalf("patching: %d %+v", code, resp)
+	}
+	monitor := resp.Data.(map[string]any)
+	if monitor["group_id"] != "" {
+		t.Errorf("group_id = %v after patching it to null", monitor["group_id"])
+	}
+	if labels, _ := monitor["labels"].(map[string]any); labels["env"] != "prod" {
+		t.Errorf("fields left out of the patch changed: labels = %v", monitor["labels"])
+	}
+}
+
+func TestServiceErrorsMapToStatusCodes(t *testing.T) {
+	s := newTestServer(t)
+
+	if code, resp := s.do(t, http.MethodPost, "/api/tags", map[string]any{"name": "core"}); code != http.StatusOK {
+		t.Fatalf("creating tag: %d %+v", code, resp)
+	}
+	code, resp := s.do(t, http.MethodPost, "/api/tags", map[string]any{"name": "core"})
+	if code != http.StatusConflict {
+		t.Fatalf("duplicate tag: %d %+v", code, resp)
+	}
+	if fieldErrs, _ := resp.Data.([]any); len(fieldErrs) != 1 || fieldErrs[0].(map[string]any)["field"] != "name" {
+		t.Errorf("duplicate tag data = %v, want a name field error", resp.Data)
+	}
+
+	for _, path := range []string{"/api/configs/missing", "/api/groups/missing", "/badge/missing/status.svg"} {
+		if code := s.get(path); code != http.StatusNotFound {
+			t.Errorf("GET %s: got %d, want 404", path, code)
+		}
+	}
+	if code, resp := s.do(t, http.MethodDelete, "/api/tags/missing", nil); code != http.StatusNotFound || resp.Success {
+		t.Errorf("deleting a missing tag: %d %+v", code, resp)
+	}
+}
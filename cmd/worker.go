@@ -4,9 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"net/http"
+	"sync"
 
+	"github.com/afrianjunior/statx/internal/config_monitor"
+	"github.com/afrianjunior/statx/internal/content_change"
 	"github.com/afrianjunior/statx/internal/pkg"
 	"github.com/afrianjunior/statx/internal/recorder"
+	"github.com/afrianjunior/statx/internal/remote_write"
+	"github.com/afrianjunior/statx/internal/tls_config"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
 	"go.uber.org/zap"
 )
@@ -18,10 +24,24 @@ type worker struct {
 	httpClient *http.Client
 	logger     *zap.SugaredLogger
 	config     *pkg.Config
+	statuses   recorder.StatusTracker
+	events     recorder.Broadcaster
+
+	pipeline  recorder.WritePipeline
+	exporter  remote_write.Exporter
+	mu        sync.Mutex
+	uptimeJob recorder.UptimeJob
 }
 
+// Worker runs the checks and background jobs. As a config_monitor.Reconciler
+// it applies monitors created, changed or deleted through the API to the
+// running checks.
 type Worker interface {
 	Start(ctx context.Context)
+	// Wait blocks until the worker stopped after the context of Start ended
+	// and the last check results are stored and queued for remote_write.
+	Wait()
+	config_monitor.Reconciler
 }
 
 func NewWorker(
@@ -31,21 +51,141 @@ func NewWorker(
 	httpClient *http.Client,
 	logger *zap.SugaredLogger,
 	config *pkg.Config,
+	statuses recorder.StatusTracker,
+	events recorder.Broadcaster,
 ) Worker {
 	return &worker{
-		tsdb,
-		db,
-		targets,
-		httpClient,
-		logger,
-		config,
+		tsdb:       tsdb,
+		db:         db,
+		targets:    targets,
+		httpClient: httpClient,
+		logger:     logger,
+		config:     config,
+		statuses:   statuses,
+		events:     events,
 	}
 }
 
 func (s *worker) Start(ctx context.Context) {
-	recorderService := recorder.NewRecorderService(s.tsdb, s.db, s.config, s.httpClient, s.logger)
+	contentChangeService := content_change.NewContentChangeService(content_change.NewContentChangeRepository(s.db))
+
+	var appendable storage.Appendable = s.tsdb
+	if len(s.config.RemoteWrite) > 0 {
+		exporter, err := remote_write.NewExporter(s.config, nil, s.logger)
+		if err != nil {
+			s.logger.Errorf("Error starting remote write: %v", err)
+		} else {
+			exporter.Start(ctx)
+			s.exporter = exporter
+			appendable = remote_write.NewAppendable(s.tsdb, exporter)
+		}
+	}
+
+	writePipeline := recorder.NewWritePipeline(appendable, s.config, s.logger)
+	writePipeline.Start(ctx)
+	s.pipeline = writePipeline
+
+	recorderService := recorder.NewRecorderService(writePipeline, s.db, s.config, s.httpClient, s.logger, contentChangeService, s.statuses, s.events)
 
-	recoderUptimeJob := recorder.NewUptimeJob(recorderService, s.targets, s.httpClient, s.logger)
+	targets := s.loadTargets(ctx)
+
+	relabelMigration := recorder.NewRelabelMigration(s.tsdb, appendable, s.db, s.config, s.logger)
+	if err := relabelMigration.Run(ctx, targets); err != nil {
+		s.logger.Errorf("Error relabeling series: %v", err)
+	}
+
+	recoderUptimeJob := recorder.NewUptimeJob(recorderService, targets, s.httpClient, s.logger)
 
 	recoderUptimeJob.Start(ctx)
+
+	s.mu.Lock()
+	s.uptimeJob = recoderUptimeJob
+	s.mu.Unlock()
+
+	recorderRollupJob := recorder.NewRollupJob(s.tsdb, appendable, s.db, s.config, s.logger)
+
+	recorderRollupJob.Start(ctx)
+
+	recorderRetentionJob := recorder.NewRetentionJob(s.tsdb, s.db, s.config, s.loadTargets, s.logger)
+
+	recorderRetentionJob.Start(ctx)
+}
+
+func (s *worker) Wait() {
+	s.pipeline.Wait()
+	if s.exporter != nil {
+		s.exporter.Close()
+	}
+}
+
+// loadTargets merges the targets from config.json with the uptime monitors stored in SQLite.
+func (s *worker) loadTargets(ctx context.Context) []pkg.Target {
+	configMonitorRepository := config_monitor.NewConfigMonitorRepository(s.db)
+
+	targets := append([]pkg.Target{}, s.targets...)
+
+	monitors, _, err := configMonitorRepository.List(ctx, -1, 0)
+	if err != nil {
+		s.logger.Errorf("Error loading monitors: %v", err)
+		return targets
+	}
+
+	for _, monitor := range monitors {
+		target, ok := s.target(ctx, monitor)
+		if ok {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets
+}
+
+// target converts a stored monitor into the target to check. Only uptime
+// monitors are checked, and monitors whose TLS config cannot be resolved are
+// skipped.
+func (s *worker) target(ctx context.Context, monitor *pkg.ConfigMonitorDTO) (pkg.Target, bool) {
+	if monitor.Type != "uptime" {
+		return pkg.Target{}, false
+	}
+
+	target := monitor.ToTarget()
+	if monitor.TLSConfigID != "" {
+		tlsConfigService := tls_config.NewTLSConfigService(tls_config.NewTLSConfigRepository(s.db), s.config)
+		tlsConfig, err := tlsConfigService.ResolveTLSConfig(ctx, monitor.TLSConfigID)
+		if err != nil {
+			s.logger.Errorf("Skipping monitor %s: %v", monitor.ID, err)
+			return pkg.Target{}, false
+		}
+		target.TLS = tlsConfig
+	}
+	return target, true
+}
+
+// MonitorSaved starts or replaces the checker of a created or updated
+// monitor, or stops it when the monitor is no longer checked.
+func (s *worker) MonitorSaved(ctx context.Context, monitor *pkg.ConfigMonitorDTO) {
+	job := s.job()
+	if job == nil {
+		return
+	}
+
+	target, ok := s.target(ctx, monitor)
+	if !ok {
+		job.Unschedule(monitor.ID)
+		return
+	}
+	job.Schedule(target)
+}
+
+// MonitorDeleted stops the checker of a deleted monitor.
+func (s *worker) MonitorDeleted(monitorID string) {
+	if job := s.job(); job != nil {
+		job.Unschedule(monitorID)
+	}
+}
+
+func (s *worker) job() recorder.UptimeJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.uptimeJob
 }
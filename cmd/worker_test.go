@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/recorder"
+	"github.com/afrianjunior/statx/internal/testutil"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+)
+
+type testServer struct {
+	router   *chi.Mux
+	db       *sql.DB
+	tsdb     *tsdb.DB
+	statuses recorder.StatusTracker
+	worker   Worker
+}
+
+// newTestServer starts a worker without config targets and builds the
+// router of a rest server sharing its storage.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	config := testutil.Config(t)
+	db := testutil.DB(t)
+	tsdb := testutil.TSDB(t, config)
+	logger := testutil.Logger()
+	httpClient := &http.Client{Timeout: config.CheckTimeout}
+	statuses := recorder.NewStatusTracker()
+	events := recorder.NewBroadcaster()
+
+	worker := NewWorker(tsdb, db, nil, httpClient, logger, config, statuses, events)
+	worker.Start(ctx)
+
+	rest := NewRest(httpClient, tsdb, db, logger, config, statuses, events, worker).(*rest)
+
+	return &testServer{
+		router:   rest.setupRouter(),
+		db:       db,
+		tsdb:     tsdb,
+		statuses: statuses,
+		worker:   worker,
+	}
+}
+
+func (s *testServer) do(t *testing.T, method, path string, body any) (int, pkg.BaseResponse) {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if raw, ok := body.(string); ok {
+			payload.WriteString(raw)
+		} else if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(method, path, &payload))
+
+	var resp pkg.BaseResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("%s %s: decoding %q: %v", method, path, rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+func (s *testServer) hasSeries(t *testing.T, monitorID string) bool {
+	t.Helper()
+
+	querier, err := s.tsdb.Querier(0, time.Now().Add(time.Minute).UnixMilli())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer querier.Close()
+
+	set := querier.Select(context.Background(), false, nil, labels.MustNewMatcher(labels.MatchEqual, "monitor_id", monitorID))
+	return set.Next()
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCreatedMonitorIsCheckedAndDeletedOneStops(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		hits    = map[string]int{}
+		release = make(chan struct{})
+	)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+	}))
+	defer target.Close()
+	hitsOf := func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return hits[path]
+	}
+
+	s := newTestServer(t)
+
+	create := func(name, path string) string {
+		code, resp := s.do(t, http.MethodPost, "/api/configs", map[string]any{
+			"name": name, "type": "uptime", "url": target.URL + path, "interval": 10,
+		})
+		if code != http.StatusOK {
+			t.Fatalf("creating %s: %d %v", name, code, resp)
+		}
+		return resp.Data.(string)
+	}
+
+	fast := create("fast", "/fast")
+	waitFor(t, "the created monitor to be checked", func() bool {
+		_, ok := s.statuses.Get(fast)
+		return ok && hitsOf("/fast") == 1
+	})
+
+	// The check of slow is held in flight while the monitor is deleted;
+	// its result must not be written back.
+	slow := create("slow", "/slow")
+	waitFor(t, "the check of slow to start", func() bool { return hitsOf("/slow") == 1 })
+
+	if code, resp := s.do(t, http.MethodDelete, "/api/configs/"+slow+"?purge=true", nil); code != http.StatusOK {
+		t.Fatalf("deleting: %d %v", code, resp)
+	}
+	close(release)
+
+	time.Sleep(200 * time.Millisecond)
+	s.worker.(*worker).pipeline.Flush()
+	if _, ok := s.statuses.Get(slow); ok {
+		t.Fatal("deleted monitor is back in the status tracker")
+	}
+	if s.hasSeries(t, slow) {
+		t.Fatal("deleted monitor wrote series after the purge")
+	}
+	if hits := hitsOf("/slow"); hits != 1 {
+		t.Fatalf("deleted monitor was checked %d times", hits)
+	}
+}
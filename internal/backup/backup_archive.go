@@ -0,0 +1,260 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+const (
+	manifestName    = "manifest.json"
+	manifestVersion = 1
+
+	tsdbDir   = "tsdb"
+	sqliteDir = "sqlite"
+)
+
+// buildManifest walks a staged backup and checksums every file in it.
+func buildManifest(root string, createdAt time.Time) (*pkg.BackupManifest, error) {
+	manifest := &pkg.BackupManifest{
+		Version:   manifestVersion,
+		CreatedAt: createdAt,
+	}
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		hash := sha256.New()
+		size, err := io.Copy(hash, f)
+		if err != nil {
+			return err
+		}
+
+		manifest.Files = append(manifest.Files, pkg.BackupFile{
+			Path:   filepath.ToSlash(rel),
+			Size:   size,
+			SHA256: hex.EncodeToString(hash.Sum(nil)),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error building manifest: %v", err)
+	}
+
+	return manifest, nil
+}
+
+// writeArchive writes a gzipped tar with the manifest as its first entry,
+// followed by the files it lists.
+func writeArchive(w io.Writer, root string, manifest *pkg.BackupManifest) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding manifest: %v", err)
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    manifestName,
+		Mode:    0644,
+		Size:    int64(len(manifestJSON)),
+		ModTime: manifest.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing manifest: %v", err)
+	}
+	if _, err := tw.Write(manifestJSON); err != nil {
+		return fmt.Errorf("error writing manifest: %v", err)
+	}
+
+	for _, file := range manifest.Files {
+		if err := writeArchiveFile(tw, root, file, manifest.CreatedAt); err != nil {
+			return fmt.Errorf("error writing %s: %v", file.Path, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("error closing archive: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("error closing archive: %v", err)
+	}
+
+	return nil
+}
+
+func writeArchiveFile(tw *tar.Writer, root string, file pkg.BackupFile, modTime time.Time) error {
+	f, err := os.Open(filepath.Join(root, filepath.FromSlash(file.Path)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:    file.Path,
+		Mode:    0644,
+		Size:    file.Size,
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(tw, f, file.Size)
+	return err
+}
+
+// Verify reads a whole archive and checks every entry against the manifest
+// without writing anything to disk.
+func Verify(archivePath string) (*pkg.BackupManifest, error) {
+	return readArchive(archivePath, func(string) (io.WriteCloser, error) {
+		return nopWriteCloser{io.Discard}, nil
+	})
+}
+
+// extract unpacks a verified archive into dir.
+func extract(archivePath, dir string) (*pkg.BackupManifest, error) {
+	return readArchive(archivePath, func(name string) (io.WriteCloser, error) {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		return os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	})
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// readArchive streams the archive, handing each entry to open and checking
+// its size and checksum against the manifest. Unknown, duplicated or missing
+// entries make the whole archive invalid.
+func readArchive(archivePath string, open func(name string) (io.WriteCloser, error)) (*pkg.BackupManifest, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening archive: %v", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive: %v", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive: %v", err)
+	}
+	if header.Name != manifestName {
+		return nil, fmt.Errorf("invalid archive: first entry is %s, expected %s", header.Name, manifestName)
+	}
+
+	var manifest pkg.BackupManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	if manifest.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", manifest.Version)
+	}
+
+	expected := make(map[string]pkg.BackupFile, len(manifest.Files))
+	for _, file := range manifest.Files {
+		if !validEntryName(file.Path) {
+			return nil, fmt.Errorf("invalid manifest: bad path %q", file.Path)
+		}
+		expected[file.Path] = file
+	}
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading archive: %v", err)
+		}
+
+		file, ok := expected[header.Name]
+		if !ok {
+			return nil, fmt.Errorf("invalid archive: unexpected entry %s", header.Name)
+		}
+		delete(expected, header.Name)
+
+		if err := copyEntry(tr, file, open); err != nil {
+			return nil, fmt.Errorf("invalid archive: %s: %v", header.Name, err)
+		}
+	}
+
+	for name := range expected {
+		return nil, fmt.Errorf("invalid archive: missing entry %s", name)
+	}
+
+	return &manifest, nil
+}
+
+func copyEntry(r io.Reader, file pkg.BackupFile, open func(name string) (io.WriteCloser, error)) error {
+	w, err := open(file.Path)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(w, hash), r)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if size != file.Size {
+		return fmt.Errorf("size %d does not match manifest size %d", size, file.Size)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != file.SHA256 {
+		return fmt.Errorf("checksum %s does not match manifest checksum %s", sum, file.SHA256)
+	}
+
+	return nil
+}
+
+// validEntryName only accepts clean relative paths below the tsdb and sqlite
+// directories, so an archive can never write outside the restore directory.
+func validEntryName(name string) bool {
+	if name == "" || path.IsAbs(name) || path.Clean(name) != name || strings.HasPrefix(name, "../") {
+		return false
+	}
+	return strings.HasPrefix(name, tsdbDir+"/") || strings.HasPrefix(name, sqliteDir+"/")
+}
@@ -0,0 +1,56 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+// BackupHandler creates a backup and streams it back as the response body.
+func BackupHandler(backupSvc BackupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		archivePath, manifest, err := backupSvc.CreateBackup(r.Context())
+		if err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, http.StatusInternalServerError)
+			return
+		}
+		defer os.Remove(archivePath)
+
+		archive, err := os.Open(archivePath)
+		if err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, http.StatusInternalServerError)
+			return
+		}
+		defer archive.Close()
+
+		info, err := archive.Stat()
+		if err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		filename := fmt.Sprintf("statx-backup-%s.tar.gz", manifest.CreatedAt.Format("20060102150405"))
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+		w.WriteHeader(http.StatusOK)
+
+		_, _ = io.Copy(w, archive)
+	}
+}
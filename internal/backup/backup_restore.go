@@ -0,0 +1,128 @@
+package backup
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/fileutil"
+	"go.uber.org/zap"
+
+	_ "github.com/glebarez/go-sqlite"
+)
+
+// Restore replaces the data directories with the content of a backup
+// archive. It must run while statx is stopped. The archive is unpacked and
+// checked next to the live data first; the current directories are only
+// swapped out, never deleted, and are left as <name>.pre-restore-<time>.
+func Restore(archivePath string, config *pkg.Config, logger *zap.SugaredLogger) error {
+	if err := os.MkdirAll(config.StoragePath, 0755); err != nil {
+		return fmt.Errorf("error creating storage directory: %v", err)
+	}
+
+	// The TSDB holds this lock for as long as it is open.
+	if _, err := os.Stat(config.TSDBPath()); err == nil {
+		lock, _, err := fileutil.Flock(filepath.Join(config.TSDBPath(), "lock"))
+		if err != nil {
+			return fmt.Errorf("error locking tsdb, is statx still running? %v", err)
+		}
+		defer lock.Release()
+	}
+
+	staging, err := os.MkdirTemp(config.StoragePath, "restore-")
+	if err != nil {
+		return fmt.Errorf("error creating staging directory: %v", err)
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, err := extract(archivePath, staging)
+	if err != nil {
+		return err
+	}
+	logger.Infof("Verified backup from %s with %d files", manifest.CreatedAt.Format(time.RFC3339), len(manifest.Files))
+
+	if err := checkSQLite(filepath.Join(staging, sqliteDir, filepath.Base(config.SQLiteFile()))); err != nil {
+		return err
+	}
+	if err := checkTSDB(filepath.Join(staging, tsdbDir), staging); err != nil {
+		return err
+	}
+
+	suffix := ".pre-restore-" + time.Now().Format("20060102150405")
+	swaps := []struct{ live, restored string }{
+		{config.TSDBPath(), filepath.Join(staging, tsdbDir)},
+		{config.SQLitePath(), filepath.Join(staging, sqliteDir)},
+	}
+
+	var done []string
+	for _, swap := range swaps {
+		if err := swapDir(swap.live, swap.restored, swap.live+suffix); err != nil {
+			rollback(done, suffix, logger)
+			return err
+		}
+		done = append(done, swap.live)
+		logger.Infof("Restored %s, previous data kept in %s", swap.live, swap.live+suffix)
+	}
+
+	return nil
+}
+
+func swapDir(live, restored, old string) error {
+	if err := os.Rename(live, old); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error moving %s aside: %v", live, err)
+	}
+	if err := os.Rename(restored, live); err != nil {
+		os.Rename(old, live)
+		return fmt.Errorf("error moving restored data into %s: %v", live, err)
+	}
+	return nil
+}
+
+func rollback(done []string, suffix string, logger *zap.SugaredLogger) {
+	for _, live := range done {
+		if err := os.RemoveAll(live); err != nil {
+			logger.Errorf("Error rolling back %s: %v", live, err)
+			continue
+		}
+		if err := os.Rename(live+suffix, live); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Errorf("Error rolling back %s: %v", live, err)
+		}
+	}
+}
+
+func checkSQLite(path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("error opening restored sqlite: %v", err)
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("error checking restored sqlite: %v", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("restored sqlite failed integrity check: %s", result)
+	}
+
+	return nil
+}
+
+func checkTSDB(dir, sandbox string) error {
+	db, err := tsdb.OpenDBReadOnly(dir, sandbox, nil)
+	if err != nil {
+		return fmt.Errorf("error opening restored tsdb: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Blocks(); err != nil {
+		return fmt.Errorf("error reading restored tsdb blocks: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+)
+
+func TestRestoreReplacesTheData(t *testing.T) {
+	archive := createBackup(t)
+	config := testutil.Config(t)
+	writeLiveData(t, config)
+
+	if err := Restore(archive, config, testutil.Logger()); err != nil {
+		t.Fatalf("restoring: %v", err)
+	}
+
+	if got := tagNames(t, config); len(got) != 1 || got[0] != "restored" {
+		t.Errorf("tags = %v, want the restored one", got)
+	}
+	if !hasMonitorSeries(t, config.TSDBPath(), "restored") || hasMonitorSeries(t, config.TSDBPath(), "live") {
+		t.Error("the tsdb does not hold the restored series only")
+	}
+
+	// the previous data is moved aside, not deleted
+	for _, dir := range []string{config.TSDBPath(), config.SQLitePath()} {
+		kept, _ := filepath.Glob(dir + ".pre-restore-*")
+		if len(kept) != 1 {
+			t.Errorf("%s kept as %v, want one copy", dir, kept)
+		}
+	}
+	if !hasMonitorSeries(t, keptDir(t, config.TSDBPath()), "live") {
+		t.Error("the previous tsdb was not kept")
+	}
+}
+
+func TestRestoreRollsBackWhenASwapFails(t *testing.T) {
+	archive := createBackup(t)
+	config := testutil.Config(t)
+	writeLiveData(t, config)
+
+	// the tsdb is swapped first; a non-empty directory where the sqlite
+	// directory is to be moved aside makes the second swap fail
+	now := time.Now()
+	for i := -1; i < 60; i++ {
+		taken := config.SQLitePath() + ".pre-restore-" + now.Add(time.Duration(i)*time.Second).Format("20060102150405")
+		if err := os.MkdirAll(filepath.Join(taken, "taken"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Restore(archive, config, testutil.Logger()); err == nil {
+		t.Fatal("restore succeeded, want the sqlite swap to fail")
+	}
+
+	if got := tagNames(t, config); len(got) != 1 || got[0] != "live" {
+		t.Errorf("tags = %v, want the live one", got)
+	}
+	if !hasMonitorSeries(t, config.TSDBPath(), "live") || hasMonitorSeries(t, config.TSDBPath(), "restored") {
+		t.Error("the tsdb does not hold the live series only")
+	}
+	if kept, _ := filepath.Glob(config.TSDBPath() + ".pre-restore-*"); len(kept) != 0 {
+		t.Errorf("tsdb left aside as %v after the rollback", kept)
+	}
+	if staging, _ := filepath.Glob(filepath.Join(config.StoragePath, "restore-*")); len(staging) != 0 {
+		t.Errorf("staging left behind: %v", staging)
+	}
+}
+
+// createBackup archives a TSDB and SQLite database that hold a series and a
+// tag named restored.
+func createBackup(t *testing.T) string {
+	t.Helper()
+	ctx := context.Background()
+	config := testutil.Config(t)
+	storage := testutil.TSDB(t, config)
+	db := testutil.DB(t)
+
+	appendMonitorSample(t, storage, "restored")
+	if _, err := db.Exec("INSERT INTO tag (name) VALUES ('restored')"); err != nil {
+		t.Fatalf("inserting tag: %v", err)
+	}
+
+	archive, _, err := NewBackupService(storage, db, config, testutil.Logger()).CreateBackup(ctx)
+	if err != nil {
+		t.Fatalf("creating backup: %v", err)
+	}
+	return archive
+}
+
+// writeLiveData fills the data directories of config with a series and a
+// tag named live, as a stopped statx leaves them.
+func writeLiveData(t *testing.T, config *pkg.Config) {
+	t.Helper()
+
+	storage, err := tsdb.Open(config.TSDBPath(), nil, nil, tsdb.DefaultOptions(), nil)
+	if err != nil {
+		t.Fatalf("opening tsdb: %v", err)
+	}
+	appendMonitorSample(t, storage, "live")
+	if err := storage.Close(); err != nil {
+		t.Fatalf("closing tsdb: %v", err)
+	}
+
+	if err := os.MkdirAll(config.SQLitePath(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite", config.SQLiteFile())
+	if err != nil {
+		t.Fatalf("opening sqlite: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE tag (name TEXT); INSERT INTO tag (name) VALUES ('live')"); err != nil {
+		t.Fatalf("writing sqlite: %v", err)
+	}
+}
+
+func appendMonitorSample(t *testing.T, storage *tsdb.DB, monitorID string) {
+	t.Helper()
+
+	appender := storage.Appender(context.Background())
+	if _, err := appender.Append(0, labels.FromStrings(labels.MetricName, "up", "monitor_id", monitorID), time.Now().UnixMilli(), 1); err != nil {
+		t.Fatalf("appending: %v", err)
+	}
+	if err := appender.Commit(); err != nil {
+		t.Fatalf("committing: %v", err)
+	}
+}
+
+func tagNames(t *testing.T, config *pkg.Config) []string {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", config.SQLiteFile())
+	if err != nil {
+		t.Fatalf("opening sqlite: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT name FROM tag")
+	if err != nil {
+		t.Fatalf("selecting tags: %v", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("scanning tag: %v", err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func hasMonitorSeries(t *testing.T, dir, monitorID string) bool {
+	t.Helper()
+
+	db, err := tsdb.Open(dir, nil, nil, tsdb.DefaultOptions(), nil)
+	if err != nil {
+		t.Fatalf("opening %s: %v", dir, err)
+	}
+	defer db.Close()
+
+	querier, err := db.Querier(0, time.Now().Add(time.Hour).UnixMilli())
+	if err != nil {
+		t.Fatalf("creating querier: %v", err)
+	}
+	defer querier.Close()
+
+	return querier.Select(context.Background(), false, nil, labels.MustNewMatcher(labels.MatchEqual, "monitor_id", monitorID)).Next()
+}
+
+func keptDir(t *testing.T, live string) string {
+	t.Helper()
+
+	kept, err := filepath.Glob(live + ".pre-restore-*")
+	if err != nil || len(kept) == 0 {
+		t.Fatalf("no previous copy of %s", live)
+	}
+	return kept[0]
+}
@@ -0,0 +1,117 @@
+package badge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxAge is short so badges follow state changes while still being cached
+// by READMEs and wikis.
+const maxAge = 60
+
+// maxUptimeWindow bounds ?window= of the uptime badge, which is public and
+// would otherwise let anyone scan the whole retention of a monitor.
+const maxUptimeWindow = 90 * 24 * time.Hour
+
+func StatusHandler(badgeSvc BadgeService) http.HandlerFunc {
+	return badgeHandler(func(ctx context.Context, r *http.Request, monitorID, token string) (Badge, error) {
+		return badgeSvc.StatusBadge(ctx, monitorID, token)
+	})
+}
+
+// UptimeHandler renders the availability over ?window=, 30d by default and at
+// most 90d.
+func UptimeHandler(badgeSvc BadgeService) http.HandlerFunc {
+	return badgeHandler(func(ctx context.Context, r *http.Request, monitorID, token string) (Badge, error) {
+		name := r.URL.Query().Get("window")
+		if name == "" {
+			name = "30d"
+		}
+		duration, err := pkg.ParseWindow(name)
+		if err != nil {
+			return Badge{}, fieldError("window", err.Error())
+		}
+		if duration > maxUptimeWindow {
+			return Badge{}, fieldError("window", "must be at most 90d")
+		}
+		return badgeSvc.UptimeBadge(ctx, monitorID, token, pkg.Window{Name: name, Duration: duration})
+	})
+}
+
+func LatencyHandler(badgeSvc BadgeService) http.HandlerFunc {
+	return badgeHandler(func(ctx context.Context, r *http.Request, monitorID, token string) (Badge, error) {
+		return badgeSvc.LatencyBadge(ctx, monitorID, token)
+	})
+}
+
+// badgeHandler resolves a badge, applies the label, color and label_color
+// overrides and serves it with an ETag.
+func badgeHandler(resolve func(ctx context.Context, r *http.Request, monitorID, token string) (Badge, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		badge, err := resolve(r.Context(), r, chi.URLParam(r, "id"), query.Get("token"))
+		if err == nil {
+			err = applyOverrides(&badge, query.Get("label"), query.Get("color"), query.Get("label_color"))
+		}
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		svg := Render(badge)
+		sum := sha256.Sum256(svg)
+		etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(svg)
+	}
+}
+
+func applyOverrides(badge *Badge, label, color, labelColor string) error {
+	if label != "" {
+		badge.Label = label
+	}
+	if color != "" {
+		parsed, ok := ParseColor(color)
+		if !ok {
+			return fieldError("color", "must be a color name or hex value")
+		}
+		badge.Color = parsed
+	}
+	if labelColor != "" {
+		parsed, ok := ParseColor(labelColor)
+		if !ok {
+			return fieldError("label_color", "must be a color name or hex value")
+		}
+		badge.LabelColor = parsed
+	}
+	return nil
+}
+
+func fieldError(field, message string) error {
+	errs := &pkg.ValidationError{}
+	errs.Add(field, message)
+	return errs
+}
+
+// errorStatuses maps service errors to status codes.
+var errorStatuses = []pkg.ErrorStatus{
+	{Err: ErrForbidden, Code: http.StatusForbidden},
+	{Err: ErrNotFound, Code: http.StatusNotFound},
+}
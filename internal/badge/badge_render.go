@@ -0,0 +1,95 @@
+package badge
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Badge is a two part shields style badge: a label on the left and a message
+// on the right.
+type Badge struct {
+	Label      string
+	Message    string
+	LabelColor string
+	Color      string
+}
+
+// Colors known by name, matching the shields.io palette.
+var namedColors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellowgreen": "#a4a61d",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+	"blue":        "#007ec6",
+	"grey":        "#555",
+	"gray":        "#555",
+	"lightgrey":   "#9f9f9f",
+	"lightgray":   "#9f9f9f",
+}
+
+var hexColorPattern = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// ParseColor accepts a named color or a hex color with or without '#'.
+func ParseColor(s string) (string, bool) {
+	if color, ok := namedColors[strings.ToLower(s)]; ok {
+		return color, true
+	}
+	if hexColorPattern.MatchString(s) {
+		return "#" + strings.TrimPrefix(s, "#"), true
+	}
+	return "", false
+}
+
+// Render draws the badge as SVG. Text widths are estimated for 11px
+// Verdana, the font shields uses, and enforced with textLength.
+func Render(b Badge) []byte {
+	labelWidth := textWidth(b.Label) + 10
+	messageWidth := textWidth(b.Message) + 10
+	width := labelWidth + messageWidth
+	label := html.EscapeString(b.Label)
+	message := html.EscapeString(b.Message)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, width, label, message)
+	fmt.Fprintf(&buf, `<title>%s: %s</title>`, label, message)
+	buf.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(&buf, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, width)
+	fmt.Fprintf(&buf, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="%s"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`,
+		labelWidth, b.LabelColor, labelWidth, messageWidth, b.Color, width)
+	buf.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" text-rendering="geometricPrecision" font-size="11">`)
+	writeText(&buf, float64(labelWidth)/2, labelWidth-10, label)
+	writeText(&buf, float64(labelWidth)+float64(messageWidth)/2, messageWidth-10, message)
+	buf.WriteString(`</g></svg>`)
+
+	return buf.Bytes()
+}
+
+func writeText(buf *bytes.Buffer, x float64, length int, text string) {
+	fmt.Fprintf(buf, `<text x="%.1f" y="15" fill="#010101" fill-opacity=".3" textLength="%d">%s</text>`, x, length, text)
+	fmt.Fprintf(buf, `<text x="%.1f" y="14" textLength="%d">%s</text>`, x, length, text)
+}
+
+// textWidth approximates the rendered width of s in pixels.
+func textWidth(s string) int {
+	var width float64
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("iljI.,:;'|!", r):
+			width += 3.5
+		case strings.ContainsRune("frt() -", r):
+			width += 4.5
+		case r == 'm' || r == 'w' || r == 'M' || r == 'W' || r == '%':
+			width += 10
+		case r >= 'A' && r <= 'Z':
+			width += 7.5
+		default:
+			width += 6.5
+		}
+	}
+	return int(width + 0.5)
+}
@@ -0,0 +1,181 @@
+package badge
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/config_monitor"
+	"github.com/afrianjunior/statx/internal/exposer"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/recorder"
+)
+
+var (
+	// ErrNotFound is also returned for monitors with badges disabled, so
+	// badges do not reveal which monitors exist.
+	ErrNotFound  = errors.New("badge not found")
+	ErrForbidden = errors.New("invalid badge token")
+)
+
+const (
+	labelColor = "#555"
+	greyColor  = "#9f9f9f"
+)
+
+type badgeService struct {
+	configMonitorRepository config_monitor.ConfigMonitorRepository
+	exposerService          exposer.ExposerService
+	statuses                recorder.StatusTracker
+}
+
+type BadgeService interface {
+	StatusBadge(ctx context.Context, monitorID, token string) (Badge, error)
+	UptimeBadge(ctx context.Context, monitorID, token string, window pkg.Window) (Badge, error)
+	LatencyBadge(ctx context.Context, monitorID, token string) (Badge, error)
+}
+
+func NewBadgeService(
+	configMonitorRepository config_monitor.ConfigMonitorRepository,
+	exposerService exposer.ExposerService,
+	statuses recorder.StatusTracker,
+) BadgeService {
+	return &badgeService{
+		configMonitorRepository: configMonitorRepository,
+		exposerService:          exposerService,
+		statuses:                statuses,
+	}
+}
+
+func (s *badgeService) StatusBadge(ctx context.Context, monitorID, token string) (Badge, error) {
+	if err := s.authorize(ctx, monitorID, token); err != nil {
+		return Badge{}, err
+	}
+
+	badge := Badge{Label: "status", Message: "unknown", LabelColor: labelColor, Color: greyColor}
+	if status, ok := s.statuses.Get(monitorID); ok {
+		badge.Message = status.State
+		badge.Color = stateColor(pkg.ParseState(status.State))
+	}
+
+	return badge, nil
+}
+
+func (s *badgeService) UptimeBadge(ctx context.Context, monitorID, token string, window pkg.Window) (Badge, error) {
+	if err := s.authorize(ctx, monitorID, token); err != nil {
+		return Badge{}, err
+	}
+
+	summaries, err := s.exposerService.QueryUptimeSummary(ctx, monitorID, []pkg.Window{window}, time.Now(), nil)
+	if err != nil {
+		return Badge{}, err
+	}
+
+	badge := Badge{Label: "uptime " + window.Name, Message: "no data", LabelColor: labelColor, Color: greyColor}
+	if len(summaries) > 0 && summaries[0].Availability != nil {
+		availability := *summaries[0].Availability
+		badge.Message = formatPercent(availability)
+		badge.Color = uptimeColor(availability)
+	}
+
+	return badge, nil
+}
+
+func (s *badgeService) LatencyBadge(ctx context.Context, monitorID, token string) (Badge, error) {
+	if err := s.authorize(ctx, monitorID, token); err != nil {
+		return Badge{}, err
+	}
+
+	badge := Badge{Label: "latency", Message: "unknown", LabelColor: labelColor, Color: greyColor}
+	if status, ok := s.statuses.Get(monitorID); ok {
+		if status.Up {
+			badge.Message = formatLatency(status.ResponseTime)
+			badge.Color = latencyColor(status.ResponseTime)
+		} else {
+			badge.Message = "n/a"
+		}
+	}
+
+	return badge, nil
+}
+
+// authorize checks that the monitor exists, has badges enabled and, when it
+// has a badge token, that the request carries it.
+func (s *badgeService) authorize(ctx context.Context, monitorID, token string) error {
+	config, err := s.configMonitorRepository.GetByID(ctx, monitorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !config.BadgeEnabled {
+		return ErrNotFound
+	}
+	if config.HasBadgeToken && subtle.ConstantTimeCompare([]byte(pkg.HashToken(token)), []byte(config.BadgeTokenHash)) != 1 {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func stateColor(state pkg.State) string {
+	switch state {
+	case pkg.StateUp:
+		return namedColors["brightgreen"]
+	case pkg.StateDegraded:
+		return namedColors["yellow"]
+	default:
+		return namedColors["red"]
+	}
+}
+
+func uptimeColor(availability float64) string {
+	switch {
+	case availability >= 99.9:
+		return namedColors["brightgreen"]
+	case availability >= 99:
+		return namedColors["green"]
+	case availability >= 95:
+		return namedColors["yellow"]
+	case availability >= 90:
+		return namedColors["orange"]
+	default:
+		return namedColors["red"]
+	}
+}
+
+func latencyColor(ms float64) string {
+	switch {
+	case ms < 200:
+		return namedColors["brightgreen"]
+	case ms < 500:
+		return namedColors["green"]
+	case ms < 1000:
+		return namedColors["yellow"]
+	case ms < 2000:
+		return namedColors["orange"]
+	default:
+		return namedColors["red"]
+	}
+}
+
+// formatPercent keeps up to two decimals without trailing zeros, e.g. 99.95%
+// or 100%. Values just below 100 are not rounded up to it.
+func formatPercent(v float64) string {
+	if v < 100 && v > 99.99 {
+		v = 99.99
+	}
+	s := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+	return s + "%"
+}
+
+func formatLatency(ms float64) string {
+	if ms >= 1000 {
+		return fmt.Sprintf("%.1fs", ms/1000)
+	}
+	return fmt.Sprintf("%.0fms", ms)
+}
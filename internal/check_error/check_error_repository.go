@@ -0,0 +1,78 @@
+package check_error
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/glebarez/go-sqlite"
+)
+
+type checkErrorRepository struct {
+	db *sql.DB
+}
+
+// CheckErrorRepository stores the messages of failed checks, keyed by
+// monitor and the millisecond timestamp of the check's samples.
+type CheckErrorRepository interface {
+	Insert(ctx context.Context, monitorID string, checkedAt time.Time, class, message string) error
+	// Messages returns the messages of a monitor's failed checks between
+	// start and end by timestamp in milliseconds.
+	Messages(ctx context.Context, monitorID string, start, end time.Time) (map[int64]string, error)
+	// DeleteBefore removes the messages of checks older than t.
+	DeleteBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+func NewCheckErrorRepository(
+	db *sql.DB,
+) CheckErrorRepository {
+	return &checkErrorRepository{
+		db,
+	}
+}
+
+func (r *checkErrorRepository) Insert(ctx context.Context, monitorID string, checkedAt time.Time, class, message string) error {
+	query := `
+		INSERT INTO check_error (monitor_id, checked_at, class, message)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(monitor_id, checked_at) DO UPDATE SET
+			class = excluded.class,
+			message = excluded.message
+	`
+	if _, err := r.db.ExecContext(ctx, query, monitorID, checkedAt.UnixMilli(), class, message); err != nil {
+		return fmt.Errorf("error inserting check error: %w", err)
+	}
+	return nil
+}
+
+func (r *checkErrorRepository) Messages(ctx context.Context, monitorID string, start, end time.Time) (map[int64]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT checked_at, message FROM check_error WHERE monitor_id = ? AND checked_at BETWEEN ? AND ?",
+		monitorID, start.UnixMilli(), end.UnixMilli(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching check errors: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make(map[int64]string)
+	for rows.Next() {
+		var ts int64
+		var message sql.NullString
+		if err := rows.Scan(&ts, &message); err != nil {
+			return nil, fmt.Errorf("error scanning check error: %w", err)
+		}
+		messages[ts] = message.String
+	}
+
+	return messages, rows.Err()
+}
+
+func (r *checkErrorRepository) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM check_error WHERE checked_at < ?", t.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("error deleting check errors: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,44 @@
+package check_error
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/testutil"
+)
+
+func TestCheckErrorRepository(t *testing.T) {
+	ctx := context.Background()
+	repository := NewCheckErrorRepository(testutil.DB(t))
+	base := time.Now().Truncate(time.Millisecond)
+
+	for i, message := range []string{"old", "refused", "timeout"} {
+		if err := repository.Insert(ctx, "a", base.Add(time.Duration(i)*time.Minute), "class", message); err != nil {
+			t.Fatalf("inserting: %v", err)
+		}
+	}
+	// a check written twice keeps its last message
+	if err := repository.Insert(ctx, "a", base.Add(time.Minute), "class", "reset"); err != nil {
+		t.Fatalf("inserting again: %v", err)
+	}
+	if err := repository.Insert(ctx, "b", base.Add(time.Minute), "class", "other"); err != nil {
+		t.Fatalf("inserting: %v", err)
+	}
+
+	messages, err := repository.Messages(ctx, "a", base.Add(time.Minute), base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("getting messages: %v", err)
+	}
+	if len(messages) != 2 || messages[base.Add(time.Minute).UnixMilli()] != "reset" || messages[base.Add(2*time.Minute).UnixMilli()] != "timeout" {
+		t.Errorf("messages = %v", messages)
+	}
+
+	deleted, err := repository.DeleteBefore(ctx, base.Add(time.Minute))
+	if err != nil || deleted != 1 {
+		t.Fatalf("deleted %d, %v, want the oldest message", deleted, err)
+	}
+	if messages, err = repository.Messages(ctx, "a", base, base.Add(time.Hour)); err != nil || len(messages) != 2 {
+		t.Errorf("messages after delete = %v, %v", messages, err)
+	}
+}
@@ -3,20 +3,29 @@ package config_monitor
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/go-chi/chi/v5"
 )
 
+const maxBodySize = 1 << 20
+
 type listResponse struct {
-	Total    int                     `json:"total"`
-	Monitors []*pkg.ConfigMonitorDTO `json:"monitors"`
+	Total      int                     `json:"total"`
+	Limit      int                     `json:"limit"`
+	Offset     int                     `json:"offset"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+	Monitors   []*pkg.ConfigMonitorDTO `json:"monitors"`
 }
 
 func MutationHandler(configMonitorSvc ConfigMonitorService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var payload pkg.ConfigMonitorDTO
-		err := json.NewDecoder(r.Body).Decode(&payload)
+		err := json.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(&payload)
 		if err != nil {
 			pkg.JsonResponse(w, pkg.BaseResponse{
 				Success: false,
@@ -25,7 +34,12 @@ func MutationHandler(configMonitorSvc ConfigMonitorService) http.HandlerFunc {
 			}, http.StatusBadRequest)
 			return
 		}
+
 		id, err := configMonitorSvc.MutateConfigMonitor(r.Context(), &payload)
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
 
 		pkg.JsonResponse(w, pkg.BaseResponse{
 			Success: true,
@@ -35,27 +49,205 @@ func MutationHandler(configMonitorSvc ConfigMonitorService) http.HandlerFunc {
 	}
 }
 
-func ListHandler(configMonitorSvc ConfigMonitorService) http.HandlerFunc {
+func GetHandler(configMonitorSvc ConfigMonitorService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		fmt.Println("GOTHCA")
-		monitors, total, err := configMonitorSvc.GetListConfigMonitors(r.Context(), 100, 0)
+		config, err := configMonitorSvc.GetConfigMonitor(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    config,
+		}, http.StatusOK)
+	}
+}
 
+func UpdateHandler(configMonitorSvc ConfigMonitorService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload pkg.ConfigMonitorDTO
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(&payload); err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: "invalid req body",
+				Data:    nil,
+			}, http.StatusBadRequest)
+			return
+		}
+
+		config, err := configMonitorSvc.UpdateConfigMonitor(r.Context(), chi.URLParam(r, "id"), &payload)
 		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    config,
+		}, http.StatusOK)
+	}
+}
+
+func PatchHandler(configMonitorSvc ConfigMonitorService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var patch map[string]json.RawMessage
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+		if err == nil {
+			err = json.Unmarshal(body, &patch)
+		}
+		if err != nil || patch == nil {
 			pkg.JsonResponse(w, pkg.BaseResponse{
 				Success: false,
-				Message: err.Error(),
+				Message: "invalid req body",
 				Data:    nil,
 			}, http.StatusBadRequest)
 			return
 		}
-		fmt.Println(monitors)
+
+		config, err := configMonitorSvc.PatchConfigMonitor(r.Context(), chi.URLParam(r, "id"), body)
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    config,
+		}, http.StatusOK)
+	}
+}
+
+// DeleteHandler removes a monitor. ?purge=true also deletes its check history
+// from the TSDB.
+func DeleteHandler(configMonitorSvc ConfigMonitorService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		purge := false
+		if raw := r.URL.Query().Get("purge"); raw != "" {
+			var err error
+			purge, err = strconv.ParseBool(raw)
+			if err != nil {
+				pkg.JsonResponse(w, pkg.BaseResponse{
+					Success: false,
+					Message: fmt.Sprintf("invalid purge: %s", raw),
+					Data:    nil,
+				}, http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := configMonitorSvc.DeleteConfigMonitor(r.Context(), chi.URLParam(r, "id"), purge); err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    nil,
+		}, http.StatusOK)
+	}
+}
+
+// ListHandler serves the monitor list. It takes limit and offset or cursor
+// for pagination, type, status, tag, group and name filters, and sort with
+// order.
+func ListHandler(configMonitorSvc ConfigMonitorService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := parseListParams(r)
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		page, err := configMonitorSvc.SearchConfigMonitors(r.Context(), params)
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
 		pkg.JsonResponse(w, pkg.BaseResponse{
 			Success: true,
 			Message: "good",
 			Data: listResponse{
-				Total:    total,
-				Monitors: monitors,
+				Total:      page.Total,
+				Limit:      params.Limit,
+				Offset:     params.Offset,
+				NextCursor: page.NextCursor,
+				Monitors:   page.Monitors,
 			},
 		}, http.StatusOK)
 	}
 }
+
+func parseListParams(r *http.Request) (ListParams, error) {
+	query := r.URL.Query()
+	errs := &pkg.ValidationError{}
+
+	params := ListParams{
+		SearchFilter: SearchFilter{
+			Type:    query.Get("type"),
+			Name:    query.Get("name"),
+			Tag:     query.Get("tag"),
+			GroupID: query.Get("group"),
+		},
+		Status: query.Get("status"),
+		Sort:   SortName,
+		Limit:  DefaultListLimit,
+		Cursor: query.Get("cursor"),
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > MaxListLimit {
+			errs.Add("limit", fmt.Sprintf("must be between 1 and %d", MaxListLimit))
+		}
+		params.Limit = limit
+	}
+	if raw := query.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			errs.Add("offset", "must be a non-negative integer")
+		}
+		params.Offset = offset
+	}
+	if params.Cursor != "" && params.Offset != 0 {
+		errs.Add("cursor", "cannot be combined with offset")
+	}
+	if params.Type != "" && !contains(MonitorTypes, params.Type) {
+		errs.Add("type", fmt.Sprintf("must be one of %s", strings.Join(MonitorTypes, ", ")))
+	}
+	if params.Status != "" && !contains(Statuses, params.Status) {
+		errs.Add("status", fmt.Sprintf("must be one of %s", strings.Join(Statuses, ", ")))
+	}
+	if raw := query.Get("sort"); raw != "" {
+		if !contains(SortKeys, raw) {
+			errs.Add("sort", fmt.Sprintf("must be one of %s", strings.Join(SortKeys, ", ")))
+		}
+		params.Sort = raw
+	}
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		params.Desc = true
+	default:
+		errs.Add("order", "must be asc or desc")
+	}
+
+	if err := errs.Err(); err != nil {
+		return params, err
+	}
+	return params, nil
+}
+
+// errorStatuses maps service errors to status codes. Name conflicts carry
+// their field error as data.
+var errorStatuses = []pkg.ErrorStatus{
+	{Err: ErrConflict, Code: http.StatusConflict, Data: []pkg.FieldError{{Field: "name", Message: "is already used by another monitor"}}},
+	{Err: ErrNotFound, Code: http.StatusNotFound},
+	{Err: ErrInvalid, Code: http.StatusBadRequest},
+	{Err: ErrInvalidCursor, Code: http.StatusBadRequest},
+}
@@ -0,0 +1,221 @@
+package config_monitor
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+// Sort keys of the monitor list.
+const (
+	SortName      = "name"
+	SortLastCheck = "last_check"
+	SortUptime    = "uptime"
+)
+
+var SortKeys = []string{SortName, SortLastCheck, SortUptime}
+
+// StatusUnknown matches monitors that have not been checked since start.
+const StatusUnknown = "unknown"
+
+// Statuses are the values of the status filter.
+var Statuses = []string{pkg.StateUp.String(), pkg.StateDegraded.String(), pkg.StateDown.String(), StatusUnknown}
+
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+// uptimeWindow is the window the uptime sort key is computed over.
+const uptimeWindow = 24 * time.Hour
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type ListParams struct {
+	SearchFilter
+	Status string
+	Sort   string
+	Desc   bool
+	Limit  int
+	Offset int
+	// Cursor continues after the last monitor of a previous page and takes
+	// precedence over Offset.
+	Cursor string
+}
+
+type ListPage struct {
+	Monitors   []*pkg.ConfigMonitorDTO
+	Total      int
+	NextCursor string
+}
+
+// listItem is a monitor with its sort key. Items are ordered by num, then
+// str, then id so every position in the list is unique and a cursor can
+// point at it.
+type listItem struct {
+	config *pkg.ConfigMonitorDTO
+	num    float64
+	str    string
+}
+
+type listCursor struct {
+	Sort string  `json:"s"`
+	Desc bool    `json:"d,omitempty"`
+	Num  float64 `json:"n,omitempty"`
+	Str  string  `json:"v,omitempty"`
+	ID   string  `json:"id"`
+}
+
+func (s *configMonitorService) SearchConfigMonitors(ctx context.Context, params ListParams) (*ListPage, error) {
+	configs, err := s.configMonitorRepository.Search(ctx, params.SearchFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	var uptimes map[string]float64
+	if params.Sort == SortUptime {
+		uptimes, err = s.uptimeRatios(ctx, time.Now().Add(-uptimeWindow))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	items := make([]listItem, 0, len(configs))
+	for _, config := range configs {
+		status, checked := s.statuses.Get(config.ID)
+		if params.Status != "" {
+			state := StatusUnknown
+			if checked {
+				state = status.State
+			}
+			if state != params.Status {
+				continue
+			}
+		}
+
+		item := listItem{config: config, str: strings.ToLower(config.Name)}
+		switch params.Sort {
+		case SortLastCheck:
+			if checked {
+				item.num = float64(status.LastCheck.UnixMilli())
+			}
+		case SortUptime:
+			item.num = -1
+			if ratio, ok := uptimes[config.ID]; ok {
+				item.num = ratio
+			}
+		}
+		items = append(items, item)
+	}
+
+	less := func(a, b listItem) bool {
+		if a.num != b.num {
+			return a.num < b.num
+		}
+		if a.str != b.str {
+			return a.str < b.str
+		}
+		return a.config.ID < b.config.ID
+	}
+	if params.Desc {
+		asc := less
+		less = func(a, b listItem) bool { return asc(b, a) }
+	}
+	sort.Slice(items, func(i, j int) bool { return less(items[i], items[j]) })
+
+	start := params.Offset
+	if params.Cursor != "" {
+		cursor, err := decodeCursor(params.Cursor)
+		if err != nil || cursor.Sort != params.Sort || cursor.Desc != params.Desc {
+			return nil, ErrInvalidCursor
+		}
+		after := listItem{
+			config: &pkg.ConfigMonitorDTO{ID: cursor.ID},
+			num:    cursor.Num,
+			str:    cursor.Str,
+		}
+		start = sort.Search(len(items), func(i int) bool { return less(after, items[i]) })
+	}
+	start = min(start, len(items))
+	end := min(start+params.Limit, len(items))
+
+	page := &ListPage{
+		Monitors: make([]*pkg.ConfigMonitorDTO, 0, end-start),
+		Total:    len(items),
+	}
+	for _, item := range items[start:end] {
+		page.Monitors = append(page.Monitors, item.config)
+	}
+	if end < len(items) && end > start {
+		last := items[end-1]
+		page.NextCursor = encodeCursor(listCursor{
+			Sort: params.Sort,
+			Desc: params.Desc,
+			Num:  last.num,
+			Str:  last.str,
+			ID:   last.config.ID,
+		})
+	}
+
+	return page, nil
+}
+
+// uptimeRatios averages the up series of every monitor since the given time.
+func (s *configMonitorService) uptimeRatios(ctx context.Context, since time.Time) (map[string]float64, error) {
+	querier, err := s.tsdb.Querier(since.UnixMilli(), time.Now().UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("error creating querier: %v", err)
+	}
+	defer querier.Close()
+
+	seriesSet := querier.Select(ctx, false, nil,
+		labels.MustNewMatcher(labels.MatchEqual, "__name__", "up"),
+		pkg.OwnSeries,
+	)
+
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for seriesSet.Next() {
+		monitorID := seriesSet.At().Labels().Get("monitor_id")
+		iter := seriesSet.At().Iterator(nil)
+		for iter.Next() == chunkenc.ValFloat {
+			_, val := iter.At()
+			sums[monitorID] += val
+			counts[monitorID]++
+		}
+	}
+	if err := seriesSet.Err(); err != nil {
+		return nil, fmt.Errorf("error selecting up series: %v", err)
+	}
+
+	ratios := make(map[string]float64, len(sums))
+	for monitorID, sum := range sums {
+		ratios[monitorID] = sum / float64(counts[monitorID])
+	}
+
+	return ratios, nil
+}
+
+func encodeCursor(cursor listCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (listCursor, error) {
+	var cursor listCursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(raw, &cursor)
+	return cursor, err
+}
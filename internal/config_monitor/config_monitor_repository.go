@@ -3,12 +3,33 @@ package config_monitor
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
+	"github.com/afrianjunior/statx/internal/group"
 	"github.com/afrianjunior/statx/internal/pkg"
 	_ "github.com/glebarez/go-sqlite"
 )
 
+const configMonitorColumns = `
+	id, type, method, name, url, interval, icon, color, 
+	max_retry, retry_interval, call_method, call_encoding, 
+	call_body, call_headers, tls_config_id, proxy_url,
+	accepted_status_codes, follow_redirects, max_redirects, final_url_pattern,
+	degraded_threshold_ms, slo_percentile, slo_window,
+	content_check, content_ignore, labels,
+	raw_retention, rollup_retention, group_id,
+	badge_enabled, badge_token_hash,
+	(
+		SELECT group_concat(tag.name, char(10))
+		FROM config_monitor_tag
+		JOIN tag ON tag.id = config_monitor_tag.tag_id
+		WHERE config_monitor_tag.monitor_id = config_monitor.id
+	) AS tags
+`
+
 type configMonitorRepository struct {
 	db *sql.DB
 }
@@ -17,6 +38,13 @@ type ConfigMonitorRepository interface {
 	Insert(ctx context.Context, config *pkg.ConfigMonitorDTO) (string, error)
 	GetByID(ctx context.Context, id string) (*pkg.ConfigMonitorDTO, error)
 	List(ctx context.Context, limit, offset int) ([]*pkg.ConfigMonitorDTO, int, error)
+	// Search returns every monitor matching the type, name and tag filters,
+	// ordered by name.
+	Search(ctx context.Context, filter SearchFilter) ([]*pkg.ConfigMonitorDTO, error)
+	Update(ctx context.Context, config *pkg.ConfigMonitorDTO) (bool, error)
+	Delete(ctx context.Context, id string) (bool, error)
+	// NameTaken reports whether a monitor other than excludeID uses name.
+	NameTaken(ctx context.Context, name, excludeID string) (bool, error)
 }
 
 func NewConfigMonitorRepository(
@@ -28,15 +56,22 @@ func NewConfigMonitorRepository(
 }
 
 func (r *configMonitorRepository) Insert(ctx context.Context, config *pkg.ConfigMonitorDTO) (string, error) {
-	query := `
-		INSERT INTO config_monitor (
-			type, method, name, url, interval, icon, color, 
-			max_retry, retry_interval, call_method, call_encoding, 
-			call_body, call_headers
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
+	labels, err := json.Marshal(config.Labels)
+	if err != nil {
+		return "", fmt.Errorf("error encoding labels: %w", err)
+	}
 
-	result, err := r.db.ExecContext(ctx, query,
+	columns := []string{
+		"type", "method", "name", "url", "interval", "icon", "color",
+		"max_retry", "retry_interval", "call_method", "call_encoding",
+		"call_body", "call_headers", "tls_config_id", "proxy_url",
+		"accepted_status_codes", "final_url_pattern",
+		"degraded_threshold_ms", "slo_percentile", "slo_window",
+		"content_check", "content_ignore", "labels",
+		"raw_retention", "rollup_retention", "group_id",
+		"badge_enabled", "badge_token_hash",
+	}
+	args := []any{
 		config.Type,
 		config.Method,
 		config.Name,
@@ -50,8 +85,41 @@ func (r *configMonitorRepository) Insert(ctx context.Context, config *pkg.Config
 		config.CallEncoding,
 		config.CallBody,
 		config.CallHeaders,
-	)
+		sql.NullString{String: config.TLSConfigID, Valid: config.TLSConfigID != ""},
+		config.ProxyURL,
+		config.AcceptedStatusCodes,
+		config.FinalURLPattern,
+		config.DegradedThresholdMs,
+		config.SLOPercentile,
+		config.SLOWindow,
+		config.ContentCheck,
+		config.ContentIgnore,
+		string(labels),
+		config.RawRetention,
+		config.RollupRetention,
+		sql.NullString{String: config.GroupID, Valid: config.GroupID != ""},
+		config.BadgeEnabled,
+		sql.NullString{String: config.BadgeTokenHash, Valid: config.BadgeTokenHash != ""},
+	}
+	// Redirect settings left unset take the column defaults.
+	if config.FollowRedirects != nil {
+		columns = append(columns, "follow_redirects")
+		args = append(args, *config.FollowRedirects)
+	}
+	if config.MaxRedirects != 0 {
+		columns = append(columns, "max_redirects")
+		args = append(args, config.MaxRedirects)
+	}
+	query := fmt.Sprintf("INSERT INTO config_monitor (%s) VALUES (%s)",
+		strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
 
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		return "", fmt.Errorf("error inserting config monitor: %w", err)
 	}
@@ -64,56 +132,215 @@ func (r *configMonitorRepository) Insert(ctx context.Context, config *pkg.Config
 
 	// Fetch the generated UUID
 	var uuid string
-	err = r.db.QueryRowContext(ctx, "SELECT id FROM config_monitor WHERE rowid = ?", id).Scan(&uuid)
+	err = tx.QueryRowContext(ctx, "SELECT id FROM config_monitor WHERE rowid = ?", id).Scan(&uuid)
 	if err != nil {
 		return "", fmt.Errorf("error fetching generated UUID: %w", err)
 	}
 
+	if err := setTags(ctx, tx, uuid, config.Tags); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("error committing transaction: %w", err)
+	}
+
 	return uuid, nil
 }
 
-func (r *configMonitorRepository) GetByID(ctx context.Context, id string) (*pkg.ConfigMonitorDTO, error) {
+// columnDefault is the default value of a config_monitor column as declared
+// by the migrations, for UPDATEs that reset a column.
+func columnDefault(column string) string {
+	return fmt.Sprintf("(SELECT dflt_value FROM pragma_table_info('config_monitor') WHERE name = '%s')", column)
+}
+
+func (r *configMonitorRepository) Update(ctx context.Context, config *pkg.ConfigMonitorDTO) (bool, error) {
 	query := `
-		SELECT id, type, method, name, url, interval, icon, color, 
-			   max_retry, retry_interval, call_method, call_encoding, 
-			   call_body, call_headers
-		FROM config_monitor
+		UPDATE config_monitor SET
+			type = ?, method = ?, name = ?, url = ?, interval = ?, icon = ?, color = ?,
+			max_retry = ?, retry_interval = ?, call_method = ?, call_encoding = ?,
+			call_body = ?, call_headers = ?, tls_config_id = ?, proxy_url = ?,
+			accepted_status_codes = ?, follow_redirects = COALESCE(?, ` + columnDefault("follow_redirects") + `),
+			max_redirects = COALESCE(?, ` + columnDefault("max_redirects") + `), final_url_pattern = ?,
+			degraded_threshold_ms = ?, slo_percentile = ?, slo_window = ?,
+			content_check = ?, content_ignore = ?, labels = ?,
+			raw_retention = ?, rollup_retention = ?, group_id = ?,
+			badge_enabled = ?, badge_token_hash = ?
 		WHERE id = ?
 	`
 
-	var config pkg.ConfigMonitorDTO
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&config.ID,
-		&config.Type,
-		&config.Method,
-		&config.Name,
-		&config.URL,
-		&config.Interval,
-		&config.Icon,
-		&config.Color,
-		&config.MaxRetry,
-		&config.RetryInterval,
-		&config.CallMethod,
-		&config.CallEncoding,
-		&config.CallBody,
-		&config.CallHeaders,
+	// Redirect settings left unset go back to the column defaults, as on insert.
+	var followRedirects sql.NullBool
+	if config.FollowRedirects != nil {
+		followRedirects = sql.NullBool{Bool: *config.FollowRedirects, Valid: true}
+	}
+	maxRedirects := sql.NullInt64{Int64: int64(config.MaxRedirects), Valid: config.MaxRedirects != 0}
+
+	labels, err := json.Marshal(config.Labels)
+	if err != nil {
+		return false, fmt.Errorf("error encoding labels: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query,
+		config.Type,
+		config.Method,
+		config.Name,
+		config.URL,
+		config.Interval,
+		config.Icon,
+		config.Color,
+		config.MaxRetry,
+		config.RetryInterval,
+		config.CallMethod,
+		config.CallEncoding,
+		config.CallBody,
+		config.CallHeaders,
+		sql.NullString{String: config.TLSConfigID, Valid: config.TLSConfigID != ""},
+		config.ProxyURL,
+		config.AcceptedStatusCodes,
+		followRedirects,
+		maxRedirects,
+		config.FinalURLPattern,
+		config.DegradedThresholdMs,
+		config.SLOPercentile,
+		config.SLOWindow,
+		config.ContentCheck,
+		config.ContentIgnore,
+		string(labels),
+		config.RawRetention,
+		config.RollupRetention,
+		sql.NullString{String: config.GroupID, Valid: config.GroupID != ""},
+		config.BadgeEnabled,
+		sql.NullString{String: config.BadgeTokenHash, Valid: config.BadgeTokenHash != ""},
+		config.ID,
 	)
+	if err != nil {
+		return false, fmt.Errorf("error updating config monitor: %w", err)
+	}
 
+	affected, err := result.RowsAffected()
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("error fetching config monitor: %w", err)
+		return false, fmt.Errorf("error getting affected rows: %w", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if err := setTags(ctx, tx, config.ID, config.Tags); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// setTags replaces the tags of a monitor, creating tags that do not exist yet.
+func setTags(ctx context.Context, tx *sql.Tx, monitorID string, names []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM config_monitor_tag WHERE monitor_id = ?", monitorID); err != nil {
+		return fmt.Errorf("error clearing tags: %w", err)
+	}
+
+	for _, name := range names {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO tag (name) VALUES (?) ON CONFLICT (name) DO NOTHING", name); err != nil {
+			return fmt.Errorf("error creating tag %s: %w", name, err)
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO config_monitor_tag (monitor_id, tag_id)
+			SELECT ?, id FROM tag WHERE name = ?
+			ON CONFLICT DO NOTHING
+		`, monitorID, name)
+		if err != nil {
+			return fmt.Errorf("error tagging monitor with %s: %w", name, err)
 		}
+	}
+
+	return nil
+}
+
+// Delete removes the monitor together with every row kept for it in SQLite:
+// tag links, maintenance windows, the content snapshot, content changes and
+// check errors. Its TSDB series are left alone, see DeleteConfigMonitor.
+func (r *configMonitorRepository) Delete(ctx context.Context, id string) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM config_monitor WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting config monitor: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting affected rows: %w", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM maintenance_window WHERE monitor_id = ?", id); err != nil {
+		return false, fmt.Errorf("error deleting maintenance windows: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM config_monitor_tag WHERE monitor_id = ?", id); err != nil {
+		return false, fmt.Errorf("error deleting tags: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM content_snapshot WHERE monitor_id = ?", id); err != nil {
+		return false, fmt.Errorf("error deleting content snapshot: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM content_change WHERE monitor_id = ?", id); err != nil {
+		return false, fmt.Errorf("error deleting content changes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM check_error WHERE monitor_id = ?", id); err != nil {
+		return false, fmt.Errorf("error deleting check errors: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *configMonitorRepository) NameTaken(ctx context.Context, name, excludeID string) (bool, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM config_monitor WHERE lower(name) = lower(?) AND id != ?",
+		name, excludeID,
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("error checking monitor name: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (r *configMonitorRepository) GetByID(ctx context.Context, id string) (*pkg.ConfigMonitorDTO, error) {
+	query := `SELECT ` + configMonitorColumns + `
+		FROM config_monitor
+		WHERE id = ?
+	`
+
+	config, err := scanConfigMonitor(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
 		return nil, fmt.Errorf("error fetching config monitor: %w", err)
 	}
 
-	return &config, nil
+	return config, nil
 }
 
 func (r *configMonitorRepository) List(ctx context.Context, limit, offset int) ([]*pkg.ConfigMonitorDTO, int, error) {
-	query := `
-		SELECT id, type, method, name, url, interval, icon, color, 
-			   max_retry, retry_interval, call_method, call_encoding, 
-			   call_body, call_headers
+	query := `SELECT ` + configMonitorColumns + `
 		FROM config_monitor
 		LIMIT ? OFFSET ?
 	`
@@ -126,27 +353,11 @@ func (r *configMonitorRepository) List(ctx context.Context, limit, offset int) (
 
 	var configs []*pkg.ConfigMonitorDTO
 	for rows.Next() {
-		var config pkg.ConfigMonitorDTO
-		err := rows.Scan(
-			&config.ID,
-			&config.Type,
-			&config.Method,
-			&config.Name,
-			&config.URL,
-			&config.Interval,
-			&config.Icon,
-			&config.Color,
-			&config.MaxRetry,
-			&config.RetryInterval,
-			&config.CallMethod,
-			&config.CallEncoding,
-			&config.CallBody,
-			&config.CallHeaders,
-		)
+		config, err := scanConfigMonitor(rows)
 		if err != nil {
 			return nil, 0, fmt.Errorf("error scanning config monitor: %w", err)
 		}
-		configs = append(configs, &config)
+		configs = append(configs, config)
 	}
 
 	// Get total count
@@ -158,3 +369,158 @@ func (r *configMonitorRepository) List(ctx context.Context, limit, offset int) (
 
 	return configs, total, nil
 }
+
+// SearchFilter holds the filters that can be answered by SQLite. Empty
+// fields match everything.
+type SearchFilter struct {
+	Type string
+	// Name matches a case-insensitive substring of the monitor name.
+	Name string
+	// Tag matches a tag name, or user labels when given as a label name or
+	// a name:value pair.
+	Tag string
+	// GroupID matches monitors in the group or any of its subgroups.
+	GroupID string
+}
+
+func (r *configMonitorRepository) Search(ctx context.Context, filter SearchFilter) ([]*pkg.ConfigMonitorDTO, error) {
+	query := `SELECT ` + configMonitorColumns + `
+		FROM config_monitor
+		WHERE 1 = 1
+	`
+	var args []any
+
+	if filter.Type != "" {
+		query += ` AND type = ?`
+		args = append(args, filter.Type)
+	}
+	if filter.Name != "" {
+		query += ` AND instr(lower(name), lower(?)) > 0`
+		args = append(args, filter.Name)
+	}
+	if filter.Tag != "" {
+		tagQuery := ` AND (EXISTS (
+			SELECT 1 FROM config_monitor_tag
+			JOIN tag ON tag.id = config_monitor_tag.tag_id
+			WHERE config_monitor_tag.monitor_id = config_monitor.id AND tag.name = ?
+		)`
+		args = append(args, filter.Tag)
+
+		name, value, hasValue := strings.Cut(filter.Tag, ":")
+		if hasValue {
+			tagQuery += ` OR EXISTS (SELECT 1 FROM json_each(config_monitor.labels) WHERE key = ? AND value = ?))`
+			args = append(args, name, value)
+		} else {
+			tagQuery += ` OR EXISTS (SELECT 1 FROM json_each(config_monitor.labels) WHERE key = ?))`
+			args = append(args, name)
+		}
+		query += tagQuery
+	}
+	if filter.GroupID != "" {
+		query += ` AND group_id IN (` + group.SubtreeQuery + `)`
+		args = append(args, filter.GroupID)
+	}
+	query += ` ORDER BY lower(name), id`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying config monitors: %w", err)
+	}
+	defer rows.Close()
+
+	var configs []*pkg.ConfigMonitorDTO
+	for rows.Next() {
+		config, err := scanConfigMonitor(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning config monitor: %w", err)
+		}
+		configs = append(configs, config)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating config monitors: %w", err)
+	}
+
+	return configs, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanConfigMonitor reads a row selected with configMonitorColumns.
+func scanConfigMonitor(row rowScanner) (*pkg.ConfigMonitorDTO, error) {
+	var config pkg.ConfigMonitorDTO
+	var tlsConfigID, proxyURL, acceptedStatusCodes, finalURLPattern, contentIgnore, labels, groupID, badgeTokenHash, tags sql.NullString
+	var followRedirects, contentCheck sql.NullBool
+	var maxRedirects, degradedThresholdMs, sloWindow, rawRetention, rollupRetention sql.NullInt64
+	var sloPercentile sql.NullFloat64
+
+	err := row.Scan(
+		&config.ID,
+		&config.Type,
+		&config.Method,
+		&config.Name,
+		&config.URL,
+		&config.Interval,
+		&config.Icon,
+		&config.Color,
+		&config.MaxRetry,
+		&config.RetryInterval,
+		&config.CallMethod,
+		&config.CallEncoding,
+		&config.CallBody,
+		&config.CallHeaders,
+		&tlsConfigID,
+		&proxyURL,
+		&acceptedStatusCodes,
+		&followRedirects,
+		&maxRedirects,
+		&finalURLPattern,
+		&degradedThresholdMs,
+		&sloPercentile,
+		&sloWindow,
+		&contentCheck,
+		&contentIgnore,
+		&labels,
+		&rawRetention,
+		&rollupRetention,
+		&groupID,
+		&config.BadgeEnabled,
+		&badgeTokenHash,
+		&tags,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	config.TLSConfigID = tlsConfigID.String
+	config.ProxyURL = proxyURL.String
+	config.AcceptedStatusCodes = acceptedStatusCodes.String
+	if followRedirects.Valid {
+		config.FollowRedirects = &followRedirects.Bool
+	}
+	config.MaxRedirects = int(maxRedirects.Int64)
+	config.FinalURLPattern = finalURLPattern.String
+	config.DegradedThresholdMs = int(degradedThresholdMs.Int64)
+	config.SLOPercentile = sloPercentile.Float64
+	config.SLOWindow = int(sloWindow.Int64)
+	config.ContentCheck = contentCheck.Bool
+	config.ContentIgnore = contentIgnore.String
+	config.RawRetention = int(rawRetention.Int64)
+	config.RollupRetention = int(rollupRetention.Int64)
+	config.GroupID = groupID.String
+	config.BadgeTokenHash = badgeTokenHash.String
+	config.HasBadgeToken = badgeTokenHash.String != ""
+	config.Tags = []string{}
+	if tags.String != "" {
+		config.Tags = strings.Split(tags.String, "\n")
+		sort.Strings(config.Tags)
+	}
+	if labels.String != "" {
+		if err := json.Unmarshal([]byte(labels.String), &config.Labels); err != nil {
+			return nil, fmt.Errorf("error decoding labels: %w", err)
+		}
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,101 @@
+package config_monitor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+)
+
+func TestInsertLeavesRedirectDefaultsToTheSchema(t *testing.T) {
+	ctx := context.Background()
+	repository := NewConfigMonitorRepository(testutil.DB(t))
+
+	id, err := repository.Insert(ctx, &pkg.ConfigMonitorDTO{Type: "uptime", Name: "defaults", URL: "http://a", Interval: 60})
+	if err != nil {
+		t.Fatalf("inserting: %v", err)
+	}
+	config, err := repository.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("getting: %v", err)
+	}
+	if config.MaxRedirects != 10 || config.FollowRedirects == nil || !*config.FollowRedirects {
+		t.Errorf("max_redirects = %d, follow_redirects = %v, want the column defaults", config.MaxRedirects, config.FollowRedirects)
+	}
+
+	follow := false
+	id, err = repository.Insert(ctx, &pkg.ConfigMonitorDTO{Type: "uptime", Name: "set", URL: "http://b", Interval: 60, FollowRedirects: &follow, MaxRedirects: 3})
+	if err != nil {
+		t.Fatalf("inserting: %v", err)
+	}
+	if config, err = repository.GetByID(ctx, id); err != nil {
+		t.Fatalf("getting: %v", err)
+	}
+	if config.MaxRedirects != 3 || config.FollowRedirects == nil || *config.FollowRedirects {
+		t.Errorf("max_redirects = %d, follow_redirects = %v, want 3 and false", config.MaxRedirects, config.FollowRedirects)
+	}
+}
+
+func TestUpdateLeavesRedirectDefaultsToTheSchema(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.DB(t)
+	repository := NewConfigMonitorRepository(db)
+
+	follow := false
+	id, err := repository.Insert(ctx, &pkg.ConfigMonitorDTO{Type: "uptime", Name: "set", URL: "http://a", Interval: 60, FollowRedirects: &follow, MaxRedirects: 3})
+	if err != nil {
+		t.Fatalf("inserting: %v", err)
+	}
+
+	// a full update without the redirect settings resets them
+	if ok, err := repository.Update(ctx, &pkg.ConfigMonitorDTO{ID: id, Type: "uptime", Name: "set", URL: "http://a", Interval: 60}); err != nil || !ok {
+		t.Fatalf("updating: %v, %v", ok, err)
+	}
+	config, err := repository.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("getting: %v", err)
+	}
+	if config.MaxRedirects != 10 || config.FollowRedirects == nil || !*config.FollowRedirects {
+		t.Errorf("max_redirects = %d, follow_redirects = %v, want the column defaults", config.MaxRedirects, config.FollowRedirects)
+	}
+	var maxRedirectsType string
+	if err := db.QueryRow(`SELECT typeof(max_redirects) FROM config_monitor WHERE id = ?`, id).Scan(&maxRedirectsType); err != nil || maxRedirectsType != "integer" {
+		t.Errorf("max_redirects is stored as %s, %v", maxRedirectsType, err)
+	}
+}
+
+func TestDeleteLeavesNoRowsOfTheMonitor(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.DB(t)
+	repository := NewConfigMonitorRepository(db)
+
+	id, err := repository.Insert(ctx, &pkg.ConfigMonitorDTO{Type: "uptime", Name: "deleted", URL: "http://a", Interval: 60, Tags: []string{"prod"}})
+	if err != nil {
+		t.Fatalf("inserting: %v", err)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO maintenance_window (monitor_id, starts_at, ends_at) VALUES (?, 0, 1)",
+		"INSERT INTO content_snapshot (monitor_id, hash) VALUES (?, 'a')",
+		"INSERT INTO content_change (monitor_id, new_hash) VALUES (?, 'a')",
+		"INSERT INTO check_error (monitor_id, checked_at, class) VALUES (?, 0, 'timeout')",
+	} {
+		if _, err := db.ExecContext(ctx, stmt, id); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	if deleted, err := repository.Delete(ctx, id); err != nil || !deleted {
+		t.Fatalf("deleting: %v, %v", deleted, err)
+	}
+
+	for _, table := range []string{"config_monitor_tag", "maintenance_window", "content_snapshot", "content_change", "check_error"} {
+		var count int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table+" WHERE monitor_id = ?", id).Scan(&count); err != nil {
+			t.Fatalf("counting %s: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("%s has %d rows of the deleted monitor", table, count)
+		}
+	}
+}
@@ -2,29 +2,107 @@ package config_monitor
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 
+	"github.com/afrianjunior/statx/internal/group"
 	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/recorder"
+	"github.com/afrianjunior/statx/internal/tls_config"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
 )
 
+var (
+	ErrNotFound = errors.New("config monitor not found")
+	ErrConflict = errors.New("a monitor with this name already exists")
+	ErrInvalid  = errors.New("invalid req body")
+)
+
+// Reconciler applies monitor changes to the running checks.
+type Reconciler interface {
+	MonitorSaved(ctx context.Context, config *pkg.ConfigMonitorDTO)
+	MonitorDeleted(monitorID string)
+}
+
 type configMonitorService struct {
 	configMonitorRepository ConfigMonitorRepository
+	tlsConfigRepository     tls_config.TLSConfigRepository
+	groupRepository         group.GroupRepository
+	tsdb                    *tsdb.DB
+	config                  *pkg.Config
+	statuses                recorder.StatusTracker
+	reconciler              Reconciler
 }
 
 type ConfigMonitorService interface {
 	MutateConfigMonitor(ctx context.Context, payload *pkg.ConfigMonitorDTO) (string, error)
+	GetConfigMonitor(ctx context.Context, id string) (*pkg.ConfigMonitorDTO, error)
 	GetListConfigMonitors(ctx context.Context, limit, offset int) ([]*pkg.ConfigMonitorDTO, int, error)
+	// SearchConfigMonitors filters, sorts and paginates the monitors. The
+	// status filter and the last_check and uptime sort keys use the latest
+	// check results.
+	SearchConfigMonitors(ctx context.Context, params ListParams) (*ListPage, error)
+	// UpdateConfigMonitor replaces every field of the monitor with payload.
+	UpdateConfigMonitor(ctx context.Context, id string, payload *pkg.ConfigMonitorDTO) (*pkg.ConfigMonitorDTO, error)
+	// PatchConfigMonitor merges a JSON object into the stored monitor, only
+	// changing the fields present in patch. A null resets a field to its
+	// default.
+	PatchConfigMonitor(ctx context.Context, id string, patch []byte) (*pkg.ConfigMonitorDTO, error)
+	// DeleteConfigMonitor removes the monitor. With purgeSeries its TSDB
+	// series are deleted as well.
+	DeleteConfigMonitor(ctx context.Context, id string, purgeSeries bool) error
 }
 
 func NewConfigService(
 	configMonitorRepository ConfigMonitorRepository,
+	tlsConfigRepository tls_config.TLSConfigRepository,
+	groupRepository group.GroupRepository,
+	tsdb *tsdb.DB,
+	config *pkg.Config,
+	statuses recorder.StatusTracker,
+	reconciler Reconciler,
 ) ConfigMonitorService {
 	return &configMonitorService{
 		configMonitorRepository: configMonitorRepository,
+		tlsConfigRepository:     tlsConfigRepository,
+		groupRepository:         groupRepository,
+		tsdb:                    tsdb,
+		config:                  config,
+		statuses:                statuses,
+		reconciler:              reconciler,
 	}
 }
 
 func (s *configMonitorService) MutateConfigMonitor(ctx context.Context, payload *pkg.ConfigMonitorDTO) (string, error) {
-	return s.configMonitorRepository.Insert(ctx, payload)
+	if err := s.validate(ctx, "", payload); err != nil {
+		return "", err
+	}
+	setBadgeToken(payload)
+
+	id, err := s.configMonitorRepository.Insert(ctx, payload)
+	if err != nil {
+		return "", err
+	}
+
+	config, err := s.GetConfigMonitor(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	s.reconciler.MonitorSaved(ctx, config)
+
+	return id, nil
+}
+
+func (s *configMonitorService) GetConfigMonitor(ctx context.Context, id string) (*pkg.ConfigMonitorDTO, error) {
+	config, err := s.configMonitorRepository.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return config, err
 }
 
 func (s *configMonitorService) GetListConfigMonitors(ctx context.Context, limit, offset int) ([]*pkg.ConfigMonitorDTO, int, error) {
@@ -36,3 +114,158 @@ func (s *configMonitorService) GetListConfigMonitors(ctx context.Context, limit,
 
 	return list, total, err
 }
+
+func (s *configMonitorService) UpdateConfigMonitor(ctx context.Context, id string, payload *pkg.ConfigMonitorDTO) (*pkg.ConfigMonitorDTO, error) {
+	current, err := s.GetConfigMonitor(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.ID = id
+	payload.BadgeTokenHash = current.BadgeTokenHash
+	return s.update(ctx, payload)
+}
+
+func (s *configMonitorService) PatchConfigMonitor(ctx context.Context, id string, patch []byte) (*pkg.ConfigMonitorDTO, error) {
+	config, err := s.GetConfigMonitor(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err = resetNulls(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if err := json.Unmarshal(patch, config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	config.ID = id
+	return s.update(ctx, config)
+}
+
+func (s *configMonitorService) update(ctx context.Context, config *pkg.ConfigMonitorDTO) (*pkg.ConfigMonitorDTO, error) {
+	if err := s.validate(ctx, config.ID, config); err != nil {
+		return nil, err
+	}
+	setBadgeToken(config)
+
+	ok, err := s.configMonitorRepository.Update(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	updated, err := s.GetConfigMonitor(ctx, config.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.reconciler.MonitorSaved(ctx, updated)
+
+	return updated, nil
+}
+
+func (s *configMonitorService) DeleteConfigMonitor(ctx context.Context, id string, purgeSeries bool) error {
+	deleted, err := s.configMonitorRepository.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+
+	// Stop the checks first so they cannot write the status or the series
+	// back.
+	s.reconciler.MonitorDeleted(id)
+	s.statuses.Remove(id)
+
+	if purgeSeries {
+		matcher := labels.MustNewMatcher(labels.MatchEqual, "monitor_id", id)
+		if err := s.tsdb.Delete(ctx, math.MinInt64, math.MaxInt64, matcher); err != nil {
+			return fmt.Errorf("error deleting series of monitor %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// resetNulls replaces the null fields of a patch with the value of the field
+// in an empty monitor, so null resets a field like in a JSON merge patch
+// instead of being ignored by json.Unmarshal.
+func resetNulls(patch []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &fields); err != nil {
+		return nil, err
+	}
+
+	var defaults map[string]json.RawMessage
+	empty, err := json.Marshal(pkg.ConfigMonitorDTO{})
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(empty, &defaults); err != nil {
+		return nil, err
+	}
+
+	for name, value := range fields {
+		if string(value) != "null" {
+			continue
+		}
+		if zero, ok := defaults[name]; ok {
+			fields[name] = zero
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// setBadgeToken replaces a badge token sent with the request by its hash. An
+// empty token removes the stored one, no token keeps it.
+func setBadgeToken(config *pkg.ConfigMonitorDTO) {
+	if config.BadgeToken == nil {
+		return
+	}
+	config.BadgeTokenHash = ""
+	if *config.BadgeToken != "" {
+		config.BadgeTokenHash = pkg.HashToken(*config.BadgeToken)
+	}
+	config.BadgeToken = nil
+}
+
+// validate checks the fields of config and the constraints that need the
+// database or the TSDB: a unique name, an existing TLS config and group, and
+// retentions the TSDB can actually keep.
+func (s *configMonitorService) validate(ctx context.Context, id string, config *pkg.ConfigMonitorDTO) error {
+	errs := validateConfigMonitor(config)
+	validateRetention(errs, config, s.config.MaxRetention())
+	if config.TLSConfigID != "" {
+		_, err := s.tlsConfigRepository.GetByID(ctx, config.TLSConfigID)
+		if errors.Is(err, sql.ErrNoRows) {
+			errs.Add("tls_config_id", "tls config not found")
+		} else if err != nil {
+			return err
+		}
+	}
+	if config.GroupID != "" {
+		_, err := s.groupRepository.GetByID(ctx, config.GroupID)
+		if errors.Is(err, sql.ErrNoRows) {
+			errs.Add("group_id", "group not found")
+		} else if err != nil {
+			return err
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	taken, err := s.configMonitorRepository.NameTaken(ctx, config.Name, id)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrConflict
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package config_monitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/group"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/recorder"
+	"github.com/afrianjunior/statx/internal/testutil"
+	"github.com/afrianjunior/statx/internal/tls_config"
+)
+
+type nopReconciler struct{}
+
+func (nopReconciler) MonitorSaved(ctx context.Context, config *pkg.ConfigMonitorDTO) {}
+func (nopReconciler) MonitorDeleted(monitorID string)                                {}
+
+func newTestService(t *testing.T, config *pkg.Config) ConfigMonitorService {
+	db := testutil.DB(t)
+	return NewConfigService(
+		NewConfigMonitorRepository(db),
+		tls_config.NewTLSConfigRepository(db),
+		group.NewGroupRepository(db),
+		testutil.TSDB(t, config),
+		config,
+		recorder.NewStatusTracker(),
+		nopReconciler{},
+	)
+}
+
+// fieldErrors returns the fields err reports, failing when err is not a
+// validation error.
+func fieldErrors(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var validationErr *pkg.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got %v, want a validation error", err)
+	}
+	fields := make(map[string]string)
+	for _, fieldErr := range validationErr.Errors {
+		fields[fieldErr.Field] = fieldErr.Message
+	}
+	return fields
+}
+
+func TestRetentionBeyondTSDBRetentionIsRejected(t *testing.T) {
+	ctx := context.Background()
+	config := testutil.Config(t)
+	config.RetentionPolicies = map[string]pkg.RetentionPolicy{"uptime": {Rollup: 30 * 24 * time.Hour}}
+	service := newTestService(t, config)
+
+	monitor := &pkg.ConfigMonitorDTO{
+		Type:            "uptime",
+		Name:            "example",
+		URL:             "https://example.com",
+		Interval:        60,
+		RawRetention:    int((30*24*time.Hour + time.Second) / time.Second),
+		RollupRetention: int(30 * 24 * time.Hour / time.Second),
+	}
+	_, err := service.MutateConfigMonitor(ctx, monitor)
+	fields := fieldErrors(t, err)
+	if _, ok := fields["raw_retention"]; !ok {
+		t.Errorf("raw_retention above the TSDB retention was accepted: %v", fields)
+	}
+	if _, ok := fields["rollup_retention"]; ok {
+		t.Errorf("rollup_retention equal to the TSDB retention was rejected: %v", fields)
+	}
+
+	monitor.RawRetention = int(time.Hour / time.Second)
+	if _, err := service.MutateConfigMonitor(ctx, monitor); err != nil {
+		t.Fatalf("creating monitor within the TSDB retention: %v", err)
+	}
+}
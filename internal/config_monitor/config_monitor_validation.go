@@ -0,0 +1,170 @@
+package config_monitor
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/tag"
+)
+
+// Interval bounds in seconds.
+const (
+	MinInterval = 10
+	MaxInterval = 24 * 60 * 60
+)
+
+const (
+	// MaxNameLength is the longest monitor name in characters.
+	MaxNameLength    = 255
+	MaxSLOPercentile = 100
+)
+
+var (
+	// MonitorTypes are the accepted values of the type of a monitor.
+	MonitorTypes = []string{"uptime", "generic"}
+	HTTPMethods  = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	// NonNegativeFields are the integer fields of a monitor that must not
+	// be negative.
+	NonNegativeFields = []string{"max_retry", "retry_interval", "max_redirects", "degraded_threshold_ms", "slo_window", "raw_retention", "rollup_retention"}
+	proxySchemes      = []string{"http", "https", "socks5", "socks5h"}
+)
+
+// validateConfigMonitor checks the fields of a monitor. Uptime monitors need
+// an http(s) URL and an interval; generic monitors only receive pushed
+// metrics, so both are optional for them.
+func validateConfigMonitor(config *pkg.ConfigMonitorDTO) *pkg.ValidationError {
+	errs := &pkg.ValidationError{}
+
+	if strings.TrimSpace(config.Name) == "" {
+		errs.Add("name", "is required")
+	} else if utf8.RuneCountInString(config.Name) > MaxNameLength {
+		errs.Add("name", fmt.Sprintf("must be at most %d characters", MaxNameLength))
+	}
+
+	switch config.Type {
+	case "":
+		errs.Add("type", "is required")
+	case "uptime":
+		if config.URL == "" {
+			errs.Add("url", "is required for uptime monitors")
+		}
+		if config.Interval == 0 {
+			errs.Add("interval", "is required for uptime monitors")
+		}
+	case "generic":
+	default:
+		errs.Add("type", fmt.Sprintf("must be one of %s", strings.Join(MonitorTypes, ", ")))
+	}
+
+	if config.URL != "" {
+		if err := validateURL(config.URL, "http", "https"); err != nil {
+			errs.Add("url", err.Error())
+		}
+	}
+	if config.Interval != 0 && (config.Interval < MinInterval || config.Interval > MaxInterval) {
+		errs.Add("interval", fmt.Sprintf("must be between %d and %d seconds", MinInterval, MaxInterval))
+	}
+	if config.Method != "" && !contains(HTTPMethods, config.Method) {
+		errs.Add("method", fmt.Sprintf("must be one of %s", strings.Join(HTTPMethods, ", ")))
+	}
+	if config.ProxyURL != "" {
+		if err := validateURL(config.ProxyURL, proxySchemes...); err != nil {
+			errs.Add("proxy_url", err.Error())
+		}
+	}
+	if config.AcceptedStatusCodes != "" {
+		if _, err := pkg.ParseStatusCodeRanges(config.AcceptedStatusCodes); err != nil {
+			errs.Add("accepted_status_codes", err.Error())
+		}
+	}
+	if config.FinalURLPattern != "" {
+		if _, err := regexp.Compile(config.FinalURLPattern); err != nil {
+			errs.Add("final_url_pattern", fmt.Sprintf("invalid pattern: %v", err))
+		}
+	}
+	if config.SLOPercentile < 0 || config.SLOPercentile > MaxSLOPercentile {
+		errs.Add("slo_percentile", fmt.Sprintf("must be between 0 and %d", MaxSLOPercentile))
+	}
+
+	values := map[string]int{
+		"max_retry":             config.MaxRetry,
+		"retry_interval":        config.RetryInterval,
+		"max_redirects":         config.MaxRedirects,
+		"degraded_threshold_ms": config.DegradedThresholdMs,
+		"slo_window":            config.SLOWindow,
+		"raw_retention":         config.RawRetention,
+		"rollup_retention":      config.RollupRetention,
+	}
+	for _, field := range NonNegativeFields {
+		if values[field] < 0 {
+			errs.Add(field, "must not be negative")
+		}
+	}
+
+	seen := make(map[string]bool, len(config.Tags))
+	for _, name := range config.Tags {
+		if err := tag.ValidateName(name); err != nil {
+			errs.Add("tags", err.Error())
+		} else if seen[name] {
+			errs.Add("tags", fmt.Sprintf("%q is listed twice", name))
+		}
+		seen[name] = true
+	}
+
+	for name := range config.Labels {
+		if !labelNamePattern.MatchString(name) {
+			errs.Add("labels."+name, "must be a valid label name")
+		}
+	}
+
+	return errs
+}
+
+// validateRetention rejects retention overrides longer than the TSDB
+// retention, which is fixed when the TSDB is opened and would otherwise
+// silently cap them.
+func validateRetention(errs *pkg.ValidationError, config *pkg.ConfigMonitorDTO, ceiling time.Duration) {
+	limit := int(ceiling / time.Second)
+	retentions := []struct {
+		field string
+		value int
+	}{
+		{"raw_retention", config.RawRetention},
+		{"rollup_retention", config.RollupRetention},
+	}
+	for _, retention := range retentions {
+		if retention.value > limit {
+			errs.Add(retention.field, fmt.Sprintf("must be at most %d seconds, the retention of the TSDB", limit))
+		}
+	}
+}
+
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func validateURL(raw string, schemes ...string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid url: %v", err)
+	}
+	if !contains(schemes, u.Scheme) {
+		return fmt.Errorf("scheme must be one of %s", strings.Join(schemes, ", "))
+	}
+	if u.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
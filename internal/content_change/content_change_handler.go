@@ -0,0 +1,45 @@
+package content_change
+
+import (
+	"net/http"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+type listResponse struct {
+	Total   int                     `json:"total"`
+	Changes []*pkg.ContentChangeDTO `json:"changes"`
+}
+
+func ListHandler(contentChangeSvc ContentChangeService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		monitorID := r.URL.Query().Get("monitor_id")
+		if monitorID == "" {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: "monitor_id parameter is required",
+				Data:    nil,
+			}, http.StatusBadRequest)
+			return
+		}
+
+		changes, total, err := contentChangeSvc.GetListContentChanges(r.Context(), monitorID, 100, 0)
+		if err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data: listResponse{
+				Total:   total,
+				Changes: changes,
+			},
+		}, http.StatusOK)
+	}
+}
@@ -0,0 +1,146 @@
+package content_change
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	_ "github.com/glebarez/go-sqlite"
+)
+
+type Snapshot struct {
+	Hash string
+	Body string
+}
+
+type contentChangeRepository struct {
+	db *sql.DB
+}
+
+type ContentChangeRepository interface {
+	GetSnapshot(ctx context.Context, monitorID string) (*Snapshot, error)
+	SaveSnapshot(ctx context.Context, monitorID string, snapshot *Snapshot) error
+	InsertChange(ctx context.Context, change *pkg.ContentChangeDTO) (string, error)
+	ListChanges(ctx context.Context, monitorID string, limit, offset int) ([]*pkg.ContentChangeDTO, int, error)
+}
+
+func NewContentChangeRepository(
+	db *sql.DB,
+) ContentChangeRepository {
+	return &contentChangeRepository{
+		db,
+	}
+}
+
+// GetSnapshot returns nil without error when the monitor has no snapshot yet.
+func (r *contentChangeRepository) GetSnapshot(ctx context.Context, monitorID string) (*Snapshot, error) {
+	var snapshot Snapshot
+	var body sql.NullString
+	err := r.db.QueryRowContext(ctx,
+		"SELECT hash, body FROM content_snapshot WHERE monitor_id = ?", monitorID,
+	).Scan(&snapshot.Hash, &body)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching content snapshot: %w", err)
+	}
+	snapshot.Body = body.String
+
+	return &snapshot, nil
+}
+
+func (r *contentChangeRepository) SaveSnapshot(ctx context.Context, monitorID string, snapshot *Snapshot) error {
+	query := `
+		INSERT INTO content_snapshot (monitor_id, hash, body, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(monitor_id) DO UPDATE SET
+			hash = excluded.hash,
+			body = excluded.body,
+			updated_at = excluded.updated_at
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, monitorID, snapshot.Hash, snapshot.Body); err != nil {
+		return fmt.Errorf("error saving content snapshot: %w", err)
+	}
+	return nil
+}
+
+func (r *contentChangeRepository) InsertChange(ctx context.Context, change *pkg.ContentChangeDTO) (string, error) {
+	query := `
+		INSERT INTO content_change (
+			monitor_id, old_hash, new_hash, diff, truncated
+		) VALUES (?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		change.MonitorID,
+		change.OldHash,
+		change.NewHash,
+		change.Diff,
+		change.Truncated,
+	)
+	if err != nil {
+		return "", fmt.Errorf("error inserting content change: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return "", fmt.Errorf("error getting last insert ID: %w", err)
+	}
+
+	var uuid string
+	err = r.db.QueryRowContext(ctx, "SELECT id FROM content_change WHERE rowid = ?", id).Scan(&uuid)
+	if err != nil {
+		return "", fmt.Errorf("error fetching generated UUID: %w", err)
+	}
+
+	return uuid, nil
+}
+
+func (r *contentChangeRepository) ListChanges(ctx context.Context, monitorID string, limit, offset int) ([]*pkg.ContentChangeDTO, int, error) {
+	query := `
+		SELECT id, monitor_id, old_hash, new_hash, diff, truncated, created_at
+		FROM content_change
+		WHERE monitor_id = ?
+		ORDER BY created_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, monitorID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying content changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []*pkg.ContentChangeDTO
+	for rows.Next() {
+		var change pkg.ContentChangeDTO
+		var oldHash, diff sql.NullString
+		err := rows.Scan(
+			&change.ID,
+			&change.MonitorID,
+			&oldHash,
+			&change.NewHash,
+			&diff,
+			&change.Truncated,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning content change: %w", err)
+		}
+		change.OldHash = oldHash.String
+		change.Diff = diff.String
+		changes = append(changes, &change)
+	}
+
+	var total int
+	err = r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM content_change WHERE monitor_id = ?", monitorID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting total count: %w", err)
+	}
+
+	return changes, total, nil
+}
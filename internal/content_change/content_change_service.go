@@ -0,0 +1,155 @@
+package content_change
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+const (
+	// MaxBodySize is how much of a response body is read for hashing.
+	MaxBodySize = 1 << 20
+	// maxSnapshotSize bounds the normalized body kept for the next diff.
+	maxSnapshotSize = 256 << 10
+	// maxDiffSize bounds the diff stored per change.
+	maxDiffSize = 64 << 10
+)
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+type contentChangeService struct {
+	contentChangeRepository ContentChangeRepository
+
+	mu sync.Mutex
+	// ignore holds the compiled content_ignore patterns by monitor id.
+	ignore map[string]compiledIgnore
+}
+
+type compiledIgnore struct {
+	patterns []string
+	regexps  []*regexp.Regexp
+}
+
+type ContentChangeService interface {
+	// DetectChange compares body against the last snapshot of the target and
+	// records a change when the normalized content differs. The first
+	// snapshot of a target is stored without raising a change.
+	DetectChange(ctx context.Context, target pkg.Target, body []byte) (*pkg.ContentChangeDTO, error)
+	GetListContentChanges(ctx context.Context, monitorID string, limit, offset int) ([]*pkg.ContentChangeDTO, int, error)
+}
+
+func NewContentChangeService(
+	contentChangeRepository ContentChangeRepository,
+) ContentChangeService {
+	return &contentChangeService{
+		contentChangeRepository: contentChangeRepository,
+		ignore:                  make(map[string]compiledIgnore),
+	}
+}
+
+// Normalize blanks out the ignored regions and collapses whitespace so that
+// formatting-only changes do not count as content changes.
+func Normalize(body string, ignore []*regexp.Regexp) string {
+	for _, re := range ignore {
+		body = re.ReplaceAllString(body, "")
+	}
+
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(whitespace.ReplaceAllString(line, " "))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func compileIgnore(patterns []string) ([]*regexp.Regexp, error) {
+	ignore := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content_ignore pattern %q: %v", pattern, err)
+		}
+		ignore = append(ignore, re)
+	}
+	return ignore, nil
+}
+
+// ignoreFor returns the compiled content_ignore patterns of target. They are
+// compiled again only when the patterns of the monitor change.
+func (s *contentChangeService) ignoreFor(target pkg.Target) ([]*regexp.Regexp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached, ok := s.ignore[target.MonitorID()]
+	if ok && slices.Equal(cached.patterns, target.ContentIgnore) {
+		return cached.regexps, nil
+	}
+
+	regexps, err := compileIgnore(target.ContentIgnore)
+	if err != nil {
+		return nil, err
+	}
+	s.ignore[target.MonitorID()] = compiledIgnore{
+		patterns: slices.Clone(target.ContentIgnore),
+		regexps:  regexps,
+	}
+	return regexps, nil
+}
+
+func (s *contentChangeService) DetectChange(ctx context.Context, target pkg.Target, body []byte) (*pkg.ContentChangeDTO, error) {
+	ignore, err := s.ignoreFor(target)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := Normalize(string(body), ignore)
+	sum := sha256.Sum256([]byte(normalized))
+	hash := hex.EncodeToString(sum[:])
+
+	previous, err := s.contentChangeRepository.GetSnapshot(ctx, target.MonitorID())
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil && previous.Hash == hash {
+		return nil, nil
+	}
+
+	stored := pkg.TruncateString(normalized, maxSnapshotSize)
+	if err := s.contentChangeRepository.SaveSnapshot(ctx, target.MonitorID(), &Snapshot{Hash: hash, Body: stored}); err != nil {
+		return nil, err
+	}
+
+	if previous == nil {
+		return nil, nil
+	}
+
+	diff, truncated := pkg.LineDiff(previous.Body, stored, maxDiffSize)
+	change := &pkg.ContentChangeDTO{
+		MonitorID: target.MonitorID(),
+		OldHash:   previous.Hash,
+		NewHash:   hash,
+		Diff:      diff,
+		Truncated: truncated,
+	}
+
+	id, err := s.contentChangeRepository.InsertChange(ctx, change)
+	if err != nil {
+		return nil, err
+	}
+	change.ID = id
+
+	return change, nil
+}
+
+func (s *contentChangeService) GetListContentChanges(ctx context.Context, monitorID string, limit, offset int) ([]*pkg.ContentChangeDTO, int, error) {
+	return s.contentChangeRepository.ListChanges(ctx, monitorID, limit, offset)
+}
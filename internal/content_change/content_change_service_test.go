@@ -0,0 +1,86 @@
+package content_change
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+)
+
+func TestDetectChangeRecordsChangedContent(t *testing.T) {
+	ctx := context.Background()
+	repository := NewContentChangeRepository(testutil.DB(t))
+	service := NewContentChangeService(repository)
+	target := pkg.Target{ID: "a", URL: "http://a", ContentIgnore: []string{`token=\w+`}}
+
+	if change, err := service.DetectChange(ctx, target, []byte("hello\nworld token=1")); err != nil || change != nil {
+		t.Fatalf("first snapshot: %+v, %v", change, err)
+	}
+	// an ignored region and formatting do not count as a change
+	if change, err := service.DetectChange(ctx, target, []byte("hello\n  world   token=2\n")); err != nil || change != nil {
+		t.Fatalf("unchanged content: %+v, %v", change, err)
+	}
+	change, err := service.DetectChange(ctx, target, []byte("hello\nthere"))
+	if err != nil || change == nil {
+		t.Fatalf("changed content: %+v, %v", change, err)
+	}
+	if !strings.Contains(change.Diff, "- world") || !strings.Contains(change.Diff, "+ there") {
+		t.Errorf("diff = %q", change.Diff)
+	}
+
+	changes, total, err := repository.ListChanges(ctx, "a", 10, 0)
+	if err != nil {
+		t.Fatalf("listing changes: %v", err)
+	}
+	if total != 1 || changes[0].ID != change.ID || changes[0].Diff != change.Diff {
+		t.Errorf("listed %d changes: %+v", total, changes)
+	}
+}
+
+func TestIgnorePatternsAreCompiledOncePerMonitor(t *testing.T) {
+	service := NewContentChangeService(NewContentChangeRepository(testutil.DB(t))).(*contentChangeService)
+	target := pkg.Target{ID: "a", ContentIgnore: []string{`\d+`}}
+
+	first, err := service.ignoreFor(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := service.ignoreFor(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first[0] != again[0] {
+		t.Errorf("patterns were compiled again for an unchanged monitor")
+	}
+
+	target.ContentIgnore = []string{`[a-z]+`}
+	edited, err := service.ignoreFor(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited[0].String() != `[a-z]+` {
+		t.Errorf("edited patterns were not picked up: %s", edited[0])
+	}
+}
+
+func TestSnapshotIsTruncatedOnARuneBoundary(t *testing.T) {
+	ctx := context.Background()
+	repository := NewContentChangeRepository(testutil.DB(t))
+	service := NewContentChangeService(repository)
+
+	// "é" is two bytes and the odd prefix puts one across the limit
+	body := "x" + strings.Repeat("é", maxSnapshotSize)
+	if _, err := service.DetectChange(ctx, pkg.Target{ID: "a"}, []byte(body)); err != nil {
+		t.Fatalf("detecting change: %v", err)
+	}
+	snapshot, err := repository.GetSnapshot(ctx, "a")
+	if err != nil {
+		t.Fatalf("getting snapshot: %v", err)
+	}
+	if len(snapshot.Body) != maxSnapshotSize-1 || !utf8.ValidString(snapshot.Body) {
+		t.Errorf("snapshot of %d bytes, valid UTF-8: %v", len(snapshot.Body), utf8.ValidString(snapshot.Body))
+	}
+}
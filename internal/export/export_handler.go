@@ -0,0 +1,76 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"go.uber.org/zap"
+)
+
+// ExportHandler streams the check history of the monitors given in
+// monitor_id (repeatable or comma separated, all monitors when absent) as
+// format=csv or format=ndjson. The range is taken from start and end or
+// duration like /api/status. Errors after the response has started are
+// logged, as they can only cut the stream short.
+func ExportHandler(exportSvc ExportService, logger *zap.SugaredLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		var monitorIDs []string
+		for _, value := range query["monitor_id"] {
+			for _, id := range strings.Split(value, ",") {
+				if id = strings.TrimSpace(id); id != "" {
+					monitorIDs = append(monitorIDs, id)
+				}
+			}
+		}
+
+		timeRange, err := pkg.ParseTimeRange(query.Get("start"), query.Get("end"), query.Get("duration"))
+		if err == nil && timeRange.End.Before(timeRange.Start) {
+			err = fmt.Errorf("end is before start")
+		}
+		if err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: fmt.Sprintf("invalid time range: %v", err),
+				Data:    nil,
+			}, http.StatusBadRequest)
+			return
+		}
+
+		format := query.Get("format")
+		if format == "" {
+			format = FormatCSV
+		}
+
+		var flush func()
+		if flusher, ok := w.(http.Flusher); ok {
+			flush = flusher.Flush
+		}
+		rowWriter, err := NewRowWriter(format, w, flush)
+		if err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", ContentType(format))
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="statx-export-%s.%s"`, time.Now().Format("20060102150405"), format))
+		w.WriteHeader(http.StatusOK)
+
+		// The status is already sent, so a failure can only cut the
+		// stream short. A client going away is not worth a warning.
+		err = exportSvc.Export(r.Context(), monitorIDs, timeRange, rowWriter)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Warnf("Error exporting checks: %v", err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package export
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/check_error"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+// exportedMetrics are the series a check writes.
+const exportedMetrics = "up|http_status|http_response_time|check_error|monitor_state"
+
+type exportService struct {
+	tsdb        *tsdb.DB
+	checkErrors check_error.CheckErrorRepository
+}
+
+type ExportService interface {
+	// Export writes the checks of the monitors within timeRange, monitor by
+	// monitor in the given order and oldest first. Without monitor IDs every
+	// monitor with data in the range is exported.
+	Export(ctx context.Context, monitorIDs []string, timeRange pkg.TimeRange, w RowWriter) error
+}
+
+func NewExportService(
+	tsdb *tsdb.DB,
+	checkErrors check_error.CheckErrorRepository,
+) ExportService {
+	return &exportService{
+		tsdb,
+		checkErrors,
+	}
+}
+
+func (s *exportService) Export(ctx context.Context, monitorIDs []string, timeRange pkg.TimeRange, w RowWriter) error {
+	querier, err := s.tsdb.Querier(timeRange.Start.UnixMilli(), timeRange.End.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("error creating querier: %v", err)
+	}
+	defer querier.Close()
+
+	if len(monitorIDs) == 0 {
+		monitorIDs, _, err = querier.LabelValues(ctx, "monitor_id", nil,
+			labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "up"),
+			pkg.OwnSeries,
+		)
+		if err != nil {
+			return fmt.Errorf("error listing monitors: %v", err)
+		}
+	}
+
+	for _, monitorID := range monitorIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.exportMonitor(ctx, querier, monitorID, timeRange, w); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// exportMonitor merges the iterators of every series of a monitor by
+// timestamp, so only the current sample of each series is held in memory.
+func (s *exportService) exportMonitor(ctx context.Context, querier storage.Querier, monitorID string, timeRange pkg.TimeRange, w RowWriter) error {
+	messages, err := s.checkErrors.Messages(ctx, monitorID, timeRange.Start, timeRange.End)
+	if err != nil {
+		return err
+	}
+
+	seriesSet := querier.Select(ctx, false, nil,
+		labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, exportedMetrics),
+		labels.MustNewMatcher(labels.MatchEqual, "monitor_id", monitorID),
+		pkg.OwnSeries,
+	)
+
+	var cursors cursorHeap
+	for seriesSet.Next() {
+		series := seriesSet.At()
+		cursor := &cursor{labels: series.Labels(), iter: series.Iterator(nil)}
+		if cursor.next() {
+			cursors = append(cursors, cursor)
+		} else if err := cursor.iter.Err(); err != nil {
+			return fmt.Errorf("error reading series of %s: %v", monitorID, err)
+		}
+	}
+	if err := seriesSet.Err(); err != nil {
+		return fmt.Errorf("error selecting series of %s: %v", monitorID, err)
+	}
+	heap.Init(&cursors)
+
+	for cursors.Len() > 0 {
+		ts := cursors[0].ts
+		row := pkg.ExportRow{
+			Timestamp: time.UnixMilli(ts).UTC(),
+			MonitorID: monitorID,
+		}
+
+		for cursors.Len() > 0 && cursors[0].ts == ts {
+			c := cursors[0]
+			applySample(&row, c.labels, c.val)
+			if message, ok := messages[ts]; ok && row.ErrorClass != "" {
+				row.ErrorMessage = message
+			}
+			if c.next() {
+				heap.Fix(&cursors, 0)
+				continue
+			}
+			// an iterator also stops on a read error, which must not
+			// pass for the end of the series
+			if err := c.iter.Err(); err != nil {
+				return fmt.Errorf("error reading series of %s: %v", monitorID, err)
+			}
+			heap.Pop(&cursors)
+		}
+
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applySample(row *pkg.ExportRow, lset labels.Labels, val float64) {
+	if row.Name == "" {
+		row.Name = lset.Get("name")
+	}
+	if row.URL == "" {
+		row.URL = lset.Get("url")
+	}
+
+	switch lset.Get(labels.MetricName) {
+	case "up":
+		row.Up = val == 1
+	case "http_status":
+		row.StatusCode = int(val)
+	case "http_response_time":
+		v := val
+		row.ResponseTime = &v
+	case "check_error":
+		row.ErrorClass = lset.Get("class")
+		row.ErrorMessage = lset.Get("message")
+	case "monitor_state":
+		row.State = pkg.State(val).String()
+	}
+}
+
+type cursor struct {
+	labels labels.Labels
+	iter   chunkenc.Iterator
+	ts     int64
+	val    float64
+}
+
+func (c *cursor) next() bool {
+	if c.iter.Next() != chunkenc.ValFloat {
+		return false
+	}
+	c.ts, c.val = c.iter.At()
+	return true
+}
+
+// cursorHeap orders cursors by their current timestamp.
+type cursorHeap []*cursor
+
+func (h cursorHeap) Len() int           { return len(h) }
+func (h cursorHeap) Less(i, j int) bool { return h[i].ts < h[j].ts }
+func (h cursorHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *cursorHeap) Push(x any)        { *h = append(*h, x.(*cursor)) }
+func (h *cursorHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
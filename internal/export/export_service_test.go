@@ -0,0 +1,154 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/check_error"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/util/annotations"
+)
+
+type rowRecorder struct {
+	rows []pkg.ExportRow
+}
+
+func (r *rowRecorder) Write(row pkg.ExportRow) error {
+	r.rows = append(r.rows, row)
+	return nil
+}
+
+func (r *rowRecorder) Flush() error { return nil }
+
+func TestExportMergesSeriesIntoRows(t *testing.T) {
+	ctx := context.Background()
+	config := testutil.Config(t)
+	db := testutil.DB(t)
+	tsdb := testutil.TSDB(t, config)
+	checkErrors := check_error.NewCheckErrorRepository(db)
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	at := func(i int) int64 { return base.Add(time.Duration(i) * time.Minute).UnixMilli() }
+	series := func(metric string, extra ...string) labels.Labels {
+		return labels.FromStrings(append([]string{labels.MetricName, metric, "monitor_id", "a", "name", "A", "url", "http://a"}, extra...)...)
+	}
+
+	appender := tsdb.Appender(ctx)
+	// an up check, a failed one and another up check
+	for i, up := range []float64{1, 0, 1} {
+		appender.Append(0, series("up"), at(i), up)
+		appender.Append(0, series("monitor_state"), at(i), float64(map[float64]pkg.State{1: pkg.StateUp, 0: pkg.StateDown}[up]))
+	}
+	appender.Append(0, series("http_status"), at(0), 200)
+	appender.Append(0, series("http_response_time"), at(0), 42)
+	appender.Append(0, series("http_status"), at(2), 204)
+	appender.Append(0, series("check_error", "class", pkg.ErrorClassTimeout), at(1), 1)
+	// another monitor must not leak into the rows of a
+	appender.Append(0, labels.FromStrings(labels.MetricName, "up", "monitor_id", "b"), at(1), 1)
+	if err := appender.Commit(); err != nil {
+		t.Fatalf("committing: %v", err)
+	}
+	if err := checkErrors.Insert(ctx, "a", time.UnixMilli(at(1)), pkg.ErrorClassTimeout, "context deadline exceeded"); err != nil {
+		t.Fatalf("inserting message: %v", err)
+	}
+
+	rows := &rowRecorder{}
+	timeRange := pkg.TimeRange{Start: base.Add(-time.Minute), End: base.Add(time.Hour)}
+	if err := NewExportService(tsdb, checkErrors).Export(ctx, []string{"a"}, timeRange, rows); err != nil {
+		t.Fatalf("exporting: %v", err)
+	}
+
+	if len(rows.rows) != 3 {
+		t.Fatalf("got %d rows, want one per check: %+v", len(rows.rows), rows.rows)
+	}
+	first, failed, last := rows.rows[0], rows.rows[1], rows.rows[2]
+	if first.Timestamp.UnixMilli() != at(0) || failed.Timestamp.UnixMilli() != at(1) || last.Timestamp.UnixMilli() != at(2) {
+		t.Fatalf("rows are not in check order: %+v", rows.rows)
+	}
+	if !first.Up || first.StatusCode != 200 || first.ResponseTime == nil || *first.ResponseTime != 42 || first.State != "up" {
+		t.Errorf("first row = %+v", first)
+	}
+	if first.Name != "A" || first.URL != "http://a" || first.MonitorID != "a" {
+		t.Errorf("first row identity = %+v", first)
+	}
+	if failed.Up || failed.ErrorClass != pkg.ErrorClassTimeout || failed.ErrorMessage != "context deadline exceeded" || failed.State != "down" {
+		t.Errorf("failed row = %+v", failed)
+	}
+	if last.StatusCode != 204 || last.ErrorClass != "" || last.ResponseTime != nil {
+		t.Errorf("last row = %+v", last)
+	}
+}
+
+var errRead = errors.New("corrupt chunk")
+
+// failingQuerier returns a single series that breaks after one sample.
+type failingQuerier struct {
+	storage.Querier
+}
+
+func (failingQuerier) Select(context.Context, bool, *storage.SelectHints, ...*labels.Matcher) storage.SeriesSet {
+	return &singleSeriesSet{series: failingSeries{}}
+}
+
+type singleSeriesSet struct {
+	series storage.Series
+	done   bool
+}
+
+func (s *singleSeriesSet) Next() bool {
+	next := !s.done
+	s.done = true
+	return next
+}
+func (s *singleSeriesSet) At() storage.Series                { return s.series }
+func (s *singleSeriesSet) Err() error                        { return nil }
+func (s *singleSeriesSet) Warnings() annotations.Annotations { return nil }
+
+type failingSeries struct{}
+
+func (failingSeries) Labels() labels.Labels {
+	return labels.FromStrings(labels.MetricName, "up", "monitor_id", "a")
+}
+func (failingSeries) Iterator(chunkenc.Iterator) chunkenc.Iterator {
+	return &failingIterator{Iterator: chunkenc.NewNopIterator()}
+}
+
+type failingIterator struct {
+	chunkenc.Iterator
+	read bool
+}
+
+func (it *failingIterator) Next() chunkenc.ValueType {
+	if it.read {
+		return chunkenc.ValNone
+	}
+	it.read = true
+	return chunkenc.ValFloat
+}
+func (it *failingIterator) At() (int64, float64) { return 1, 1 }
+func (it *failingIterator) AtT() int64           { return 1 }
+func (it *failingIterator) Err() error {
+	if it.read {
+		return errRead
+	}
+	return nil
+}
+
+func TestExportReportsIteratorErrors(t *testing.T) {
+	ctx := context.Background()
+	service := NewExportService(nil, check_error.NewCheckErrorRepository(testutil.DB(t))).(*exportService)
+
+	rows := &rowRecorder{}
+	timeRange := pkg.TimeRange{Start: time.UnixMilli(0), End: time.Now()}
+	err := service.exportMonitor(ctx, failingQuerier{storage.NoopQuerier()}, "a", timeRange, rows)
+	if err == nil || !strings.Contains(err.Error(), errRead.Error()) {
+		t.Fatalf("exportMonitor returned %v, want the read error", err)
+	}
+}
@@ -0,0 +1,140 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+// Export formats.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// flushEvery is how many rows are buffered before they are flushed to the
+// client.
+const flushEvery = 1000
+
+// RowWriter encodes export rows one at a time.
+type RowWriter interface {
+	Write(row pkg.ExportRow) error
+	Flush() error
+}
+
+// NewRowWriter returns a writer for format. flush, if not nil, is called
+// after every flushEvery rows and at the end so the output streams.
+func NewRowWriter(format string, w io.Writer, flush func()) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w), flush: flush}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w), flush: flush}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected %s or %s", format, FormatCSV, FormatNDJSON)
+	}
+}
+
+// ContentType is the media type of an export format.
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+var csvHeader = []string{
+	"timestamp", "monitor_id", "name", "url", "up", "state",
+	"status_code", "response_time", "error_class", "error_message",
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	flush   func()
+	started bool
+	rows    int
+}
+
+func (c *csvWriter) Write(row pkg.ExportRow) error {
+	if !c.started {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.started = true
+	}
+
+	statusCode, responseTime := "", ""
+	if row.StatusCode != 0 {
+		statusCode = strconv.Itoa(row.StatusCode)
+	}
+	if row.ResponseTime != nil {
+		responseTime = strconv.FormatFloat(*row.ResponseTime, 'f', -1, 64)
+	}
+
+	err := c.w.Write([]string{
+		row.Timestamp.Format(time.RFC3339Nano),
+		row.MonitorID,
+		row.Name,
+		row.URL,
+		strconv.FormatBool(row.Up),
+		row.State,
+		statusCode,
+		responseTime,
+		row.ErrorClass,
+		row.ErrorMessage,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.rows++
+	if c.rows%flushEvery == 0 {
+		return c.Flush()
+	}
+	return nil
+}
+
+// Flush also writes the header of an export without rows.
+func (c *csvWriter) Flush() error {
+	if !c.started {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.started = true
+	}
+	c.w.Flush()
+	if c.flush != nil {
+		c.flush()
+	}
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	enc   *json.Encoder
+	flush func()
+	rows  int
+}
+
+func (n *ndjsonWriter) Write(row pkg.ExportRow) error {
+	if err := n.enc.Encode(row); err != nil {
+		return err
+	}
+
+	n.rows++
+	if n.rows%flushEvery == 0 {
+		return n.Flush()
+	}
+	return nil
+}
+
+func (n *ndjsonWriter) Flush() error {
+	if n.flush != nil {
+		n.flush()
+	}
+	return nil
+}
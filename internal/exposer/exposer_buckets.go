@@ -0,0 +1,234 @@
+package exposer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+// MaxPoints bounds the number of samples or buckets a status query returns.
+const MaxPoints = 11000
+
+var ErrTooManyPoints = fmt.Errorf("query would return more than %d points, use a larger step or a shorter range", MaxPoints)
+
+// QueryUpTimeBuckets aggregates the checks of a monitor into buckets of
+// step, aligned to multiples of step. Every bucket of the range is returned,
+// empty ones with a sample count of 0, so charts keep their gaps.
+//
+// When step is a multiple of a rollup resolution the buckets are built from
+// those rollups, which outlive the raw samples, and from raw samples after the
+// last rollup. Rollups keep neither status codes nor p50 and p99, so these are
+// only set for buckets of raw samples alone, and the p95 of a bucket holding
+// rollups is the highest p95 of its parts.
+func (s *exposerService) QueryUpTimeBuckets(ctx context.Context, monitorID string, timeRange pkg.TimeRange, step time.Duration) ([]pkg.QueryResult, error) {
+	if step <= 0 {
+		return nil, errors.New("step must be positive")
+	}
+	if bucketCount(timeRange, step) > MaxPoints {
+		return nil, ErrTooManyPoints
+	}
+	buckets := newBuckets(monitorID, timeRange, step)
+
+	rawStart := timeRange.Start
+	if res, ok := rollupFor(step); ok {
+		rollups, err := s.queryRollup(ctx, monitorID, timeRange, res)
+		if err != nil {
+			return nil, err
+		}
+		for _, rollup := range rollups {
+			buckets.addRollup(rollup)
+		}
+		if len(rollups) > 0 {
+			rawStart = rollups[len(rollups)-1].Timestamp.Add(res.Step)
+		}
+	}
+
+	if rawStart.Before(timeRange.End) {
+		samples, err := s.queryRaw(ctx, monitorID, pkg.TimeRange{Start: rawStart, End: timeRange.End})
+		if err != nil {
+			return nil, err
+		}
+		for _, sample := range samples {
+			buckets.addSample(sample)
+		}
+	}
+
+	return buckets.results(), nil
+}
+
+// bucketCount is the number of step aligned buckets touching timeRange.
+func bucketCount(timeRange pkg.TimeRange, step time.Duration) int64 {
+	stepMs := step.Milliseconds()
+	first := timeRange.Start.UnixMilli() - timeRange.Start.UnixMilli()%stepMs
+	last := timeRange.End.UnixMilli() - timeRange.End.UnixMilli()%stepMs
+	return (last-first)/stepMs + 1
+}
+
+// rollupFor is the coarsest rollup resolution whose buckets fit a whole
+// number of times into step.
+func rollupFor(step time.Duration) (pkg.Resolution, bool) {
+	for i := len(pkg.Resolutions) - 1; i >= 0; i-- {
+		if res := pkg.Resolutions[i]; step%res.Step == 0 {
+			return res, true
+		}
+	}
+	return pkg.Resolution{}, false
+}
+
+type bucket struct {
+	result pkg.QueryResult
+	up     float64
+	// latencies of raw samples, kept for the percentiles
+	latencies []float64
+	// latencySum and latencyCount also include the rollups, weighted by
+	// their sample count
+	latencySum   float64
+	latencyCount float64
+	latencyMin   *float64
+	latencyMax   *float64
+	rollupP95    *float64
+	rolledUp     bool
+}
+
+func (b *bucket) addLatency(min, max float64) {
+	if b.latencyMin == nil || min < *b.latencyMin {
+		b.latencyMin = &min
+	}
+	if b.latencyMax == nil || max > *b.latencyMax {
+		b.latencyMax = &max
+	}
+}
+
+type bucketSet struct {
+	first   int64
+	stepMs  int64
+	buckets []bucket
+}
+
+func newBuckets(monitorID string, timeRange pkg.TimeRange, step time.Duration) *bucketSet {
+	stepMs := step.Milliseconds()
+	first := timeRange.Start.UnixMilli() - timeRange.Start.UnixMilli()%stepMs
+
+	count := bucketCount(timeRange, step)
+	set := &bucketSet{first: first, stepMs: stepMs, buckets: make([]bucket, 0, count)}
+	for i := int64(0); i < count; i++ {
+		set.buckets = append(set.buckets, bucket{
+			result: pkg.QueryResult{
+				MonitorID:  monitorID,
+				Timestamp:  time.UnixMilli(first + i*stepMs),
+				Resolution: step.String(),
+			},
+		})
+	}
+	return set
+}
+
+func (s *bucketSet) at(t time.Time) *bucket {
+	i := (t.UnixMilli() - s.first) / s.stepMs
+	if i < 0 || i >= int64(len(s.buckets)) {
+		return nil
+	}
+	return &s.buckets[i]
+}
+
+func (s *bucketSet) addSample(sample pkg.QueryResult) {
+	b := s.at(sample.Timestamp)
+	if b == nil {
+		return
+	}
+
+	b.result.URL = sample.URL
+	b.result.SampleCount++
+	if b.result.StatusCodes == nil {
+		b.result.StatusCodes = make(map[int]int)
+	}
+	b.result.StatusCodes[sample.Status]++
+	if sample.Up {
+		b.up++
+	}
+	if sample.ResponseTime != nil {
+		latency := *sample.ResponseTime
+		b.latencies = append(b.latencies, latency)
+		b.latencySum += latency
+		b.latencyCount++
+		b.addLatency(latency, latency)
+	}
+}
+
+func (s *bucketSet) addRollup(rollup pkg.QueryResult) {
+	b := s.at(rollup.Timestamp)
+	if b == nil || rollup.SampleCount == 0 {
+		return
+	}
+
+	b.rolledUp = true
+	b.result.URL = rollup.URL
+	b.result.SampleCount += rollup.SampleCount
+	if rollup.UptimeRatio != nil {
+		b.up += *rollup.UptimeRatio * float64(rollup.SampleCount)
+	}
+	if rollup.ResponseTime != nil {
+		b.latencySum += *rollup.ResponseTime * float64(rollup.SampleCount)
+		b.latencyCount += float64(rollup.SampleCount)
+	}
+	if rollup.LatencyMin != nil && rollup.LatencyMax != nil {
+		b.addLatency(*rollup.LatencyMin, *rollup.LatencyMax)
+	}
+	if rollup.LatencyP95 != nil && (b.rollupP95 == nil || *rollup.LatencyP95 > *b.rollupP95) {
+		b.rollupP95 = rollup.LatencyP95
+	}
+}
+
+func (s *bucketSet) results() []pkg.QueryResult {
+	results := make([]pkg.QueryResult, 0, len(s.buckets))
+	for _, b := range s.buckets {
+		result := b.result
+		if result.SampleCount > 0 {
+			ratio := b.up / float64(result.SampleCount)
+			result.UptimeRatio = &ratio
+			result.Up = ratio == 1
+			if !b.rolledUp {
+				result.Status = dominantStatus(result.StatusCodes)
+			} else {
+				result.StatusCodes = nil
+			}
+		}
+
+		if b.latencyCount > 0 {
+			avg := b.latencySum / b.latencyCount
+			result.ResponseTime = &avg
+			result.LatencyMin = b.latencyMin
+			result.LatencyMax = b.latencyMax
+		}
+		if len(b.latencies) > 0 {
+			p95 := pkg.Percentile(b.latencies, 95)
+			result.LatencyP95 = &p95
+			if !b.rolledUp {
+				p50 := pkg.Percentile(b.latencies, 50)
+				p99 := pkg.Percentile(b.latencies, 99)
+				result.LatencyP50 = &p50
+				result.LatencyP99 = &p99
+			}
+		}
+		if b.rollupP95 != nil && (result.LatencyP95 == nil || *b.rollupP95 > *result.LatencyP95) {
+			result.LatencyP95 = b.rollupP95
+		}
+
+		results = append(results, result)
+	}
+	return results
+}
+
+// dominantStatus is the most frequent status code, the highest one on ties.
+func dominantStatus(codes map[int]int) int {
+	status, count := 0, 0
+	for code, n := range codes {
+		if n > count || (n == count && code > status) {
+			status, count = code, n
+		}
+	}
+	return status
+}
@@ -1,24 +1,33 @@
 package exposer
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/afrianjunior/statx/internal/maintenance"
 	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/go-chi/chi/v5"
 )
 
+const defaultUptimeWindows = "24h,7d,30d,90d"
+
 func StatusHandler(exposerSvc ExposerService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		url := r.URL.Query().Get("url")
+		monitorID := r.URL.Query().Get("monitor_id")
 		start := r.URL.Query().Get("start")
 		end := r.URL.Query().Get("end")
 		duration := r.URL.Query().Get("duration")
+		resolution := r.URL.Query().Get("resolution")
+		step := r.URL.Query().Get("step")
 
-		if url == "" {
+		if monitorID == "" {
 			pkg.JsonResponse(w, pkg.BaseResponse{
 				Success: false,
-				Message: "url parameter is required",
+				Message: "monitor_id parameter is required",
 				Data:    nil,
 			}, http.StatusBadRequest)
 			return
@@ -34,13 +43,31 @@ func StatusHandler(exposerSvc ExposerService) http.HandlerFunc {
 			return
 		}
 
-		results, err := exposerSvc.QueryUpTimeStatus(ctx, url, timeRange)
+		var results []pkg.QueryResult
+		if step != "" {
+			stepDuration, err := pkg.ParseWindow(step)
+			if err != nil {
+				pkg.JsonResponse(w, pkg.BaseResponse{
+					Success: false,
+					Message: fmt.Sprintf("invalid step: %v", err),
+					Data:    nil,
+				}, http.StatusBadRequest)
+				return
+			}
+			results, err = exposerSvc.QueryUpTimeBuckets(ctx, monitorID, timeRange, stepDuration)
+		} else {
+			results, err = exposerSvc.QueryUpTimeStatus(ctx, monitorID, timeRange, resolution)
+		}
 		if err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, ErrTooManyPoints) {
+				code = http.StatusBadRequest
+			}
 			pkg.JsonResponse(w, pkg.BaseResponse{
 				Success: false,
 				Message: err.Error(),
 				Data:    nil,
-			}, http.StatusInternalServerError)
+			}, code)
 			return
 		}
 
@@ -51,3 +78,73 @@ func StatusHandler(exposerSvc ExposerService) http.HandlerFunc {
 		}, http.StatusOK)
 	}
 }
+
+// UptimeHandler returns the uptime summary of a monitor per window. With
+// exclude_maintenance=true the monitor's maintenance windows are left out.
+func UptimeHandler(exposerSvc ExposerService, maintenanceSvc maintenance.MaintenanceService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		monitorID := chi.URLParam(r, "id")
+
+		windowsParam := r.URL.Query().Get("windows")
+		if windowsParam == "" {
+			windowsParam = defaultUptimeWindows
+		}
+		windows, err := pkg.ParseWindows(windowsParam)
+		if err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, http.StatusBadRequest)
+			return
+		}
+
+		excludeMaintenance := false
+		if value := r.URL.Query().Get("exclude_maintenance"); value != "" {
+			excludeMaintenance, err = strconv.ParseBool(value)
+			if err != nil {
+				pkg.JsonResponse(w, pkg.BaseResponse{
+					Success: false,
+					Message: "invalid exclude_maintenance parameter",
+					Data:    nil,
+				}, http.StatusBadRequest)
+				return
+			}
+		}
+
+		now := time.Now()
+		var excluded []pkg.TimeRange
+		if excludeMaintenance {
+			var longest time.Duration
+			for _, window := range windows {
+				longest = max(longest, window.Duration)
+			}
+			excluded, err = maintenanceSvc.ExcludedRanges(ctx, monitorID, pkg.TimeRange{Start: now.Add(-longest), End: now})
+			if err != nil {
+				pkg.JsonResponse(w, pkg.BaseResponse{
+					Success: false,
+					Message: err.Error(),
+					Data:    nil,
+				}, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		summaries, err := exposerSvc.QueryUptimeSummary(ctx, monitorID, windows, now, excluded)
+		if err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    summaries,
+		}, http.StatusOK)
+	}
+}
@@ -3,8 +3,10 @@ package exposer
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
+	"github.com/afrianjunior/statx/internal/check_error"
 	"github.com/afrianjunior/statx/internal/pkg"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/tsdb"
@@ -12,20 +14,127 @@ import (
 )
 
 type exposerService struct {
-	tsdb *tsdb.DB
+	tsdb        *tsdb.DB
+	checkErrors check_error.CheckErrorRepository
 }
 
 type ExposerService interface {
-	QueryUpTimeStatus(ctx context.Context, url string, timeRange pkg.TimeRange) ([]pkg.QueryResult, error)
+	// QueryUpTimeStatus returns raw samples or rollup buckets of a monitor.
+	// An empty resolution picks one from the length of the range. Buckets
+	// the rollup job has not written yet are aggregated from raw samples.
+	QueryUpTimeStatus(ctx context.Context, monitorID string, timeRange pkg.TimeRange, resolution string) ([]pkg.QueryResult, error)
+	// QueryUpTimeBuckets aggregates checks into step sized buckets, from
+	// rollups where step allows and raw samples after them.
+	QueryUpTimeBuckets(ctx context.Context, monitorID string, timeRange pkg.TimeRange, step time.Duration) ([]pkg.QueryResult, error)
+	QueryUptimeSummary(ctx context.Context, monitorID string, windows []pkg.Window, now time.Time, excluded []pkg.TimeRange) ([]pkg.UptimeSummary, error)
 }
 
-func NewExposerService(db *tsdb.DB) ExposerService {
+func NewExposerService(db *tsdb.DB, checkErrors check_error.CheckErrorRepository) ExposerService {
 	return &exposerService{
 		db,
+		checkErrors,
 	}
 }
 
-func (s *exposerService) QueryUpTimeStatus(ctx context.Context, url string, timeRange pkg.TimeRange) ([]pkg.QueryResult, error) {
+func (s *exposerService) QueryUpTimeStatus(ctx context.Context, monitorID string, timeRange pkg.TimeRange, resolution string) ([]pkg.QueryResult, error) {
+	if resolution == "" {
+		resolution = pkg.ResolutionFor(timeRange)
+	}
+	if resolution == pkg.ResolutionRaw {
+		return s.queryRawLimited(ctx, monitorID, timeRange)
+	}
+
+	res, ok := pkg.LookupResolution(resolution)
+	if !ok {
+		return nil, fmt.Errorf("unknown resolution %q", resolution)
+	}
+	// rollups and the raw tail share the buckets of the range, so their
+	// count bounds the response
+	if bucketCount(timeRange, res.Step) > MaxPoints {
+		return nil, ErrTooManyPoints
+	}
+
+	results, err := s.queryRollup(ctx, monitorID, timeRange, res)
+	if err != nil {
+		return nil, err
+	}
+
+	// rollups are written behind real time; the buckets after the last one
+	// are aggregated from the raw samples
+	tailStart := timeRange.Start
+	if len(results) > 0 {
+		tailStart = results[len(results)-1].Timestamp.Add(res.Step)
+	}
+	if !tailStart.Before(timeRange.End) {
+		return results, nil
+	}
+	tail, err := s.QueryUpTimeBuckets(ctx, monitorID, pkg.TimeRange{Start: tailStart, End: timeRange.End}, res.Step)
+	if err != nil {
+		return nil, err
+	}
+	for _, bucket := range tail {
+		if bucket.SampleCount == 0 {
+			continue
+		}
+		bucket.Resolution = res.Name
+		results = append(results, bucket)
+	}
+	return results, nil
+}
+
+// queryRawLimited is queryRaw for responses, refusing to return more than
+// MaxPoints samples. The checks are counted first, so an oversized range is
+// refused before its samples are loaded.
+func (s *exposerService) queryRawLimited(ctx context.Context, monitorID string, timeRange pkg.TimeRange) ([]pkg.QueryResult, error) {
+	if err := s.checkRawPoints(ctx, monitorID, timeRange); err != nil {
+		return nil, err
+	}
+	results, err := s.queryRaw(ctx, monitorID, timeRange)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > MaxPoints {
+		return nil, ErrTooManyPoints
+	}
+	return results, nil
+}
+
+// checkRawPoints counts the up samples of a monitor in timeRange and returns
+// ErrTooManyPoints as soon as they exceed MaxPoints.
+func (s *exposerService) checkRawPoints(ctx context.Context, monitorID string, timeRange pkg.TimeRange) error {
+	querier, err := s.tsdb.Querier(
+		timeRange.Start.UnixMilli(),
+		timeRange.End.UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating querier: %v", err)
+	}
+	defer querier.Close()
+
+	upSeries := querier.Select(ctx, false, nil,
+		labels.MustNewMatcher(labels.MatchEqual, "__name__", "up"),
+		labels.MustNewMatcher(labels.MatchEqual, "monitor_id", monitorID),
+		pkg.OwnSeries,
+	)
+	count := 0
+	for upSeries.Next() {
+		iter := upSeries.At().Iterator(nil)
+		for iter.Next() == chunkenc.ValFloat {
+			if count++; count > MaxPoints {
+				return ErrTooManyPoints
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return fmt.Errorf("error reading up series: %v", err)
+		}
+	}
+	if err := upSeries.Err(); err != nil {
+		return fmt.Errorf("error selecting up series: %v", err)
+	}
+	return nil
+}
+
+func (s *exposerService) queryRollup(ctx context.Context, monitorID string, timeRange pkg.TimeRange, res pkg.Resolution) ([]pkg.QueryResult, error) {
 	querier, err := s.tsdb.Querier(
 		timeRange.Start.UnixMilli(),
 		timeRange.End.UnixMilli(),
@@ -35,44 +144,207 @@ func (s *exposerService) QueryUpTimeStatus(ctx context.Context, url string, time
 	}
 	defer querier.Close()
 
-	statusMatchers := []*labels.Matcher{
-		labels.MustNewMatcher(labels.MatchEqual, "__name__", "http_status"),
-		labels.MustNewMatcher(labels.MatchEqual, "url", url),
+	seriesSet := querier.Select(ctx, false, nil,
+		labels.MustNewMatcher(labels.MatchRegexp, "__name__", "rollup_.+"),
+		labels.MustNewMatcher(labels.MatchEqual, "monitor_id", monitorID),
+		labels.MustNewMatcher(labels.MatchEqual, "resolution", res.Name),
+		pkg.OwnSeries,
+	)
+
+	buckets := make(map[int64]*pkg.QueryResult)
+	for seriesSet.Next() {
+		lset := seriesSet.At().Labels()
+		name := lset.Get("__name__")
+		iter := seriesSet.At().Iterator(nil)
+		for iter.Next() == chunkenc.ValFloat {
+			ts, val := iter.At()
+			bucket, ok := buckets[ts]
+			if !ok {
+				bucket = &pkg.QueryResult{
+					MonitorID:  monitorID,
+					URL:        lset.Get("url"),
+					Timestamp:  time.UnixMilli(ts),
+					Resolution: res.Name,
+				}
+				buckets[ts] = bucket
+			}
+
+			v := val
+			switch name {
+			case pkg.RollupUptimeRatio:
+				bucket.UptimeRatio = &v
+				bucket.Up = v == 1
+			case pkg.RollupLatencyAvg:
+				bucket.ResponseTime = &v
+			case pkg.RollupLatencyMin:
+				bucket.LatencyMin = &v
+			case pkg.RollupLatencyMax:
+				bucket.LatencyMax = &v
+			case pkg.RollupLatencyP95:
+				bucket.LatencyP95 = &v
+			case pkg.RollupSampleCount:
+				bucket.SampleCount = int(v)
+			}
+		}
+	}
+	if err := seriesSet.Err(); err != nil {
+		return nil, fmt.Errorf("error selecting rollups: %v", err)
 	}
 
-	responseTimeMatchers := []*labels.Matcher{
-		labels.MustNewMatcher(labels.MatchEqual, "__name__", "http_response_time"),
-		labels.MustNewMatcher(labels.MatchEqual, "url", url),
+	results := make([]pkg.QueryResult, 0, len(buckets))
+	for _, bucket := range buckets {
+		results = append(results, *bucket)
 	}
-	statusSeries := querier.Select(ctx, false, nil, statusMatchers...)
-	responseTimeSeries := querier.Select(ctx, false, nil, responseTimeMatchers...)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Timestamp.Before(results[j].Timestamp)
+	})
+
+	return results, nil
+}
+
+func (s *exposerService) queryRaw(ctx context.Context, monitorID string, timeRange pkg.TimeRange) ([]pkg.QueryResult, error) {
+	querier, err := s.tsdb.Querier(
+		timeRange.Start.UnixMilli(),
+		timeRange.End.UnixMilli(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating querier: %v", err)
+	}
+	defer querier.Close()
+
+	matchers := func(metric string) []*labels.Matcher {
+		return []*labels.Matcher{
+			labels.MustNewMatcher(labels.MatchEqual, "__name__", metric),
+			labels.MustNewMatcher(labels.MatchEqual, "monitor_id", monitorID),
+			pkg.OwnSeries,
+		}
+	}
+
+	statusSeries := querier.Select(ctx, false, nil, matchers("http_status")...)
+	responseTimeSeries := querier.Select(ctx, false, nil, matchers("http_response_time")...)
+	upSeries := querier.Select(ctx, false, nil, matchers("up")...)
+	checkErrorSeries := querier.Select(ctx, false, nil, matchers("check_error")...)
+	stateSeries := querier.Select(ctx, false, nil, matchers("monitor_state")...)
 
 	statusResults := make(map[int64]int)
+	statusURLs := make(map[int64]string)
 	for statusSeries.Next() {
+		url := statusSeries.At().Labels().Get("url")
 		iter := statusSeries.At().Iterator(nil)
 		for iter.Next() == chunkenc.ValFloat {
 			ts, val := iter.At()
 			statusResults[ts] = int(val)
+			statusURLs[ts] = url
 		}
 	}
 
-	var results []pkg.QueryResult
+	responseTimes := make(map[int64]float64)
 	for responseTimeSeries.Next() {
 		iter := responseTimeSeries.At().Iterator(nil)
 		for iter.Next() == chunkenc.ValFloat {
 			ts, val := iter.At()
-			responseTime := float64(val)
-			status, exists := statusResults[ts]
-			if exists {
-				results = append(results, pkg.QueryResult{
-					URL:          url,
-					Timestamp:    time.Unix(0, ts*int64(time.Millisecond)),
-					Status:       status,
-					ResponseTime: responseTime,
-				})
+			responseTimes[ts] = val
+		}
+	}
+
+	stateResults := make(map[int64]pkg.State)
+	for stateSeries.Next() {
+		iter := stateSeries.At().Iterator(nil)
+		for iter.Next() == chunkenc.ValFloat {
+			ts, val := iter.At()
+			stateResults[ts] = pkg.State(val)
+		}
+	}
+
+	messages, err := s.checkErrors.Messages(ctx, monitorID, timeRange.Start, timeRange.End)
+	if err != nil {
+		return nil, err
+	}
+
+	type checkError struct {
+		class   string
+		message string
+	}
+	checkErrors := make(map[int64]checkError)
+	for checkErrorSeries.Next() {
+		lset := checkErrorSeries.At().Labels()
+		iter := checkErrorSeries.At().Iterator(nil)
+		for iter.Next() == chunkenc.ValFloat {
+			ts, _ := iter.At()
+			message, ok := messages[ts]
+			if !ok {
+				// series written before messages moved out of the labels
+				message = lset.Get("message")
+			}
+			checkErrors[ts] = checkError{lset.Get("class"), message}
+		}
+	}
+
+	var results []pkg.QueryResult
+	seen := make(map[int64]bool)
+	for upSeries.Next() {
+		url := upSeries.At().Labels().Get("url")
+		iter := upSeries.At().Iterator(nil)
+		for iter.Next() == chunkenc.ValFloat {
+			ts, val := iter.At()
+			seen[ts] = true
+			result := pkg.QueryResult{
+				MonitorID: monitorID,
+				URL:       url,
+				Timestamp: time.UnixMilli(ts),
+				Status:    statusResults[ts],
+				Up:        val == 1,
 			}
+
+			if responseTime, ok := responseTimes[ts]; ok && result.Up {
+				result.ResponseTime = &responseTime
+			}
+
+			state, recorded := stateResults[ts]
+			if !recorded && result.Up {
+				state = pkg.StateUp
+			}
+			result.State = state.String()
+
+			if !result.Up {
+				result.ErrorClass = pkg.ErrorClassUnknown
+				if checkErr, ok := checkErrors[ts]; ok {
+					result.ErrorClass = checkErr.class
+					result.ErrorMessage = checkErr.message
+				}
+			}
+
+			results = append(results, result)
 		}
 	}
 
+	// samples written before the up series existed: status 0 meant failure
+	for ts, status := range statusResults {
+		if seen[ts] {
+			continue
+		}
+		result := pkg.QueryResult{
+			MonitorID: monitorID,
+			URL:       statusURLs[ts],
+			Timestamp: time.UnixMilli(ts),
+			Status:    status,
+			Up:        status > 0,
+			State:     pkg.StateDown.String(),
+		}
+		if result.Up {
+			responseTime := responseTimes[ts]
+			result.ResponseTime = &responseTime
+			result.State = pkg.StateUp.String()
+		} else {
+			result.ErrorClass = pkg.ErrorClassUnknown
+		}
+		results = append(results, result)
+	}
+
+	// a renamed monitor has one series per name, keep the merged result ordered
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Timestamp.Before(results[j].Timestamp)
+	})
+
 	return results, nil
 }
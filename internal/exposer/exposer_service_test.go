@@ -0,0 +1,155 @@
+package exposer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/check_error"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/recorder"
+	"github.com/afrianjunior/statx/internal/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+func TestQueryUpTimeStatusStitchesRawOntoRollups(t *testing.T) {
+	ctx := context.Background()
+	config := testutil.Config(t)
+	db := testutil.DB(t)
+	storage := testutil.TSDB(t, config)
+	now := time.Now()
+
+	up := labels.FromStrings(labels.MetricName, "up", "monitor_id", "a", "url", "http://a")
+	appender := storage.Appender(ctx)
+	samples := 0
+	var lastSample time.Time
+	for ts := now.Add(-3 * time.Hour); ts.Before(now); ts = ts.Add(time.Minute) {
+		if _, err := appender.Append(0, up, ts.UnixMilli(), 1); err != nil {
+			t.Fatalf("appending: %v", err)
+		}
+		samples++
+		lastSample = ts
+	}
+	if err := appender.Commit(); err != nil {
+		t.Fatalf("committing: %v", err)
+	}
+
+	// the rollups stop an hour ago, as if the job fell behind
+	job := recorder.NewRollupJob(storage, storage, db, config, testutil.Logger())
+	if err := job.RunOnce(ctx, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("writing rollups: %v", err)
+	}
+
+	service := NewExposerService(storage, check_error.NewCheckErrorRepository(db))
+	timeRange := pkg.TimeRange{Start: now.Add(-2 * 24 * time.Hour), End: now}
+	results, err := service.QueryUpTimeStatus(ctx, "a", timeRange, "")
+	if err != nil {
+		t.Fatalf("querying: %v", err)
+	}
+
+	counted := 0
+	for i, result := range results {
+		if result.Resolution != "5m" {
+			t.Fatalf("result %d has resolution %q", i, result.Resolution)
+		}
+		if i > 0 && !result.Timestamp.After(results[i-1].Timestamp) {
+			t.Fatalf("result %d at %s is not after %s", i, result.Timestamp, results[i-1].Timestamp)
+		}
+		counted += result.SampleCount
+	}
+	if counted != samples {
+		t.Fatalf("buckets hold %d samples, want %d", counted, samples)
+	}
+	if last := results[len(results)-1].Timestamp; !last.Add(5 * time.Minute).After(lastSample) {
+		t.Fatalf("last bucket at %s, want one covering the sample at %s", last, lastSample)
+	}
+}
+
+func TestQueryUpTimeBucketsReadsRollupsBeyondRawSamples(t *testing.T) {
+	ctx := context.Background()
+	config := testutil.Config(t)
+	db := testutil.DB(t)
+	storage := testutil.TSDB(t, config)
+	now := time.Now()
+
+	up := labels.FromStrings(labels.MetricName, "up", "monitor_id", "a", "url", "http://a")
+	latency := labels.FromStrings(labels.MetricName, "http_response_time", "monitor_id", "a", "url", "http://a")
+	appender := storage.Appender(ctx)
+	samples := 0
+	for ts := now.Add(-3 * time.Hour); ts.Before(now); ts = ts.Add(time.Minute) {
+		appender.Append(0, up, ts.UnixMilli(), 1)
+		appender.Append(0, latency, ts.UnixMilli(), 100)
+		samples++
+	}
+	if err := appender.Commit(); err != nil {
+		t.Fatalf("committing: %v", err)
+	}
+
+	job := recorder.NewRollupJob(storage, storage, db, config, testutil.Logger())
+	if err := job.RunOnce(ctx, now); err != nil {
+		t.Fatalf("writing rollups: %v", err)
+	}
+	// raw retention ran out for everything but the last two hours, which
+	// the hourly rollups still cover
+	rawEnd := now.Add(-2 * time.Hour)
+	if err := storage.Delete(ctx, 0, rawEnd.UnixMilli(), labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, "up|http_response_time")); err != nil {
+		t.Fatalf("deleting raw samples: %v", err)
+	}
+
+	service := NewExposerService(storage, check_error.NewCheckErrorRepository(db))
+	results, err := service.QueryUpTimeBuckets(ctx, "a", pkg.TimeRange{Start: now.Add(-4 * time.Hour), End: now}, time.Hour)
+	if err != nil {
+		t.Fatalf("querying: %v", err)
+	}
+
+	counted := 0
+	for _, result := range results {
+		counted += result.SampleCount
+		if result.SampleCount == 0 {
+			continue
+		}
+		if result.UptimeRatio == nil || *result.UptimeRatio != 1 || !result.Up {
+			t.Errorf("bucket at %s has uptime %v", result.Timestamp, result.UptimeRatio)
+		}
+		if result.ResponseTime == nil || *result.ResponseTime != 100 || result.LatencyP95 == nil || *result.LatencyP95 != 100 {
+			t.Errorf("bucket at %s has latency %v, p95 %v", result.Timestamp, result.ResponseTime, result.LatencyP95)
+		}
+		if result.Timestamp.Before(rawEnd.Add(-time.Hour)) && (result.LatencyP50 != nil || result.StatusCodes != nil) {
+			t.Errorf("rolled up bucket at %s claims raw only fields", result.Timestamp)
+		}
+	}
+	if counted != samples {
+		t.Fatalf("buckets hold %d samples, want %d", counted, samples)
+	}
+}
+
+func TestOversizedStatusQueriesAreRefusedUpFront(t *testing.T) {
+	ctx := context.Background()
+	config := testutil.Config(t)
+	db := testutil.DB(t)
+	storage := testutil.TSDB(t, config)
+	service := NewExposerService(storage, check_error.NewCheckErrorRepository(db))
+	now := time.Now()
+
+	// no data at all: the range alone is too large for 5m buckets
+	timeRange := pkg.TimeRange{Start: now.Add(-100 * 24 * time.Hour), End: now}
+	if _, err := service.QueryUpTimeStatus(ctx, "a", timeRange, "5m"); !errors.Is(err, ErrTooManyPoints) {
+		t.Errorf("100 days of 5m rollups: got %v, want ErrTooManyPoints", err)
+	}
+	if _, err := service.QueryUpTimeBuckets(ctx, "a", timeRange, time.Minute); !errors.Is(err, ErrTooManyPoints) {
+		t.Errorf("100 days of 1m buckets: got %v, want ErrTooManyPoints", err)
+	}
+
+	up := labels.FromStrings(labels.MetricName, "up", "monitor_id", "a")
+	appender := storage.Appender(ctx)
+	for i := 0; i <= MaxPoints; i++ {
+		appender.Append(0, up, now.Add(-time.Duration(i)*time.Second).UnixMilli(), 1)
+	}
+	if err := appender.Commit(); err != nil {
+		t.Fatalf("committing: %v", err)
+	}
+	if _, err := service.QueryUpTimeStatus(ctx, "a", pkg.TimeRange{Start: now.Add(-time.Hour * 4), End: now}, pkg.ResolutionRaw); !errors.Is(err, ErrTooManyPoints) {
+		t.Errorf("%d raw samples: got %v, want ErrTooManyPoints", MaxPoints+1, err)
+	}
+}
@@ -0,0 +1,294 @@
+package exposer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+const (
+	// uptimeLookback is read before the longest window so that the state at
+	// its start is known.
+	uptimeLookback = time.Hour
+	// minSampleGap is the shortest time a single check is assumed to cover.
+	minSampleGap = time.Minute
+)
+
+type upSample struct {
+	t  int64
+	up bool
+}
+
+type rollupRatio struct {
+	t     int64
+	ratio float64
+}
+
+// QueryUptimeSummary computes availability, downtime and outages for each
+// window ending at now. Every up sample counts until the next one, but at
+// most twice the usual check interval; longer gaps count as no data. Time
+// before the oldest raw sample is filled from the uptime rollups, which
+// only contribute to availability and downtime, not to outage statistics.
+func (s *exposerService) QueryUptimeSummary(ctx context.Context, monitorID string, windows []pkg.Window, now time.Time, excluded []pkg.TimeRange) ([]pkg.UptimeSummary, error) {
+	var longest time.Duration
+	for _, window := range windows {
+		longest = max(longest, window.Duration)
+	}
+	rangeStart := now.Add(-longest)
+
+	samples, err := s.upSamples(ctx, monitorID, rangeStart.Add(-uptimeLookback), now)
+	if err != nil {
+		return nil, err
+	}
+
+	firstRaw := now.UnixMilli()
+	if len(samples) > 0 {
+		firstRaw = samples[0].t
+	}
+
+	var rollups map[string][]rollupRatio
+	if rangeStart.UnixMilli() < firstRaw {
+		rollups, err = s.rollupRatios(ctx, monitorID, rangeStart, time.UnixMilli(firstRaw))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	excluded = mergeRanges(excluded)
+	gap := sampleGap(samples)
+
+	summaries := make([]pkg.UptimeSummary, 0, len(windows))
+	for _, window := range windows {
+		start := now.Add(-window.Duration)
+		summary := summarizeRaw(samples, gap, start.UnixMilli(), now.UnixMilli(), excluded)
+		summary.Window = window.Name
+		summary.Start = start
+		summary.End = now
+
+		if start.UnixMilli() < firstRaw {
+			addRollups(&summary, rollups, start.UnixMilli(), firstRaw, excluded)
+		}
+
+		windowMs := float64(now.Sub(start).Milliseconds())
+		excludedMs := overlap(start.UnixMilli(), now.UnixMilli(), excluded)
+		summary.ExcludedSeconds = float64(excludedMs) / 1000
+
+		observed := summary.UptimeSeconds + summary.DowntimeSeconds
+		if measurable := windowMs - float64(excludedMs); measurable > 0 {
+			summary.Coverage = math.Min(1, observed*1000/measurable)
+		}
+		if observed > 0 {
+			availability := summary.UptimeSeconds / observed * 100
+			summary.Availability = &availability
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
+
+// upSamples returns the up samples of a monitor ordered by time. A renamed
+// monitor has several series, they are merged.
+func (s *exposerService) upSamples(ctx context.Context, monitorID string, start, end time.Time) ([]upSample, error) {
+	querier, err := s.tsdb.Querier(start.UnixMilli(), end.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("error creating querier: %v", err)
+	}
+	defer querier.Close()
+
+	seriesSet := querier.Select(ctx, false, nil,
+		labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "up"),
+		labels.MustNewMatcher(labels.MatchEqual, "monitor_id", monitorID),
+		pkg.OwnSeries,
+	)
+
+	var samples []upSample
+	var iter chunkenc.Iterator
+	for seriesSet.Next() {
+		iter = seriesSet.At().Iterator(iter)
+		for iter.Next() == chunkenc.ValFloat {
+			ts, val := iter.At()
+			samples = append(samples, upSample{ts, val == 1})
+		}
+	}
+	if err := seriesSet.Err(); err != nil {
+		return nil, fmt.Errorf("error selecting up series: %v", err)
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].t < samples[j].t
+	})
+
+	return samples, nil
+}
+
+// rollupRatios returns the uptime ratio buckets in [start, end) per resolution.
+func (s *exposerService) rollupRatios(ctx context.Context, monitorID string, start, end time.Time) (map[string][]rollupRatio, error) {
+	querier, err := s.tsdb.Querier(start.Add(-pkg.Resolutions[len(pkg.Resolutions)-1].Step).UnixMilli(), end.UnixMilli()-1)
+	if err != nil {
+		return nil, fmt.Errorf("error creating querier: %v", err)
+	}
+	defer querier.Close()
+
+	seriesSet := querier.Select(ctx, false, nil,
+		labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, pkg.RollupUptimeRatio),
+		labels.MustNewMatcher(labels.MatchEqual, "monitor_id", monitorID),
+		pkg.OwnSeries,
+	)
+
+	ratios := make(map[string][]rollupRatio)
+	var iter chunkenc.Iterator
+	for seriesSet.Next() {
+		resolution := seriesSet.At().Labels().Get("resolution")
+		iter = seriesSet.At().Iterator(iter)
+		for iter.Next() == chunkenc.ValFloat {
+			ts, val := iter.At()
+			ratios[resolution] = append(ratios[resolution], rollupRatio{ts, val})
+		}
+	}
+	if err := seriesSet.Err(); err != nil {
+		return nil, fmt.Errorf("error selecting rollups: %v", err)
+	}
+
+	return ratios, nil
+}
+
+// sampleGap is how long a single sample is assumed to hold: twice the
+// median distance between samples.
+func sampleGap(samples []upSample) int64 {
+	if len(samples) < 2 {
+		return minSampleGap.Milliseconds()
+	}
+
+	deltas := make([]float64, 0, len(samples)-1)
+	for i := 1; i < len(samples); i++ {
+		deltas = append(deltas, float64(samples[i].t-samples[i-1].t))
+	}
+
+	return max(int64(pkg.Percentile(deltas, 50))*2, minSampleGap.Milliseconds())
+}
+
+func summarizeRaw(samples []upSample, gap, start, end int64, excluded []pkg.TimeRange) pkg.UptimeSummary {
+	var summary pkg.UptimeSummary
+	var upMs, downMs, outageMs, longestMs int64
+	inOutage := false
+
+	for i, sample := range samples {
+		segmentEnd := sample.t + gap
+		if i+1 < len(samples) {
+			segmentEnd = min(segmentEnd, samples[i+1].t)
+		}
+
+		from, to := max(sample.t, start), min(segmentEnd, end)
+		length := int64(0)
+		if to > from {
+			length = to - from - overlap(from, to, excluded)
+		}
+
+		if sample.up {
+			upMs += length
+			if inOutage {
+				summary.Outages, longestMs = closeOutage(summary.Outages, outageMs, longestMs)
+				inOutage, outageMs = false, 0
+			}
+			continue
+		}
+
+		downMs += length
+		inOutage = true
+		outageMs += length
+	}
+
+	if inOutage {
+		before := summary.Outages
+		summary.Outages, longestMs = closeOutage(summary.Outages, outageMs, longestMs)
+		summary.OngoingOutage = summary.Outages > before && samples[len(samples)-1].t+gap >= end
+	}
+
+	summary.UptimeSeconds = float64(upMs) / 1000
+	summary.DowntimeSeconds = float64(downMs) / 1000
+	summary.LongestOutageSeconds = float64(longestMs) / 1000
+
+	return summary
+}
+
+// closeOutage counts an outage unless none of it fell inside the window.
+func closeOutage(outages int, outageMs, longestMs int64) (int, int64) {
+	if outageMs <= 0 {
+		return outages, longestMs
+	}
+	return outages + 1, max(longestMs, outageMs)
+}
+
+// addRollups fills [start, end) from the finest rollup resolution that has
+// data there.
+func addRollups(summary *pkg.UptimeSummary, rollups map[string][]rollupRatio, start, end int64, excluded []pkg.TimeRange) {
+	for _, res := range pkg.Resolutions {
+		step := res.Step.Milliseconds()
+
+		var upMs, downMs float64
+		found := false
+		for _, bucket := range rollups[res.Name] {
+			from, to := max(bucket.t, start), min(bucket.t+step, end)
+			if to <= from {
+				continue
+			}
+			found = true
+			length := float64(to - from - overlap(from, to, excluded))
+			upMs += bucket.ratio * length
+			downMs += (1 - bucket.ratio) * length
+		}
+
+		if found {
+			summary.UptimeSeconds += upMs / 1000
+			summary.DowntimeSeconds += downMs / 1000
+			summary.RollupDowntimeSeconds = downMs / 1000
+			return
+		}
+	}
+}
+
+// mergeRanges sorts ranges and joins the overlapping ones.
+func mergeRanges(ranges []pkg.TimeRange) []pkg.TimeRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sorted := append([]pkg.TimeRange{}, ranges...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	merged := []pkg.TimeRange{sorted[0]}
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if !r.Start.After(last.End) {
+			if r.End.After(last.End) {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged
+}
+
+// overlap returns how many milliseconds of [from, to) lie in the merged ranges.
+func overlap(from, to int64, ranges []pkg.TimeRange) int64 {
+	var total int64
+	for _, r := range ranges {
+		start, end := max(from, r.Start.UnixMilli()), min(to, r.End.UnixMilli())
+		if end > start {
+			total += end - start
+		}
+	}
+	return total
+}
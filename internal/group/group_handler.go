@@ -0,0 +1,165 @@
+package group
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/go-chi/chi/v5"
+)
+
+type listResponse struct {
+	Total  int             `json:"total"`
+	Groups []*pkg.GroupDTO `json:"groups"`
+}
+
+type statusListResponse struct {
+	Total  int               `json:"total"`
+	Groups []pkg.GroupStatus `json:"groups"`
+}
+
+func CreateHandler(groupSvc GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload pkg.GroupDTO
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: "invalid req body",
+				Data:    nil,
+			}, http.StatusBadRequest)
+			return
+		}
+
+		id, err := groupSvc.CreateGroup(r.Context(), &payload)
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    id,
+		}, http.StatusOK)
+	}
+}
+
+func GetHandler(groupSvc GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		group, err := groupSvc.GetGroup(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    group,
+		}, http.StatusOK)
+	}
+}
+
+func ListHandler(groupSvc GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groups, err := groupSvc.GetListGroups(r.Context())
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data: listResponse{
+				Total:  len(groups),
+				Groups: groups,
+			},
+		}, http.StatusOK)
+	}
+}
+
+func UpdateHandler(groupSvc GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload pkg.GroupDTO
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: "invalid req body",
+				Data:    nil,
+			}, http.StatusBadRequest)
+			return
+		}
+
+		group, err := groupSvc.UpdateGroup(r.Context(), chi.URLParam(r, "id"), &payload)
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    group,
+		}, http.StatusOK)
+	}
+}
+
+func DeleteHandler(groupSvc GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := groupSvc.DeleteGroup(r.Context(), chi.URLParam(r, "id")); err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    nil,
+		}, http.StatusOK)
+	}
+}
+
+// StatusHandler returns the rollup of one group, e.g. "payments: 1 of 12 down".
+func StatusHandler(groupSvc GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := groupSvc.GetGroupStatus(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    status,
+		}, http.StatusOK)
+	}
+}
+
+// StatusListHandler returns the rollups of all top level groups, subgroups
+// nested inside.
+func StatusListHandler(groupSvc GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses, err := groupSvc.GetListGroupStatuses(r.Context())
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data: statusListResponse{
+				Total:  len(statuses),
+				Groups: statuses,
+			},
+		}, http.StatusOK)
+	}
+}
+
+// errorStatuses maps service errors to status codes.
+var errorStatuses = []pkg.ErrorStatus{
+	{Err: ErrHasSubgroups, Code: http.StatusConflict},
+	{Err: ErrNotFound, Code: http.StatusNotFound},
+}
@@ -0,0 +1,224 @@
+package group
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	_ "github.com/glebarez/go-sqlite"
+)
+
+// SubtreeQuery selects the id of a group and of all its descendants. It takes
+// the group id as its only argument.
+const SubtreeQuery = `
+	WITH RECURSIVE subtree(id) AS (
+		SELECT id FROM monitor_group WHERE id = ?
+		UNION
+		SELECT monitor_group.id FROM monitor_group JOIN subtree ON monitor_group.parent_id = subtree.id
+	)
+	SELECT id FROM subtree
+`
+
+type groupRepository struct {
+	db *sql.DB
+}
+
+type GroupRepository interface {
+	Insert(ctx context.Context, group *pkg.GroupDTO) (string, error)
+	GetByID(ctx context.Context, id string) (*pkg.GroupDTO, error)
+	List(ctx context.Context) ([]*pkg.GroupDTO, error)
+	Update(ctx context.Context, group *pkg.GroupDTO) (bool, error)
+	// Delete removes a group and moves its monitors out of any group.
+	Delete(ctx context.Context, id string) (bool, error)
+	CountSubgroups(ctx context.Context, id string) (int, error)
+	// MonitorGroups maps every grouped monitor to its group.
+	MonitorGroups(ctx context.Context) (map[string]string, error)
+}
+
+func NewGroupRepository(
+	db *sql.DB,
+) GroupRepository {
+	return &groupRepository{
+		db,
+	}
+}
+
+func (r *groupRepository) Insert(ctx context.Context, group *pkg.GroupDTO) (string, error) {
+	query := `
+		INSERT INTO monitor_group (
+			parent_id, name, description
+		) VALUES (?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		sql.NullString{String: group.ParentID, Valid: group.ParentID != ""},
+		group.Name,
+		group.Description,
+	)
+	if err != nil {
+		return "", fmt.Errorf("error inserting group: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return "", fmt.Errorf("error getting last insert ID: %w", err)
+	}
+
+	var uuid string
+	err = r.db.QueryRowContext(ctx, "SELECT id FROM monitor_group WHERE rowid = ?", id).Scan(&uuid)
+	if err != nil {
+		return "", fmt.Errorf("error fetching generated UUID: %w", err)
+	}
+
+	return uuid, nil
+}
+
+func (r *groupRepository) GetByID(ctx context.Context, id string) (*pkg.GroupDTO, error) {
+	query := `
+		SELECT id, parent_id, name, description, created_at
+		FROM monitor_group
+		WHERE id = ?
+	`
+
+	group, err := scanGroup(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching group: %w", err)
+	}
+
+	return group, nil
+}
+
+func (r *groupRepository) List(ctx context.Context) ([]*pkg.GroupDTO, error) {
+	query := `
+		SELECT id, parent_id, name, description, created_at
+		FROM monitor_group
+		ORDER BY lower(name), id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*pkg.GroupDTO
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
+
+func (r *groupRepository) Update(ctx context.Context, group *pkg.GroupDTO) (bool, error) {
+	query := `
+		UPDATE monitor_group SET parent_id = ?, name = ?, description = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		sql.NullString{String: group.ParentID, Valid: group.ParentID != ""},
+		group.Name,
+		group.Description,
+		group.ID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error updating group: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting affected rows: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+func (r *groupRepository) Delete(ctx context.Context, id string) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM monitor_group WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting group: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting affected rows: %w", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE config_monitor SET group_id = NULL WHERE group_id = ?", id); err != nil {
+		return false, fmt.Errorf("error ungrouping monitors: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *groupRepository) CountSubgroups(ctx context.Context, id string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM monitor_group WHERE parent_id = ?", id).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting subgroups: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *groupRepository) MonitorGroups(ctx context.Context) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, group_id FROM config_monitor WHERE group_id IS NOT NULL")
+	if err != nil {
+		return nil, fmt.Errorf("error querying grouped monitors: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make(map[string]string)
+	for rows.Next() {
+		var monitorID, groupID string
+		if err := rows.Scan(&monitorID, &groupID); err != nil {
+			return nil, fmt.Errorf("error scanning grouped monitor: %w", err)
+		}
+		groups[monitorID] = groupID
+	}
+
+	return groups, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanGroup(row rowScanner) (*pkg.GroupDTO, error) {
+	var group pkg.GroupDTO
+	var parentID, description sql.NullString
+
+	err := row.Scan(
+		&group.ID,
+		&parentID,
+		&group.Name,
+		&description,
+		&group.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	group.ParentID = parentID.String
+	group.Description = description.String
+
+	return &group, nil
+}
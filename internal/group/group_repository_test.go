@@ -0,0 +1,91 @@
+package group
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+)
+
+func insertMonitor(t *testing.T, db *sql.DB, name, groupID string) string {
+	t.Helper()
+	result, err := db.Exec(`INSERT INTO config_monitor (type, name, url, interval, group_id) VALUES ('uptime', ?, 'http://a', 60, ?)`,
+		name, sql.NullString{String: groupID, Valid: groupID != ""})
+	if err != nil {
+		t.Fatalf("inserting monitor: %v", err)
+	}
+	rowID, _ := result.LastInsertId()
+	var id string
+	if err := db.QueryRow(`SELECT id FROM config_monitor WHERE rowid = ?`, rowID).Scan(&id); err != nil {
+		t.Fatalf("fetching monitor id: %v", err)
+	}
+	return id
+}
+
+func TestGroupRepository(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.DB(t)
+	repository := NewGroupRepository(db)
+
+	parentID, err := repository.Insert(ctx, &pkg.GroupDTO{Name: "team", Description: "all of it"})
+	if err != nil {
+		t.Fatalf("inserting group: %v", err)
+	}
+	childID, err := repository.Insert(ctx, &pkg.GroupDTO{Name: "API", ParentID: parentID})
+	if err != nil {
+		t.Fatalf("inserting subgroup: %v", err)
+	}
+
+	child, err := repository.GetByID(ctx, childID)
+	if err != nil {
+		t.Fatalf("getting group: %v", err)
+	}
+	if child.Name != "API" || child.ParentID != parentID || child.Description != "" {
+		t.Errorf("subgroup = %+v", child)
+	}
+	if count, err := repository.CountSubgroups(ctx, parentID); err != nil || count != 1 {
+		t.Errorf("subgroups = %d, %v, want 1", count, err)
+	}
+
+	// names sort case-insensitively
+	groups, err := repository.List(ctx)
+	if err != nil {
+		t.Fatalf("listing groups: %v", err)
+	}
+	if len(groups) != 2 || groups[0].ID != childID || groups[1].ID != parentID {
+		t.Errorf("groups = %+v, want API before team", groups)
+	}
+
+	child.Name, child.ParentID = "api", ""
+	if ok, err := repository.Update(ctx, child); err != nil || !ok {
+		t.Fatalf("updating group: %v, %v", ok, err)
+	}
+	if child, err = repository.GetByID(ctx, childID); err != nil || child.Name != "api" || child.ParentID != "" {
+		t.Errorf("updated group = %+v, %v", child, err)
+	}
+	if ok, err := repository.Update(ctx, &pkg.GroupDTO{ID: "missing", Name: "x"}); err != nil || ok {
+		t.Errorf("updating a missing group: %v, %v", ok, err)
+	}
+
+	monitorID := insertMonitor(t, db, "grouped", childID)
+	insertMonitor(t, db, "ungrouped", "")
+	monitorGroups, err := repository.MonitorGroups(ctx)
+	if err != nil {
+		t.Fatalf("getting monitor groups: %v", err)
+	}
+	if len(monitorGroups) != 1 || monitorGroups[monitorID] != childID {
+		t.Errorf("monitor groups = %v", monitorGroups)
+	}
+
+	if ok, err := repository.Delete(ctx, childID); err != nil || !ok {
+		t.Fatalf("deleting group: %v, %v", ok, err)
+	}
+	if monitorGroups, err = repository.MonitorGroups(ctx); err != nil || len(monitorGroups) != 0 {
+		t.Errorf("monitors of a deleted group stay grouped: %v, %v", monitorGroups, err)
+	}
+	if ok, err := repository.Delete(ctx, childID); err != nil || ok {
+		t.Errorf("deleting a deleted group: %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,273 @@
+package group
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/recorder"
+)
+
+// MaxNameLength is the longest group name in characters.
+const MaxNameLength = 255
+
+var (
+	ErrNotFound     = errors.New("group not found")
+	ErrHasSubgroups = errors.New("group still has subgroups")
+)
+
+type groupService struct {
+	groupRepository GroupRepository
+	statuses        recorder.StatusTracker
+}
+
+type GroupService interface {
+	CreateGroup(ctx context.Context, payload *pkg.GroupDTO) (string, error)
+	GetGroup(ctx context.Context, id string) (*pkg.GroupDTO, error)
+	GetListGroups(ctx context.Context) ([]*pkg.GroupDTO, error)
+	UpdateGroup(ctx context.Context, id string, payload *pkg.GroupDTO) (*pkg.GroupDTO, error)
+	// DeleteGroup refuses groups with subgroups; their monitors become
+	// ungrouped.
+	DeleteGroup(ctx context.Context, id string) error
+	// GetGroupStatus rolls up the current state of a group and its subgroups.
+	GetGroupStatus(ctx context.Context, id string) (*pkg.GroupStatus, error)
+	// GetListGroupStatuses rolls up every top level group.
+	GetListGroupStatuses(ctx context.Context) ([]pkg.GroupStatus, error)
+}
+
+func NewGroupService(
+	groupRepository GroupRepository,
+	statuses recorder.StatusTracker,
+) GroupService {
+	return &groupService{
+		groupRepository: groupRepository,
+		statuses:        statuses,
+	}
+}
+
+func (s *groupService) CreateGroup(ctx context.Context, payload *pkg.GroupDTO) (string, error) {
+	if err := s.validate(ctx, "", payload); err != nil {
+		return "", err
+	}
+	return s.groupRepository.Insert(ctx, payload)
+}
+
+func (s *groupService) GetGroup(ctx context.Context, id string) (*pkg.GroupDTO, error) {
+	group, err := s.groupRepository.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return group, err
+}
+
+func (s *groupService) GetListGroups(ctx context.Context) ([]*pkg.GroupDTO, error) {
+	return s.groupRepository.List(ctx)
+}
+
+func (s *groupService) UpdateGroup(ctx context.Context, id string, payload *pkg.GroupDTO) (*pkg.GroupDTO, error) {
+	if _, err := s.GetGroup(ctx, id); err != nil {
+		return nil, err
+	}
+
+	payload.ID = id
+	if err := s.validate(ctx, id, payload); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.groupRepository.Update(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrNotFound
+	}
+
+	return s.GetGroup(ctx, id)
+}
+
+func (s *groupService) DeleteGroup(ctx context.Context, id string) error {
+	subgroups, err := s.groupRepository.CountSubgroups(ctx, id)
+	if err != nil {
+		return err
+	}
+	if subgroups > 0 {
+		return ErrHasSubgroups
+	}
+
+	deleted, err := s.groupRepository.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *groupService) GetGroupStatus(ctx context.Context, id string) (*pkg.GroupStatus, error) {
+	tree, err := s.loadTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	node, ok := tree.nodes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	status := tree.rollup(node, s.statuses)
+	return &status, nil
+}
+
+func (s *groupService) GetListGroupStatuses(ctx context.Context) ([]pkg.GroupStatus, error) {
+	tree, err := s.loadTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]pkg.GroupStatus, 0, len(tree.roots))
+	for _, node := range tree.roots {
+		statuses = append(statuses, tree.rollup(node, s.statuses))
+	}
+
+	return statuses, nil
+}
+
+// validate checks the name and that the parent exists and is not the group
+// itself or one of its descendants.
+func (s *groupService) validate(ctx context.Context, id string, group *pkg.GroupDTO) error {
+	errs := &pkg.ValidationError{}
+
+	if strings.TrimSpace(group.Name) == "" {
+		errs.Add("name", "is required")
+	} else if utf8.RuneCountInString(group.Name) > MaxNameLength {
+		errs.Add("name", fmt.Sprintf("must be at most %d characters", MaxNameLength))
+	}
+
+	if group.ParentID != "" {
+		tree, err := s.loadTree(ctx)
+		if err != nil {
+			return err
+		}
+		parent, ok := tree.nodes[group.ParentID]
+		switch {
+		case !ok:
+			errs.Add("parent_id", "group not found")
+		case id != "" && tree.isDescendant(parent, id):
+			errs.Add("parent_id", "cannot be the group itself or one of its subgroups")
+		}
+	}
+
+	return errs.Err()
+}
+
+type groupNode struct {
+	group    *pkg.GroupDTO
+	children []*groupNode
+	monitors []string
+}
+
+type groupTree struct {
+	nodes map[string]*groupNode
+	roots []*groupNode
+}
+
+func (s *groupService) loadTree(ctx context.Context) (*groupTree, error) {
+	groups, err := s.groupRepository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	monitorGroups, err := s.groupRepository.MonitorGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &groupTree{nodes: make(map[string]*groupNode, len(groups))}
+	for _, group := range groups {
+		tree.nodes[group.ID] = &groupNode{group: group}
+	}
+	// groups are sorted by name, so children and roots keep that order
+	for _, group := range groups {
+		node := tree.nodes[group.ID]
+		if parent, ok := tree.nodes[group.ParentID]; ok {
+			parent.children = append(parent.children, node)
+		} else {
+			tree.roots = append(tree.roots, node)
+		}
+	}
+	for monitorID, groupID := range monitorGroups {
+		if node, ok := tree.nodes[groupID]; ok {
+			node.monitors = append(node.monitors, monitorID)
+		}
+	}
+
+	return tree, nil
+}
+
+// isDescendant reports whether node is the group id or lies below it.
+func (t *groupTree) isDescendant(node *groupNode, id string) bool {
+	for seen := make(map[string]bool); node != nil && !seen[node.group.ID]; node = t.nodes[node.group.ParentID] {
+		if node.group.ID == id {
+			return true
+		}
+		seen[node.group.ID] = true
+	}
+	return false
+}
+
+func (t *groupTree) rollup(node *groupNode, statuses recorder.StatusTracker) pkg.GroupStatus {
+	status := pkg.GroupStatus{
+		GroupID: node.group.ID,
+		Name:    node.group.Name,
+	}
+
+	for _, monitorID := range node.monitors {
+		status.Total++
+		current, ok := statuses.Get(monitorID)
+		switch {
+		case !ok:
+			status.Unknown++
+		case current.State == pkg.StateDown.String():
+			status.Down++
+		case current.State == pkg.StateDegraded.String():
+			status.Degraded++
+		default:
+			status.Up++
+		}
+	}
+
+	for _, child := range node.children {
+		sub := t.rollup(child, statuses)
+		status.Total += sub.Total
+		status.Up += sub.Up
+		status.Degraded += sub.Degraded
+		status.Down += sub.Down
+		status.Unknown += sub.Unknown
+		status.Subgroups = append(status.Subgroups, sub)
+	}
+
+	switch {
+	case status.Down > 0:
+		status.State = pkg.StateDown.String()
+		status.Summary = fmt.Sprintf("%s: %d of %d down", status.Name, status.Down, status.Total)
+	case status.Degraded > 0:
+		status.State = pkg.StateDegraded.String()
+		status.Summary = fmt.Sprintf("%s: %d of %d degraded", status.Name, status.Degraded, status.Total)
+	case status.Total == 0:
+		status.State = "unknown"
+		status.Summary = fmt.Sprintf("%s: no monitors", status.Name)
+	case status.Up == status.Total:
+		status.State = pkg.StateUp.String()
+		status.Summary = fmt.Sprintf("%s: all %d up", status.Name, status.Total)
+	default:
+		status.State = "unknown"
+		status.Summary = fmt.Sprintf("%s: %d of %d not checked yet", status.Name, status.Unknown, status.Total)
+	}
+
+	return status
+}
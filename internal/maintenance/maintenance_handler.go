@@ -0,0 +1,97 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/go-chi/chi/v5"
+)
+
+type listResponse struct {
+	Total   int                         `json:"total"`
+	Windows []*pkg.MaintenanceWindowDTO `json:"windows"`
+}
+
+func CreateHandler(maintenanceSvc MaintenanceService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload pkg.MaintenanceWindowDTO
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: "invalid req body",
+				Data:    nil,
+			}, http.StatusBadRequest)
+			return
+		}
+		payload.MonitorID = chi.URLParam(r, "id")
+
+		id, err := maintenanceSvc.CreateMaintenanceWindow(r.Context(), &payload)
+		if err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, ErrInvalidWindow) {
+				code = http.StatusBadRequest
+			}
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, code)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    id,
+		}, http.StatusOK)
+	}
+}
+
+func ListHandler(maintenanceSvc MaintenanceService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		windows, total, err := maintenanceSvc.GetListMaintenanceWindows(r.Context(), chi.URLParam(r, "id"), 100, 0)
+		if err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data: listResponse{
+				Total:   total,
+				Windows: windows,
+			},
+		}, http.StatusOK)
+	}
+}
+
+func DeleteHandler(maintenanceSvc MaintenanceService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := maintenanceSvc.DeleteMaintenanceWindow(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "windowID"))
+		if err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, ErrNotFound) {
+				code = http.StatusNotFound
+			}
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, code)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    nil,
+		}, http.StatusOK)
+	}
+}
@@ -0,0 +1,142 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	_ "github.com/glebarez/go-sqlite"
+)
+
+type maintenanceRepository struct {
+	db *sql.DB
+}
+
+type MaintenanceRepository interface {
+	Insert(ctx context.Context, window *pkg.MaintenanceWindowDTO) (string, error)
+	Delete(ctx context.Context, monitorID, id string) (bool, error)
+	List(ctx context.Context, monitorID string, limit, offset int) ([]*pkg.MaintenanceWindowDTO, int, error)
+	// ListOverlapping returns the windows of a monitor that intersect [start, end].
+	ListOverlapping(ctx context.Context, monitorID string, start, end time.Time) ([]*pkg.MaintenanceWindowDTO, error)
+}
+
+func NewMaintenanceRepository(
+	db *sql.DB,
+) MaintenanceRepository {
+	return &maintenanceRepository{
+		db,
+	}
+}
+
+func (r *maintenanceRepository) Insert(ctx context.Context, window *pkg.MaintenanceWindowDTO) (string, error) {
+	query := `
+		INSERT INTO maintenance_window (
+			monitor_id, starts_at, ends_at, reason
+		) VALUES (?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		window.MonitorID,
+		window.StartsAt.UnixMilli(),
+		window.EndsAt.UnixMilli(),
+		window.Reason,
+	)
+	if err != nil {
+		return "", fmt.Errorf("error inserting maintenance window: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return "", fmt.Errorf("error getting last insert ID: %w", err)
+	}
+
+	var uuid string
+	err = r.db.QueryRowContext(ctx, "SELECT id FROM maintenance_window WHERE rowid = ?", id).Scan(&uuid)
+	if err != nil {
+		return "", fmt.Errorf("error fetching generated UUID: %w", err)
+	}
+
+	return uuid, nil
+}
+
+func (r *maintenanceRepository) Delete(ctx context.Context, monitorID, id string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM maintenance_window WHERE monitor_id = ? AND id = ?", monitorID, id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting maintenance window: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting affected rows: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+func (r *maintenanceRepository) List(ctx context.Context, monitorID string, limit, offset int) ([]*pkg.MaintenanceWindowDTO, int, error) {
+	query := `
+		SELECT id, monitor_id, starts_at, ends_at, reason, created_at
+		FROM maintenance_window
+		WHERE monitor_id = ?
+		ORDER BY starts_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	windows, err := r.query(ctx, query, monitorID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM maintenance_window WHERE monitor_id = ?", monitorID).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting total count: %w", err)
+	}
+
+	return windows, total, nil
+}
+
+func (r *maintenanceRepository) ListOverlapping(ctx context.Context, monitorID string, start, end time.Time) ([]*pkg.MaintenanceWindowDTO, error) {
+	query := `
+		SELECT id, monitor_id, starts_at, ends_at, reason, created_at
+		FROM maintenance_window
+		WHERE monitor_id = ? AND starts_at <= ? AND ends_at >= ?
+		ORDER BY starts_at
+	`
+
+	return r.query(ctx, query, monitorID, end.UnixMilli(), start.UnixMilli())
+}
+
+func (r *maintenanceRepository) query(ctx context.Context, query string, args ...any) ([]*pkg.MaintenanceWindowDTO, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying maintenance windows: %w", err)
+	}
+	defer rows.Close()
+
+	var windows []*pkg.MaintenanceWindowDTO
+	for rows.Next() {
+		var window pkg.MaintenanceWindowDTO
+		var startsAt, endsAt int64
+		var reason sql.NullString
+		err := rows.Scan(
+			&window.ID,
+			&window.MonitorID,
+			&startsAt,
+			&endsAt,
+			&reason,
+			&window.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning maintenance window: %w", err)
+		}
+		window.StartsAt = time.UnixMilli(startsAt).UTC()
+		window.EndsAt = time.UnixMilli(endsAt).UTC()
+		window.Reason = reason.String
+		windows = append(windows, &window)
+	}
+
+	return windows, rows.Err()
+}
@@ -0,0 +1,58 @@
+package maintenance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+)
+
+func TestMaintenanceRepository(t *testing.T) {
+	ctx := context.Background()
+	repository := NewMaintenanceRepository(testutil.DB(t))
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	insert := func(monitorID string, start, end time.Duration, reason string) string {
+		t.Helper()
+		id, err := repository.Insert(ctx, &pkg.MaintenanceWindowDTO{
+			MonitorID: monitorID, StartsAt: base.Add(start), EndsAt: base.Add(end), Reason: reason,
+		})
+		if err != nil {
+			t.Fatalf("inserting window: %v", err)
+		}
+		return id
+	}
+	early := insert("a", 0, time.Hour, "upgrade")
+	late := insert("a", 3*time.Hour, 4*time.Hour, "")
+	other := insert("b", 0, time.Hour, "")
+
+	windows, total, err := repository.List(ctx, "a", 1, 0)
+	if err != nil {
+		t.Fatalf("listing windows: %v", err)
+	}
+	if total != 2 || len(windows) != 1 || windows[0].ID != late {
+		t.Errorf("listed %+v of %d, want the latest of 2 windows first", windows, total)
+	}
+
+	windows, err = repository.ListOverlapping(ctx, "a", base.Add(30*time.Minute), base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("listing overlapping windows: %v", err)
+	}
+	if len(windows) != 1 || windows[0].ID != early || windows[0].Reason != "upgrade" ||
+		!windows[0].StartsAt.Equal(base) || !windows[0].EndsAt.Equal(base.Add(time.Hour)) {
+		t.Errorf("overlapping windows = %+v", windows)
+	}
+
+	// a window is only deleted through its own monitor
+	if ok, err := repository.Delete(ctx, "a", other); err != nil || ok {
+		t.Errorf("deleted the window of another monitor: %v, %v", ok, err)
+	}
+	if ok, err := repository.Delete(ctx, "a", early); err != nil || !ok {
+		t.Errorf("deleting window: %v, %v", ok, err)
+	}
+	if _, total, err = repository.List(ctx, "a", 10, 0); err != nil || total != 1 {
+		t.Errorf("%d windows left, %v, want 1", total, err)
+	}
+}
@@ -0,0 +1,88 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+var (
+	ErrInvalidWindow = errors.New("ends_at must be after starts_at")
+	ErrNotFound      = errors.New("maintenance window not found")
+)
+
+type maintenanceService struct {
+	maintenanceRepository MaintenanceRepository
+}
+
+type MaintenanceService interface {
+	CreateMaintenanceWindow(ctx context.Context, payload *pkg.MaintenanceWindowDTO) (string, error)
+	DeleteMaintenanceWindow(ctx context.Context, monitorID, id string) error
+	GetListMaintenanceWindows(ctx context.Context, monitorID string, limit, offset int) ([]*pkg.MaintenanceWindowDTO, int, error)
+	// ExcludedRanges returns the maintenance windows of a monitor within
+	// timeRange, clipped to it.
+	ExcludedRanges(ctx context.Context, monitorID string, timeRange pkg.TimeRange) ([]pkg.TimeRange, error)
+}
+
+func NewMaintenanceService(
+	maintenanceRepository MaintenanceRepository,
+) MaintenanceService {
+	return &maintenanceService{
+		maintenanceRepository: maintenanceRepository,
+	}
+}
+
+func (s *maintenanceService) CreateMaintenanceWindow(ctx context.Context, payload *pkg.MaintenanceWindowDTO) (string, error) {
+	if !payload.EndsAt.After(payload.StartsAt) {
+		return "", ErrInvalidWindow
+	}
+	return s.maintenanceRepository.Insert(ctx, payload)
+}
+
+func (s *maintenanceService) DeleteMaintenanceWindow(ctx context.Context, monitorID, id string) error {
+	deleted, err := s.maintenanceRepository.Delete(ctx, monitorID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *maintenanceService) GetListMaintenanceWindows(ctx context.Context, monitorID string, limit, offset int) ([]*pkg.MaintenanceWindowDTO, int, error) {
+	return s.maintenanceRepository.List(ctx, monitorID, limit, offset)
+}
+
+func (s *maintenanceService) ExcludedRanges(ctx context.Context, monitorID string, timeRange pkg.TimeRange) ([]pkg.TimeRange, error) {
+	windows, err := s.maintenanceRepository.ListOverlapping(ctx, monitorID, timeRange.Start, timeRange.End)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := make([]pkg.TimeRange, 0, len(windows))
+	for _, window := range windows {
+		ranges = append(ranges, pkg.TimeRange{
+			Start: maxTime(window.StartsAt, timeRange.Start),
+			End:   minTime(window.EndsAt, timeRange.End),
+		})
+	}
+
+	return ranges, nil
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"sort"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/recorder"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var monitorLabels = []string{"monitor_id", "type", "name", "url"}
+
+var (
+	upDesc = prometheus.NewDesc(
+		"statx_monitor_up",
+		"Whether the last check of the monitor succeeded.",
+		monitorLabels, nil,
+	)
+	stateDesc = prometheus.NewDesc(
+		"statx_monitor_state",
+		"State of the monitor after the last check: 0 down, 1 up, 2 degraded.",
+		monitorLabels, nil,
+	)
+	statusCodeDesc = prometheus.NewDesc(
+		"statx_monitor_last_status_code",
+		"HTTP status code of the last check, 0 when no response was received.",
+		monitorLabels, nil,
+	)
+	latencyDesc = prometheus.NewDesc(
+		"statx_monitor_last_latency_seconds",
+		"Response time of the last successful check.",
+		monitorLabels, nil,
+	)
+	lastCheckDesc = prometheus.NewDesc(
+		"statx_monitor_last_check_timestamp_seconds",
+		"Unix time of the last check.",
+		monitorLabels, nil,
+	)
+	certExpiryDesc = prometheus.NewDesc(
+		"statx_monitor_cert_expiry_timestamp_seconds",
+		"Unix time at which the certificate served by the monitor expires.",
+		monitorLabels, nil,
+	)
+	consecutiveFailuresDesc = prometheus.NewDesc(
+		"statx_monitor_consecutive_failures",
+		"Number of failed checks in a row.",
+		monitorLabels, nil,
+	)
+)
+
+// collector turns the status tracker into metrics on every scrape. User
+// labels vary between monitors, so they go on a separate info metric
+// instead of every gauge.
+type collector struct {
+	statuses recorder.StatusTracker
+}
+
+func newCollector(statuses recorder.StatusTracker) prometheus.Collector {
+	return &collector{statuses}
+}
+
+// Describe sends nothing, which makes this an unchecked collector: the
+// label names of statx_monitor_labels are only known at collection time.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	statuses := c.statuses.List()
+
+	for _, status := range statuses {
+		values := []string{status.MonitorID, status.Type, status.Name, status.URL}
+
+		up := 0.0
+		if status.Up {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, up, values...)
+		ch <- prometheus.MustNewConstMetric(stateDesc, prometheus.GaugeValue, float64(pkg.ParseState(status.State)), values...)
+		ch <- prometheus.MustNewConstMetric(statusCodeDesc, prometheus.GaugeValue, float64(status.StatusCode), values...)
+		if status.Up {
+			ch <- prometheus.MustNewConstMetric(latencyDesc, prometheus.GaugeValue, status.ResponseTime/1000, values...)
+		}
+		ch <- prometheus.MustNewConstMetric(lastCheckDesc, prometheus.GaugeValue, float64(status.LastCheck.UnixMilli())/1000, values...)
+		if status.CertExpiry != nil {
+			ch <- prometheus.MustNewConstMetric(certExpiryDesc, prometheus.GaugeValue, float64(status.CertExpiry.Unix()), values...)
+		}
+		ch <- prometheus.MustNewConstMetric(consecutiveFailuresDesc, prometheus.GaugeValue, float64(status.ConsecutiveFailures), values...)
+	}
+
+	collectLabels(ch, statuses)
+}
+
+// collectLabels exposes statx_monitor_labels{monitor_id, label_<name>...} 1
+// with the union of all user label names, so the info metric can be joined
+// onto the gauges by monitor_id.
+func collectLabels(ch chan<- prometheus.Metric, statuses []pkg.MonitorStatus) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, status := range statuses {
+		for name := range status.Labels {
+			if !seen[name] && prometheusLabelName(name) {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	labelNames := []string{"monitor_id"}
+	for _, name := range names {
+		labelNames = append(labelNames, "label_"+name)
+	}
+	desc := prometheus.NewDesc(
+		"statx_monitor_labels",
+		"User labels of the monitor, always 1.",
+		labelNames, nil,
+	)
+
+	for _, status := range statuses {
+		values := []string{status.MonitorID}
+		for _, name := range names {
+			values = append(values, status.Labels[name])
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, values...)
+	}
+}
+
+func prometheusLabelName(name string) bool {
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/afrianjunior/statx/internal/recorder"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Handler serves the monitor gauges together with the Go runtime and
+// process metrics. The exposition format, Prometheus text or OpenMetrics, is
+// negotiated from the Accept header.
+func Handler(statuses recorder.StatusTracker) http.HandlerFunc {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		newCollector(statuses),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})
+
+	return handler.ServeHTTP
+}
@@ -0,0 +1,230 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Document is the subset of the OpenAPI 3.0 object model statx needs to
+// describe its REST API.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem maps lower case HTTP methods to operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+
+	// unvalidated operations keep their own error format, like the
+	// Prometheus compatible query API.
+	unvalidated bool
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	Responses       map[string]*Response       `json:"responses"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description,omitempty"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+}
+
+const schemaRefPrefix = "#/components/schemas/"
+
+func ref(name string) *Schema {
+	return &Schema{Ref: schemaRefPrefix + name}
+}
+
+// resolve follows a $ref to its component schema.
+func (d *Document) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = d.Components.Schemas[strings.TrimPrefix(schema.Ref, schemaRefPrefix)]
+	}
+	return schema
+}
+
+// Operation looks up the operation of a method and chi route pattern.
+func (d *Document) Operation(method, pattern string) *Operation {
+	item, ok := d.Paths[pattern]
+	if !ok {
+		return nil
+	}
+	return (*item)[strings.ToLower(method)]
+}
+
+// schemaRegistry derives component schemas from Go types through their json
+// tags. Registered struct types are referenced by name wherever they appear,
+// which also ends the recursion of self referencing types.
+type schemaRegistry struct {
+	names   map[reflect.Type]string
+	schemas map[string]*Schema
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		names:   make(map[reflect.Type]string),
+		schemas: make(map[string]*Schema),
+	}
+}
+
+// register adds the type of v as a component and returns a reference to it.
+func (s *schemaRegistry) register(name string, v any) *Schema {
+	t := reflect.TypeOf(v)
+	s.names[t] = name
+	s.schemas[name] = s.object(t)
+	return ref(name)
+}
+
+func (s *schemaRegistry) of(t reflect.Type) *Schema {
+	if name, ok := s.names[t]; ok {
+		return ref(name)
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.of(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Struct:
+		return s.object(t)
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem()), Nullable: true}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.of(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		return &Schema{}
+	}
+}
+
+// object describes the exported fields of a struct. Fields without
+// omitempty are always present in responses and listed as required.
+func (s *schemaRegistry) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = s.of(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// input derives a request body schema from a response component: required
+// is replaced and the server assigned fields are marked read only.
+func input(schema *Schema, required []string, readOnly ...string) *Schema {
+	in := *schema
+	in.Required = required
+	in.Properties = make(map[string]*Schema, len(schema.Properties))
+	for name, property := range schema.Properties {
+		in.Properties[name] = property
+	}
+	for _, name := range readOnly {
+		property := *in.Properties[name]
+		property.ReadOnly = true
+		in.Properties[name] = &property
+	}
+	return &in
+}
+
+// nullable marks every property of schema as accepting null, for patches
+// where null resets a field.
+func nullable(schema *Schema) *Schema {
+	out := *schema
+	out.Properties = make(map[string]*Schema, len(schema.Properties))
+	for name, property := range schema.Properties {
+		copied := *property
+		copied.Nullable = true
+		out.Properties[name] = &copied
+	}
+	return &out
+}
@@ -0,0 +1,28 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/go-chi/chi/v5"
+)
+
+// Handler serves the document as JSON.
+func Handler(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pkg.JsonResponse(w, doc, http.StatusOK)
+	}
+}
+
+// Undocumented lists the routes of routes without an operation in doc, so a
+// route added to the router but not to the document is noticed at start.
+func (d *Document) Undocumented(routes chi.Routes) []string {
+	var missing []string
+	_ = chi.Walk(routes, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if d.Operation(method, route) == nil {
+			missing = append(missing, method+" "+route)
+		}
+		return nil
+	})
+	return missing
+}
@@ -0,0 +1,711 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/afrianjunior/statx/internal/config_monitor"
+	"github.com/afrianjunior/statx/internal/export"
+	"github.com/afrianjunior/statx/internal/group"
+	"github.com/afrianjunior/statx/internal/overview"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/remote_write"
+	"github.com/afrianjunior/statx/internal/tag"
+)
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// responseNames are the shared error responses by status code.
+var responseNames = map[int]string{
+	http.StatusBadRequest:          "BadRequest",
+	http.StatusUnauthorized:        "Unauthorized",
+	http.StatusForbidden:           "Forbidden",
+	http.StatusNotFound:            "NotFound",
+	http.StatusConflict:            "Conflict",
+	http.StatusUnprocessableEntity: "ValidationFailed",
+	http.StatusInternalServerError: "InternalError",
+}
+
+// NewDocument describes every route of the REST API.
+func NewDocument() *Document {
+	d := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "statx",
+			Description: "Uptime monitoring backed by a Prometheus TSDB. Unless noted otherwise responses are wrapped in {success, message, data}.",
+			Version:     "1.0.0",
+		},
+		Paths: make(map[string]*PathItem),
+	}
+
+	s := newSchemaRegistry()
+	configMonitor := s.register("ConfigMonitor", pkg.ConfigMonitorDTO{})
+	tlsConfig := s.register("TLSConfig", pkg.TLSConfigDTO{})
+	tagSchema := s.register("Tag", pkg.TagDTO{})
+	groupSchema := s.register("Group", pkg.GroupDTO{})
+	groupStatus := s.register("GroupStatus", pkg.GroupStatus{})
+	maintenanceWindow := s.register("MaintenanceWindow", pkg.MaintenanceWindowDTO{})
+	contentChange := s.register("ContentChange", pkg.ContentChangeDTO{})
+	queryResult := s.register("QueryResult", pkg.QueryResult{})
+	uptimeSummary := s.register("UptimeSummary", pkg.UptimeSummary{})
+	s.register("MonitorStatus", pkg.MonitorStatus{})
+	checkEvent := s.register("CheckEvent", pkg.CheckEvent{})
+	s.register("MonitorOverview", pkg.MonitorOverview{})
+	monitorOverview := s.register("Overview", overview.Overview{})
+	exportRow := s.register("ExportRow", pkg.ExportRow{})
+	fieldError := s.register("FieldError", pkg.FieldError{})
+	writeResult := s.register("WriteResult", remote_write.WriteResult{})
+	schemas := s.schemas
+
+	monitorInput := input(schemas["ConfigMonitor"], []string{"name", "type"}, "id", "has_badge_token")
+	constrain(monitorInput, "badge_token", func(p *Schema) {
+		p.Description = "Write only. Leaving it out keeps the stored token, an empty string removes it."
+	})
+	constrain(monitorInput, "name", func(p *Schema) { p.MaxLength = intPtr(config_monitor.MaxNameLength) })
+	constrain(monitorInput, "type", func(p *Schema) { p.Enum = config_monitor.MonitorTypes })
+	constrain(monitorInput, "method", func(p *Schema) { p.Enum = config_monitor.HTTPMethods })
+	// 0 leaves the interval unset, which a schema cannot tell apart from the
+	// range, so the bounds are only checked by the service
+	constrain(monitorInput, "interval", func(p *Schema) {
+		p.Description = fmt.Sprintf("Seconds between checks, %d to %d. Required for uptime monitors.", config_monitor.MinInterval, config_monitor.MaxInterval)
+	})
+	constrain(monitorInput, "slo_percentile", func(p *Schema) {
+		p.Minimum, p.Maximum = floatPtr(0), floatPtr(config_monitor.MaxSLOPercentile)
+	})
+	for _, name := range config_monitor.NonNegativeFields {
+		constrain(monitorInput, name, func(p *Schema) { p.Minimum = floatPtr(0) })
+	}
+	schemas["ConfigMonitorInput"] = monitorInput
+	schemas["ConfigMonitorPatch"] = nullable(input(monitorInput, nil))
+
+	schemas["TLSConfigInput"] = input(schemas["TLSConfig"], []string{"name"}, "id", "has_client_key", "updated_at")
+	tagInput := input(schemas["Tag"], []string{"name"}, "id", "monitor_count", "created_at")
+	constrain(tagInput, "name", func(p *Schema) { p.MaxLength = intPtr(tag.MaxNameLength) })
+	schemas["TagInput"] = tagInput
+	groupInput := input(schemas["Group"], []string{"name"}, "id", "created_at")
+	constrain(groupInput, "name", func(p *Schema) { p.MaxLength = intPtr(group.MaxNameLength) })
+	schemas["GroupInput"] = groupInput
+	schemas["MaintenanceWindowInput"] = input(schemas["MaintenanceWindow"], []string{"starts_at", "ends_at"}, "id", "monitor_id", "created_at")
+
+	schemas["Error"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"message": {Type: "string"},
+			"data":    {Nullable: true},
+		},
+		Required: []string{"success", "message", "data"},
+	}
+	schemas["ValidationFailure"] = &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"message": {Type: "string"},
+			"data":    {Type: "array", Items: fieldError},
+		},
+		Required: []string{"success", "message", "data"},
+	}
+	schemas["PrometheusResponse"] = &Schema{
+		Type:        "object",
+		Description: "Prometheus HTTP API response.",
+		Properties: map[string]*Schema{
+			"status":    {Type: "string", Enum: []string{"success", "error"}},
+			"data":      {},
+			"errorType": {Type: "string"},
+			"error":     {Type: "string"},
+			"warnings":  {Type: "array", Items: &Schema{Type: "string"}},
+			"infos":     {Type: "array", Items: &Schema{Type: "string"}},
+		},
+		Required: []string{"status"},
+	}
+
+	d.Components = Components{
+		Schemas: schemas,
+		Responses: map[string]*Response{
+			"BadRequest":       errorResponse("The request is malformed.", ref("Error")),
+			"Unauthorized":     errorResponse("The bearer token is missing or wrong.", ref("Error")),
+			"Forbidden":        errorResponse("The operation is not allowed.", ref("Error")),
+			"NotFound":         errorResponse("The resource does not exist.", ref("Error")),
+			"Conflict":         errorResponse("The name is already taken.", ref("ValidationFailure")),
+			"ValidationFailed": errorResponse("The query parameters or the body are invalid.", ref("ValidationFailure")),
+			"InternalError":    errorResponse("The request failed on the server.", ref("Error")),
+		},
+		SecuritySchemes: map[string]*SecurityScheme{
+			"bearer": {Type: "http", Scheme: "bearer", Description: "admin_token for /api/admin, remote_write_receiver.bearer_token for /api/v1/write."},
+		},
+	}
+
+	monitorIDs := param("monitor_id", "Monitor ids, repeatable or comma separated.", array(str()))
+	timeRange := []*Parameter{
+		param("start", "Start of the range, RFC 3339. Used together with end.", dateTime()),
+		param("end", "End of the range, RFC 3339.", dateTime()),
+		param("duration", "Range ending now as a Go duration like 6h, takes precedence over start and end.", str()),
+	}
+	searchFilters := []*Parameter{
+		param("type", "", enum(config_monitor.MonitorTypes...)),
+		param("name", "Case insensitive substring of the name.", str()),
+		param("tag", "Tag name or label name.", str()),
+		param("group", "Group id, subgroups included.", str()),
+		param("status", "Current state.", enum(config_monitor.Statuses...)),
+	}
+
+	// Meta
+	d.add(http.MethodGet, "/api/openapi.json", &Operation{
+		OperationID: "getOpenAPIDocument",
+		Summary:     "This OpenAPI document",
+		Tags:        []string{"meta"},
+		Responses:   responses(raw("The document.", "application/json", &Schema{Type: "object"})),
+	})
+	d.add(http.MethodGet, "/metrics", &Operation{
+		OperationID: "getMetrics",
+		Summary:     "Monitor gauges and process metrics in the Prometheus or OpenMetrics text format",
+		Tags:        []string{"meta"},
+		Responses:   responses(raw("Metrics exposition.", "text/plain", str())),
+	})
+
+	// Status
+	d.add(http.MethodGet, "/api/status", &Operation{
+		OperationID: "getStatus",
+		Summary:     "Check results of a monitor",
+		Description: "Raw samples or rollups, picked from the length of the range unless resolution is given. With step the range is cut into step aligned buckets.",
+		Tags:        []string{"status"},
+		Parameters: append([]*Parameter{
+			required(param("monitor_id", "", str())),
+			param("resolution", "", enum(resolutions()...)),
+			param("step", "Bucket width like 5m or 1d. Multiples of a rollup resolution read the rollups, which keep neither status codes nor p50 and p99.", str()),
+		}, timeRange...),
+		Responses: responses(data("Check results.", array(queryResult)), http.StatusBadRequest),
+	})
+	d.add(http.MethodGet, "/api/overview", &Operation{
+		OperationID: "getOverview",
+		Summary:     "Dashboard summary of every monitor",
+		Description: fmt.Sprintf("State, last check and latency, 24h uptime and a %d point latency sparkline. Cached for a few seconds.", overview.SparklinePoints),
+		Tags:        []string{"status"},
+		Parameters:  append([]*Parameter{monitorIDs}, searchFilters...),
+		Responses:   responses(data("The overview.", monitorOverview)),
+	})
+	d.add(http.MethodGet, "/api/stream", &Operation{
+		OperationID: "streamEvents",
+		Summary:     "Check results and state changes as Server-Sent Events",
+		Description: "Every event carries a CheckEvent as data; a comment is sent as heartbeat.",
+		Tags:        []string{"status"},
+		Parameters: []*Parameter{
+			monitorIDs,
+			param("tag", "Tag name, label name or name:value label, repeatable or comma separated.", array(str())),
+			param("type", "", array(enum(pkg.EventCheck, pkg.EventStateChange))),
+		},
+		Responses: responses(raw("Event stream.", "text/event-stream", checkEvent)),
+	})
+	d.add(http.MethodGet, "/api/export", &Operation{
+		OperationID: "exportHistory",
+		Summary:     "Check history as CSV or NDJSON",
+		Tags:        []string{"status"},
+		Parameters: append([]*Parameter{
+			param("monitor_id", "Monitor ids, repeatable or comma separated. All monitors when absent.", array(str())),
+			param("format", "", withDefault(enum(export.FormatCSV, export.FormatNDJSON), export.FormatCSV)),
+		}, timeRange...),
+		Responses: responses(&Response{
+			Description: "One row per check, ordered by time.",
+			Content: map[string]*MediaType{
+				export.ContentType(export.FormatCSV):    {Schema: str()},
+				export.ContentType(export.FormatNDJSON): {Schema: exportRow},
+			},
+		}, http.StatusBadRequest),
+	})
+	d.add(http.MethodGet, "/api/monitors/{id}/uptime", &Operation{
+		OperationID: "getUptime",
+		Summary:     "Availability of a monitor per window",
+		Tags:        []string{"status"},
+		Parameters: []*Parameter{
+			param("windows", "Comma separated windows like 24h,7d,30d.", withDefault(str(), "24h,7d,30d,90d")),
+			param("exclude_maintenance", "Leave the maintenance windows of the monitor out.", boolean()),
+		},
+		Responses: responses(data("One summary per window.", array(uptimeSummary)), http.StatusBadRequest),
+	})
+
+	// Monitors
+	d.add(http.MethodPost, "/api/configs", &Operation{
+		OperationID: "createConfigMonitor",
+		Summary:     "Create a monitor",
+		Tags:        []string{"monitors"},
+		RequestBody: jsonBody(ref("ConfigMonitorInput")),
+		Responses:   responses(data("Id of the new monitor.", str()), http.StatusConflict),
+	})
+	d.add(http.MethodGet, "/api/configs", &Operation{
+		OperationID: "listConfigMonitors",
+		Summary:     "List monitors",
+		Tags:        []string{"monitors"},
+		Parameters: append([]*Parameter{
+			param("limit", "", withDefault(integer(1, config_monitor.MaxListLimit), config_monitor.DefaultListLimit)),
+			param("offset", "", integer(0, -1)),
+			param("cursor", "next_cursor of the previous page, not combinable with offset.", str()),
+			param("sort", "", withDefault(enum(config_monitor.SortKeys...), config_monitor.SortName)),
+			param("order", "", withDefault(enum("asc", "desc"), "asc")),
+		}, searchFilters...),
+		Responses: responses(data("A page of monitors.", object(map[string]*Schema{
+			"total":       {Type: "integer"},
+			"limit":       {Type: "integer"},
+			"offset":      {Type: "integer"},
+			"next_cursor": {Type: "string"},
+			"monitors":    array(configMonitor),
+		}, "total", "limit", "offset", "monitors")), http.StatusBadRequest),
+	})
+	d.add(http.MethodGet, "/api/configs/{id}", &Operation{
+		OperationID: "getConfigMonitor",
+		Summary:     "Get a monitor",
+		Tags:        []string{"monitors"},
+		Responses:   responses(data("The monitor.", configMonitor), http.StatusNotFound),
+	})
+	d.add(http.MethodPut, "/api/configs/{id}", &Operation{
+		OperationID: "updateConfigMonitor",
+		Summary:     "Replace a monitor",
+		Tags:        []string{"monitors"},
+		RequestBody: jsonBody(ref("ConfigMonitorInput")),
+		Responses:   responses(data("The updated monitor.", configMonitor), http.StatusNotFound, http.StatusConflict),
+	})
+	d.add(http.MethodPatch, "/api/configs/{id}", &Operation{
+		OperationID: "patchConfigMonitor",
+		Summary:     "Change some fields of a monitor",
+		Description: "Fields left out keep their value. Like a JSON merge patch, null resets a field to its default, so group_id null ungroups the monitor.",
+		Tags:        []string{"monitors"},
+		RequestBody: jsonBody(ref("ConfigMonitorPatch")),
+		Responses:   responses(data("The updated monitor.", configMonitor), http.StatusNotFound, http.StatusConflict),
+	})
+	d.add(http.MethodDelete, "/api/configs/{id}", &Operation{
+		OperationID: "deleteConfigMonitor",
+		Summary:     "Delete a monitor",
+		Tags:        []string{"monitors"},
+		Parameters:  []*Parameter{param("purge", "Also delete the check history from the TSDB.", boolean())},
+		Responses:   responses(data("Deleted.", nil), http.StatusNotFound),
+	})
+
+	// Maintenance and content changes
+	d.add(http.MethodPost, "/api/monitors/{id}/maintenance", &Operation{
+		OperationID: "createMaintenanceWindow",
+		Summary:     "Schedule a maintenance window",
+		Tags:        []string{"maintenance"},
+		RequestBody: jsonBody(ref("MaintenanceWindowInput")),
+		Responses:   responses(data("Id of the new window.", str()), http.StatusBadRequest),
+	})
+	d.add(http.MethodGet, "/api/monitors/{id}/maintenance", &Operation{
+		OperationID: "listMaintenanceWindows",
+		Summary:     "List the maintenance windows of a monitor",
+		Tags:        []string{"maintenance"},
+		Responses: responses(data("The windows.", object(map[string]*Schema{
+			"total":   {Type: "integer"},
+			"windows": array(maintenanceWindow),
+		}, "total", "windows"))),
+	})
+	d.add(http.MethodDelete, "/api/monitors/{id}/maintenance/{windowID}", &Operation{
+		OperationID: "deleteMaintenanceWindow",
+		Summary:     "Delete a maintenance window",
+		Tags:        []string{"maintenance"},
+		Responses:   responses(data("Deleted.", nil), http.StatusNotFound),
+	})
+	d.add(http.MethodGet, "/api/content-changes", &Operation{
+		OperationID: "listContentChanges",
+		Summary:     "Latest content changes of a monitor",
+		Tags:        []string{"monitors"},
+		Parameters:  []*Parameter{required(param("monitor_id", "", str()))},
+		Responses: responses(data("The changes.", object(map[string]*Schema{
+			"total":   {Type: "integer"},
+			"changes": array(contentChange),
+		}, "total", "changes"))),
+	})
+
+	// Tags
+	d.add(http.MethodPost, "/api/tags", &Operation{
+		OperationID: "createTag",
+		Summary:     "Create a tag",
+		Tags:        []string{"tags"},
+		RequestBody: jsonBody(ref("TagInput")),
+		Responses:   responses(data("Id of the new tag.", str()), http.StatusConflict),
+	})
+	d.add(http.MethodGet, "/api/tags", &Operation{
+		OperationID: "listTags",
+		Summary:     "List tags",
+		Tags:        []string{"tags"},
+		Responses: responses(data("The tags.", object(map[string]*Schema{
+			"total": {Type: "integer"},
+			"tags":  array(tagSchema),
+		}, "total", "tags"))),
+	})
+	d.add(http.MethodPut, "/api/tags/{id}", &Operation{
+		OperationID: "updateTag",
+		Summary:     "Rename or recolor a tag",
+		Tags:        []string{"tags"},
+		RequestBody: jsonBody(ref("TagInput")),
+		Responses:   responses(data("The updated tag.", tagSchema), http.StatusNotFound, http.StatusConflict),
+	})
+	d.add(http.MethodDelete, "/api/tags/{id}", &Operation{
+		OperationID: "deleteTag",
+		Summary:     "Delete a tag",
+		Tags:        []string{"tags"},
+		Responses:   responses(data("Deleted.", nil), http.StatusNotFound),
+	})
+
+	// Groups
+	d.add(http.MethodPost, "/api/groups", &Operation{
+		OperationID: "createGroup",
+		Summary:     "Create a group",
+		Tags:        []string{"groups"},
+		RequestBody: jsonBody(ref("GroupInput")),
+		Responses:   responses(data("Id of the new group.", str())),
+	})
+	d.add(http.MethodGet, "/api/groups", &Operation{
+		OperationID: "listGroups",
+		Summary:     "List groups",
+		Tags:        []string{"groups"},
+		Responses: responses(data("The groups.", object(map[string]*Schema{
+			"total":  {Type: "integer"},
+			"groups": array(groupSchema),
+		}, "total", "groups"))),
+	})
+	d.add(http.MethodGet, "/api/groups/status", &Operation{
+		OperationID: "listGroupStatuses",
+		Summary:     "Rolled up state of every top level group",
+		Tags:        []string{"groups"},
+		Responses: responses(data("The group states.", object(map[string]*Schema{
+			"total":  {Type: "integer"},
+			"groups": array(groupStatus),
+		}, "total", "groups"))),
+	})
+	d.add(http.MethodGet, "/api/groups/{id}", &Operation{
+		OperationID: "getGroup",
+		Summary:     "Get a group",
+		Tags:        []string{"groups"},
+		Responses:   responses(data("The group.", groupSchema), http.StatusNotFound),
+	})
+	d.add(http.MethodPut, "/api/groups/{id}", &Operation{
+		OperationID: "updateGroup",
+		Summary:     "Update a group",
+		Tags:        []string{"groups"},
+		RequestBody: jsonBody(ref("GroupInput")),
+		Responses:   responses(data("The updated group.", groupSchema), http.StatusNotFound),
+	})
+	d.add(http.MethodDelete, "/api/groups/{id}", &Operation{
+		OperationID: "deleteGroup",
+		Summary:     "Delete a group without subgroups",
+		Description: "Its monitors are ungrouped.",
+		Tags:        []string{"groups"},
+		Responses:   responses(data("Deleted.", nil), http.StatusNotFound, http.StatusConflict),
+	})
+	d.add(http.MethodGet, "/api/groups/{id}/status", &Operation{
+		OperationID: "getGroupStatus",
+		Summary:     "Rolled up state of a group and its subgroups",
+		Tags:        []string{"groups"},
+		Responses:   responses(data("The group state.", groupStatus), http.StatusNotFound),
+	})
+
+	// TLS configs
+	d.add(http.MethodPost, "/api/tls-configs", &Operation{
+		OperationID: "createTLSConfig",
+		Summary:     "Create a TLS config",
+		Description: "client_key is stored encrypted and never returned.",
+		Tags:        []string{"tls"},
+		RequestBody: jsonBody(ref("TLSConfigInput")),
+		Responses:   responses(data("Id of the new TLS config.", str()), http.StatusBadRequest),
+	})
+	d.add(http.MethodGet, "/api/tls-configs", &Operation{
+		OperationID: "listTLSConfigs",
+		Summary:     "List TLS configs",
+		Tags:        []string{"tls"},
+		Responses: responses(data("The TLS configs.", object(map[string]*Schema{
+			"total":       {Type: "integer"},
+			"tls_configs": array(tlsConfig),
+		}, "total", "tls_configs"))),
+	})
+	d.add(http.MethodGet, "/api/tls-configs/{id}", &Operation{
+		OperationID: "getTLSConfig",
+		Summary:     "Get a TLS config",
+		Tags:        []string{"tls"},
+		Responses:   responses(data("The TLS config.", tlsConfig), http.StatusNotFound),
+	})
+
+	// Badges
+	badgeParams := []*Parameter{
+		param("token", "Required when the monitor has a badge token.", str()),
+		param("label", "Replaces the left hand text.", str()),
+		param("color", "Named color or hex of the right hand side.", str()),
+		param("label_color", "Named color or hex of the left hand side.", str()),
+	}
+	badge := raw("The badge.", "image/svg+xml", str())
+	d.add(http.MethodGet, "/badge/{id}/status.svg", &Operation{
+		OperationID: "getStatusBadge",
+		Summary:     "Current state badge",
+		Tags:        []string{"badges"},
+		Parameters:  badgeParams,
+		Responses:   responses(badge, http.StatusForbidden, http.StatusNotFound),
+	})
+	d.add(http.MethodGet, "/badge/{id}/uptime.svg", &Operation{
+		OperationID: "getUptimeBadge",
+		Summary:     "Availability badge",
+		Tags:        []string{"badges"},
+		Parameters:  append([]*Parameter{param("window", "Window like 24h or 30d, at most 90d.", withDefault(str(), "30d"))}, badgeParams...),
+		Responses:   responses(badge, http.StatusForbidden, http.StatusNotFound),
+	})
+	d.add(http.MethodGet, "/badge/{id}/latency.svg", &Operation{
+		OperationID: "getLatencyBadge",
+		Summary:     "Last latency badge",
+		Tags:        []string{"badges"},
+		Parameters:  badgeParams,
+		Responses:   responses(badge, http.StatusForbidden, http.StatusNotFound),
+	})
+
+	// Prometheus compatible query API
+	selectors := param("match[]", "Series selector, repeatable.", array(str()))
+	timeout := param("timeout", "Evaluation timeout.", str())
+	queryParams := []*Parameter{
+		required(param("query", "PromQL expression.", str())),
+		param("time", "Evaluation time, RFC 3339 or unix seconds. Defaults to now.", str()),
+		timeout,
+	}
+	rangeParams := []*Parameter{
+		required(param("query", "PromQL expression.", str())),
+		required(param("start", "RFC 3339 or unix seconds.", str())),
+		required(param("end", "RFC 3339 or unix seconds.", str())),
+		required(param("step", "Duration or float seconds.", str())),
+		timeout,
+	}
+	metadataParams := []*Parameter{
+		selectors,
+		param("start", "RFC 3339 or unix seconds.", str()),
+		param("end", "RFC 3339 or unix seconds.", str()),
+	}
+	d.addPrometheus("/api/v1/query", "query", "Evaluate an instant query", queryParams, true)
+	d.addPrometheus("/api/v1/query_range", "queryRange", "Evaluate a range query", rangeParams, true)
+	d.addPrometheus("/api/v1/series", "series", "Find series by label matchers", metadataParams, true)
+	d.addPrometheus("/api/v1/labels", "labelNames", "List label names", metadataParams, true)
+	d.addPrometheus("/api/v1/label/{name}/values", "labelValues", "List the values of a label", metadataParams, false)
+
+	d.add(http.MethodPost, "/api/v1/write", &Operation{
+		OperationID: "remoteWrite",
+		Summary:     "Prometheus remote_write receiver",
+		Description: "Only served when remote_write_receiver.enabled is set. Samples are stored for generic monitors.",
+		Tags:        []string{"query"},
+		Security:    []map[string][]string{{"bearer": {}}},
+		Parameters: []*Parameter{
+			param("namespace", "Prefix of the stored metric names.", str()),
+			param("monitor_id", "Generic monitor the samples belong to.", str()),
+		},
+		RequestBody: &RequestBody{
+			Description: "Snappy compressed protobuf WriteRequest.",
+			Required:    true,
+			Content:     map[string]*MediaType{"application/x-protobuf": {Schema: &Schema{Type: "string", Format: "binary"}}},
+		},
+		Responses: map[string]*Response{
+			"204": {Description: "Stored."},
+			"400": {Description: "Invalid request or rejected samples; data counts what was stored.", Content: jsonContent(envelope(writeResult))},
+			"401": responseRef(http.StatusUnauthorized),
+			"404": responseRef(http.StatusNotFound),
+		},
+	})
+
+	// Admin
+	d.add(http.MethodPost, "/api/admin/backup", &Operation{
+		OperationID: "createBackup",
+		Summary:     "Create and download a backup of the TSDB and SQLite database",
+		Tags:        []string{"admin"},
+		Security:    []map[string][]string{{"bearer": {}}},
+		Responses: responses(raw("The backup archive.", "application/gzip", &Schema{Type: "string", Format: "binary"}),
+			http.StatusUnauthorized, http.StatusForbidden),
+	})
+
+	return d
+}
+
+func resolutions() []string {
+	names := []string{pkg.ResolutionRaw}
+	for _, res := range pkg.Resolutions {
+		names = append(names, res.Name)
+	}
+	return names
+}
+
+// add registers an operation. Path parameters are declared from the path
+// and the validation and server errors are added to the responses.
+func (d *Document) add(method, path string, op *Operation) {
+	var params []*Parameter
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		params = append(params, &Parameter{Name: match[1], In: "path", Required: true, Schema: str()})
+	}
+	op.Parameters = append(params, op.Parameters...)
+
+	if !op.unvalidated {
+		for _, param := range op.Parameters {
+			if param.In == "query" {
+				op.Responses["422"] = responseRef(http.StatusUnprocessableEntity)
+			}
+		}
+		if op.RequestBody != nil && op.RequestBody.Content["application/json"] != nil {
+			op.Responses["400"] = responseRef(http.StatusBadRequest)
+			op.Responses["422"] = responseRef(http.StatusUnprocessableEntity)
+		}
+		op.Responses["500"] = responseRef(http.StatusInternalServerError)
+	}
+
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+	(*item)[strings.ToLower(method)] = op
+}
+
+// addPrometheus registers a route of the query API. They answer in the
+// Prometheus format and are left to their handlers for validation. The
+// parameters can also be sent form encoded with POST.
+func (d *Document) addPrometheus(path, operationID, summary string, params []*Parameter, post bool) {
+	answer := func() map[string]*Response {
+		return map[string]*Response{
+			"200": {Description: "Success.", Content: jsonContent(ref("PrometheusResponse"))},
+			"400": {Description: "Bad parameters.", Content: jsonContent(ref("PrometheusResponse"))},
+			"422": {Description: "The expression cannot be executed.", Content: jsonContent(ref("PrometheusResponse"))},
+			"503": {Description: "Query timed out or was canceled.", Content: jsonContent(ref("PrometheusResponse"))},
+		}
+	}
+
+	d.add(http.MethodGet, path, &Operation{
+		OperationID: operationID,
+		Summary:     summary,
+		Tags:        []string{"query"},
+		Parameters:  params,
+		Responses:   answer(),
+		unvalidated: true,
+	})
+	if !post {
+		return
+	}
+
+	form := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, param := range params {
+		form.Properties[param.Name] = param.Schema
+		if param.Required {
+			form.Required = append(form.Required, param.Name)
+		}
+	}
+	d.add(http.MethodPost, path, &Operation{
+		OperationID: operationID + "Post",
+		Summary:     summary,
+		Tags:        []string{"query"},
+		RequestBody: &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/x-www-form-urlencoded": {Schema: form}},
+		},
+		Responses:   answer(),
+		unvalidated: true,
+	})
+}
+
+func responses(ok *Response, codes ...int) map[string]*Response {
+	out := map[string]*Response{"200": ok}
+	for _, code := range codes {
+		out[strconv.Itoa(code)] = responseRef(code)
+	}
+	return out
+}
+
+func responseRef(code int) *Response {
+	return &Response{Ref: "#/components/responses/" + responseNames[code]}
+}
+
+func errorResponse(description string, schema *Schema) *Response {
+	return &Response{Description: description, Content: jsonContent(schema)}
+}
+
+// data describes a successful response wrapped in pkg.BaseResponse.
+func data(description string, schema *Schema) *Response {
+	return &Response{Description: description, Content: jsonContent(envelope(schema))}
+}
+
+func raw(description, contentType string, schema *Schema) *Response {
+	return &Response{Description: description, Content: map[string]*MediaType{contentType: {Schema: schema}}}
+}
+
+func envelope(schema *Schema) *Schema {
+	if schema == nil {
+		schema = &Schema{Nullable: true}
+	}
+	return object(map[string]*Schema{
+		"success": {Type: "boolean"},
+		"message": {Type: "string"},
+		"data":    schema,
+	}, "success", "message", "data")
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: schema}}
+}
+
+func jsonBody(schema *Schema) *RequestBody {
+	return &RequestBody{Required: true, Content: jsonContent(schema)}
+}
+
+func param(name, description string, schema *Schema) *Parameter {
+	return &Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func required(param *Parameter) *Parameter {
+	param.Required = true
+	return param
+}
+
+func object(properties map[string]*Schema, required ...string) *Schema {
+	return &Schema{Type: "object", Properties: properties, Required: required}
+}
+
+func array(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+func str() *Schema {
+	return &Schema{Type: "string"}
+}
+
+func dateTime() *Schema {
+	return &Schema{Type: "string", Format: "date-time"}
+}
+
+func boolean() *Schema {
+	return &Schema{Type: "boolean"}
+}
+
+func enum(values ...string) *Schema {
+	return &Schema{Type: "string", Enum: values}
+}
+
+// integer bounds are left open when negative.
+func integer(minimum, maximum int) *Schema {
+	schema := &Schema{Type: "integer"}
+	if minimum >= 0 {
+		schema.Minimum = floatPtr(float64(minimum))
+	}
+	if maximum >= 0 {
+		schema.Maximum = floatPtr(float64(maximum))
+	}
+	return schema
+}
+
+func withDefault(schema *Schema, value any) *Schema {
+	schema.Default = value
+	return schema
+}
+
+// constrain changes a property of an input schema without touching the
+// component it was derived from.
+func constrain(schema *Schema, name string, change func(property *Schema)) {
+	property := *schema.Properties[name]
+	change(&property)
+	schema.Properties[name] = &property
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func intPtr(i int) *int {
+	return &i
+}
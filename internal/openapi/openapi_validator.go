@@ -0,0 +1,262 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/go-chi/chi/v5"
+)
+
+const maxBodySize = 1 << 20
+
+// Validator checks the query parameters and JSON request bodies against the
+// operation of the route before the handler runs. Like the handlers, bodies
+// are read as JSON whatever their Content-Type. Malformed JSON is answered
+// with 400 and schema violations with 422 and their field errors, the same
+// payloads the handlers use. Routes are resolved with routes so operations
+// are found by the same path templates the router uses.
+func Validator(doc *Document, routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rctx := chi.NewRouteContext()
+			if !routes.Match(rctx, r.Method, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			op := doc.Operation(r.Method, rctx.RoutePattern())
+			if op == nil || op.unvalidated {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			errs := &pkg.ValidationError{}
+			query := r.URL.Query()
+			for _, param := range op.Parameters {
+				if param.In == "query" {
+					doc.validateParameter(param, query[param.Name], errs)
+				}
+			}
+
+			if body := op.RequestBody; body != nil && body.Content["application/json"] != nil {
+				raw, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+				r.Body.Close()
+				r.Body = io.NopCloser(bytes.NewReader(raw))
+
+				var value any
+				if err == nil && (len(bytes.TrimSpace(raw)) > 0 || body.Required) {
+					decoder := json.NewDecoder(bytes.NewReader(raw))
+					decoder.UseNumber()
+					if err = decoder.Decode(&value); err == nil {
+						doc.validate(body.Content["application/json"].Schema, value, "", errs)
+					}
+				}
+				if err != nil {
+					pkg.JsonResponse(w, pkg.BaseResponse{
+						Success: false,
+						Message: "invalid req body",
+						Data:    nil,
+					}, http.StatusBadRequest)
+					return
+				}
+			}
+
+			if len(errs.Errors) > 0 {
+				pkg.JsonResponse(w, pkg.BaseResponse{
+					Success: false,
+					Message: "validation failed",
+					Data:    errs.Errors,
+				}, http.StatusUnprocessableEntity)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validateParameter checks the values of a query parameter. Empty values
+// count as absent and array parameters take repeated or comma separated
+// values.
+func (d *Document) validateParameter(param *Parameter, values []string, errs *pkg.ValidationError) {
+	schema := d.resolve(param.Schema)
+
+	var present []string
+	for _, value := range values {
+		if schema.Type != "array" {
+			present = append(present, value)
+			continue
+		}
+		for _, v := range strings.Split(value, ",") {
+			present = append(present, strings.TrimSpace(v))
+		}
+	}
+	present = nonEmpty(present)
+
+	if len(present) == 0 {
+		if param.Required {
+			errs.Add(param.Name, "is required")
+		}
+		return
+	}
+
+	if schema.Type == "array" {
+		for _, value := range present {
+			d.validate(schema.Items, parseScalar(d.resolve(schema.Items), value), param.Name, errs)
+		}
+		return
+	}
+	d.validate(schema, parseScalar(schema, present[0]), param.Name, errs)
+}
+
+// parseScalar converts a query value to the JSON value it stands for. Values
+// that do not parse stay strings and fail validation.
+func parseScalar(schema *Schema, value string) any {
+	switch schema.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// validate checks a decoded JSON value against schema, adding an error per
+// offending field. Nested fields are named like labels.env and tags[0].
+func (d *Document) validate(schema *Schema, value any, field string, errs *pkg.ValidationError) {
+	schema = d.resolve(schema)
+	if schema == nil || schema.ReadOnly {
+		return
+	}
+	name := field
+	if name == "" {
+		name = "body"
+	}
+
+	if value == nil {
+		if !schema.Nullable && schema.Type != "" {
+			errs.Add(name, "must not be null")
+		}
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			errs.Add(name, "must be an object")
+			return
+		}
+		for _, property := range schema.Required {
+			if _, ok := object[property]; !ok {
+				errs.Add(join(field, property), "is required")
+			}
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if property, ok := schema.Properties[key]; ok {
+				d.validate(property, object[key], join(field, key), errs)
+			} else if schema.AdditionalProperties != nil {
+				d.validate(schema.AdditionalProperties, object[key], join(field, key), errs)
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			errs.Add(name, "must be an array")
+			return
+		}
+		for i, item := range items {
+			d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", name, i), errs)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			errs.Add(name, "must be a string")
+			return
+		}
+		if schema.MaxLength != nil && utf8.RuneCountInString(s) > *schema.MaxLength {
+			errs.Add(name, fmt.Sprintf("must be at most %d characters", *schema.MaxLength))
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				errs.Add(name, "must be an RFC 3339 timestamp")
+			}
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, s) {
+			errs.Add(name, fmt.Sprintf("must be one of %s", strings.Join(schema.Enum, ", ")))
+		}
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			errs.Add(name, fmt.Sprintf("must be %s", article(schema.Type)))
+			return
+		}
+		if schema.Type == "integer" {
+			if _, err := n.Int64(); err != nil {
+				errs.Add(name, "must be an integer")
+				return
+			}
+		}
+		f, _ := n.Float64()
+		if schema.Minimum != nil && f < *schema.Minimum {
+			errs.Add(name, fmt.Sprintf("must be at least %v", *schema.Minimum))
+		}
+		if schema.Maximum != nil && f > *schema.Maximum {
+			errs.Add(name, fmt.Sprintf("must be at most %v", *schema.Maximum))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errs.Add(name, "must be a boolean")
+		}
+	}
+}
+
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+func article(schemaType string) string {
+	if schemaType == "integer" {
+		return "an integer"
+	}
+	return "a " + schemaType
+}
+
+func nonEmpty(values []string) []string {
+	out := values[:0]
+	for _, value := range values {
+		if value != "" {
+			out = append(out, value)
+		}
+	}
+	return out
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
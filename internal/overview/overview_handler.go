@@ -0,0 +1,78 @@
+package overview
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/afrianjunior/statx/internal/config_monitor"
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+// OverviewHandler returns the state, last check, last latency, 24h uptime and
+// latency sparkline of every monitor, optionally narrowed by monitor_id
+// (repeatable or comma separated), type, name, tag, group and status.
+func OverviewHandler(overviewSvc OverviewService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, errs := parseParams(r)
+		if errs != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: "validation failed",
+				Data:    errs.Errors,
+			}, http.StatusUnprocessableEntity)
+			return
+		}
+
+		overview, err := overviewSvc.GetOverview(r.Context(), params)
+		if err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    overview,
+		}, http.StatusOK)
+	}
+}
+
+func parseParams(r *http.Request) (Params, *pkg.ValidationError) {
+	query := r.URL.Query()
+	errs := &pkg.ValidationError{}
+
+	params := Params{
+		SearchFilter: config_monitor.SearchFilter{
+			Type:    query.Get("type"),
+			Name:    query.Get("name"),
+			Tag:     query.Get("tag"),
+			GroupID: query.Get("group"),
+		},
+		Status: query.Get("status"),
+	}
+	for _, value := range query["monitor_id"] {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				params.MonitorIDs = append(params.MonitorIDs, id)
+			}
+		}
+	}
+
+	if params.Type != "" && !slices.Contains(config_monitor.MonitorTypes, params.Type) {
+		errs.Add("type", fmt.Sprintf("must be one of %s", strings.Join(config_monitor.MonitorTypes, ", ")))
+	}
+	if params.Status != "" && !slices.Contains(config_monitor.Statuses, params.Status) {
+		errs.Add("status", fmt.Sprintf("must be one of %s", strings.Join(config_monitor.Statuses, ", ")))
+	}
+
+	if len(errs.Errors) > 0 {
+		return params, errs
+	}
+	return params, nil
+}
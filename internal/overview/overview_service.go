@@ -0,0 +1,363 @@
+package overview
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/config_monitor"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/recorder"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+const (
+	Window          = 24 * time.Hour
+	SparklinePoints = 90
+	// cacheTTL lets many dashboards share one computation.
+	cacheTTL = 10 * time.Second
+)
+
+type Params struct {
+	config_monitor.SearchFilter
+	MonitorIDs []string
+	Status     string
+}
+
+type Overview struct {
+	GeneratedAt time.Time             `json:"generated_at"`
+	Window      string                `json:"window"`
+	Points      int                   `json:"points"`
+	Total       int                   `json:"total"`
+	Monitors    []pkg.MonitorOverview `json:"monitors"`
+}
+
+type cacheEntry struct {
+	overview  *Overview
+	expiresAt time.Time
+}
+
+type overviewService struct {
+	configMonitorRepository config_monitor.ConfigMonitorRepository
+	targets                 []pkg.Target
+	tsdb                    *tsdb.DB
+	statuses                recorder.StatusTracker
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type OverviewService interface {
+	// GetOverview summarizes the monitors matching params: current state,
+	// last check and latency, uptime and a latency sparkline over Window.
+	// Results are cached for a few seconds per distinct params.
+	GetOverview(ctx context.Context, params Params) (*Overview, error)
+}
+
+// NewOverviewService lists the stored monitors together with targets, the
+// monitors declared in config.json.
+func NewOverviewService(
+	configMonitorRepository config_monitor.ConfigMonitorRepository,
+	targets []pkg.Target,
+	tsdb *tsdb.DB,
+	statuses recorder.StatusTracker,
+) OverviewService {
+	return &overviewService{
+		configMonitorRepository: configMonitorRepository,
+		targets:                 targets,
+		tsdb:                    tsdb,
+		statuses:                statuses,
+		cache:                   make(map[string]cacheEntry),
+	}
+}
+
+func (s *overviewService) GetOverview(ctx context.Context, params Params) (*Overview, error) {
+	key := cacheKey(params)
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.cache[key]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.overview, nil
+	}
+
+	overview, err := s.compute(ctx, params, now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	for k, e := range s.cache {
+		if now.After(e.expiresAt) {
+			delete(s.cache, k)
+		}
+	}
+	s.cache[key] = cacheEntry{overview: overview, expiresAt: now.Add(cacheTTL)}
+	s.mu.Unlock()
+
+	return overview, nil
+}
+
+// accumulator collects the samples of one monitor during the querier pass.
+type accumulator struct {
+	upSamples, upTotal int
+	lastTs             int64
+	lastUp             bool
+	lastStateTs        int64
+	lastState          string
+	lastLatencyTs      int64
+	lastLatency        float64
+	latencySums        [SparklinePoints]float64
+	latencyCounts      [SparklinePoints]int
+}
+
+func (s *overviewService) compute(ctx context.Context, params Params, now time.Time) (*Overview, error) {
+	configs, err := s.configMonitorRepository.Search(ctx, params.SearchFilter)
+	if err != nil {
+		return nil, err
+	}
+	configs = s.withTargets(configs, params.SearchFilter)
+	if len(params.MonitorIDs) > 0 {
+		wanted := make(map[string]bool, len(params.MonitorIDs))
+		for _, id := range params.MonitorIDs {
+			wanted[id] = true
+		}
+		filtered := configs[:0]
+		for _, config := range configs {
+			if wanted[config.ID] {
+				filtered = append(filtered, config)
+			}
+		}
+		configs = filtered
+	}
+
+	start := now.Add(-Window)
+	accs := make(map[string]*accumulator, len(configs))
+	ids := make([]string, 0, len(configs))
+	for _, config := range configs {
+		accs[config.ID] = &accumulator{}
+		ids = append(ids, regexp.QuoteMeta(config.ID))
+	}
+
+	if len(ids) > 0 {
+		if err := s.scan(ctx, start, now, strings.Join(ids, "|"), accs); err != nil {
+			return nil, err
+		}
+	}
+
+	overview := &Overview{
+		GeneratedAt: now.UTC(),
+		Window:      "24h",
+		Points:      SparklinePoints,
+		Monitors:    make([]pkg.MonitorOverview, 0, len(configs)),
+	}
+	for _, config := range configs {
+		item := summarize(config, accs[config.ID])
+		if status, ok := s.statuses.Get(config.ID); ok {
+			lastCheck := status.LastCheck
+			item.State = status.State
+			item.LastCheck = &lastCheck
+			item.LastLatency = nil
+			if status.Up {
+				latency := status.ResponseTime
+				item.LastLatency = &latency
+			}
+		}
+		if params.Status != "" && item.State != params.Status {
+			continue
+		}
+		overview.Monitors = append(overview.Monitors, item)
+	}
+	overview.Total = len(overview.Monitors)
+
+	return overview, nil
+}
+
+// withTargets adds the config.json targets matching filter to the stored
+// monitors, keeping the name order of Search. Config targets belong to no
+// group.
+func (s *overviewService) withTargets(configs []*pkg.ConfigMonitorDTO, filter config_monitor.SearchFilter) []*pkg.ConfigMonitorDTO {
+	if len(s.targets) == 0 || filter.GroupID != "" {
+		return configs
+	}
+
+	known := make(map[string]bool, len(configs))
+	for _, config := range configs {
+		known[config.ID] = true
+	}
+	added := false
+	for _, target := range s.targets {
+		if known[target.MonitorID()] || !matchesTarget(target, filter) {
+			continue
+		}
+		configs = append(configs, &pkg.ConfigMonitorDTO{
+			ID:     target.MonitorID(),
+			Type:   target.MonitorType(),
+			Name:   target.Name,
+			URL:    target.URL,
+			Labels: target.Labels,
+			Tags:   target.Tags,
+		})
+		added = true
+	}
+	if added {
+		sort.SliceStable(configs, func(i, j int) bool {
+			a, b := strings.ToLower(configs[i].Name), strings.ToLower(configs[j].Name)
+			if a != b {
+				return a < b
+			}
+			return configs[i].ID < configs[j].ID
+		})
+	}
+	return configs
+}
+
+// matchesTarget applies the type, name and tag filters of Search to a
+// config.json target.
+func matchesTarget(target pkg.Target, filter config_monitor.SearchFilter) bool {
+	if filter.Type != "" && target.MonitorType() != filter.Type {
+		return false
+	}
+	if filter.Name != "" && !strings.Contains(strings.ToLower(target.Name), strings.ToLower(filter.Name)) {
+		return false
+	}
+	if filter.Tag != "" && !slices.Contains(target.Tags, filter.Tag) {
+		name, value, hasValue := strings.Cut(filter.Tag, ":")
+		labelValue, ok := target.Labels[name]
+		if !ok || (hasValue && labelValue != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// scan reads the up, latency and state series of all monitors matching the
+// monitor_id regex with one querier.
+func (s *overviewService) scan(ctx context.Context, start, end time.Time, monitorIDs string, accs map[string]*accumulator) error {
+	querier, err := s.tsdb.Querier(start.UnixMilli(), end.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("error creating querier: %v", err)
+	}
+	defer querier.Close()
+
+	seriesSet := querier.Select(ctx, false, nil,
+		labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, "up|http_response_time|monitor_state"),
+		labels.MustNewMatcher(labels.MatchRegexp, "monitor_id", monitorIDs),
+		pkg.OwnSeries,
+	)
+
+	startMs := start.UnixMilli()
+	bucketMs := Window.Milliseconds() / SparklinePoints
+	for seriesSet.Next() {
+		lset := seriesSet.At().Labels()
+		acc, ok := accs[lset.Get("monitor_id")]
+		if !ok {
+			continue
+		}
+		metric := lset.Get(labels.MetricName)
+
+		iter := seriesSet.At().Iterator(nil)
+		for iter.Next() == chunkenc.ValFloat {
+			ts, val := iter.At()
+			switch metric {
+			case "up":
+				acc.upTotal++
+				if val == 1 {
+					acc.upSamples++
+				}
+				if ts >= acc.lastTs {
+					acc.lastTs = ts
+					acc.lastUp = val == 1
+				}
+			case "http_response_time":
+				i := min((ts-startMs)/bucketMs, SparklinePoints-1)
+				if i >= 0 {
+					acc.latencySums[i] += val
+					acc.latencyCounts[i]++
+				}
+				if ts >= acc.lastLatencyTs {
+					acc.lastLatencyTs = ts
+					acc.lastLatency = val
+				}
+			case "monitor_state":
+				if ts >= acc.lastStateTs {
+					acc.lastStateTs = ts
+					acc.lastState = pkg.State(val).String()
+				}
+			}
+		}
+	}
+	if err := seriesSet.Err(); err != nil {
+		return fmt.Errorf("error selecting series: %v", err)
+	}
+
+	return nil
+}
+
+// summarize builds the overview of a monitor from the TSDB samples. The
+// caller prefers the in-memory status for the current state when it has one.
+func summarize(config *pkg.ConfigMonitorDTO, acc *accumulator) pkg.MonitorOverview {
+	item := pkg.MonitorOverview{
+		MonitorID: config.ID,
+		Name:      config.Name,
+		Type:      config.Type,
+		URL:       config.URL,
+		Tags:      config.Tags,
+		GroupID:   config.GroupID,
+		State:     config_monitor.StatusUnknown,
+		Sparkline: make([]*float64, SparklinePoints),
+	}
+
+	if acc.upTotal > 0 {
+		uptime := float64(acc.upSamples) / float64(acc.upTotal)
+		item.Uptime24h = &uptime
+
+		lastCheck := time.UnixMilli(acc.lastTs).UTC()
+		item.LastCheck = &lastCheck
+		// the state of a check is written with its up sample; a state
+		// from an earlier check does not describe the last one
+		if acc.lastStateTs == acc.lastTs {
+			item.State = acc.lastState
+		}
+		if item.State == "" {
+			item.State = pkg.StateDown.String()
+			if acc.lastUp {
+				item.State = pkg.StateUp.String()
+			}
+		}
+		if acc.lastUp && acc.lastLatencyTs == acc.lastTs {
+			latency := acc.lastLatency
+			item.LastLatency = &latency
+		}
+	}
+
+	for i := range item.Sparkline {
+		if acc.latencyCounts[i] > 0 {
+			avg := acc.latencySums[i] / float64(acc.latencyCounts[i])
+			item.Sparkline[i] = &avg
+		}
+	}
+
+	return item
+}
+
+func cacheKey(params Params) string {
+	ids := append([]string{}, params.MonitorIDs...)
+	sort.Strings(ids)
+	return strings.Join([]string{
+		params.Type,
+		params.Name,
+		params.Tag,
+		params.GroupID,
+		params.Status,
+		strings.Join(ids, ","),
+	}, "\x00")
+}
@@ -0,0 +1,98 @@
+package overview
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/config_monitor"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/recorder"
+	"github.com/afrianjunior/statx/internal/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+func TestOverviewIncludesConfigTargets(t *testing.T) {
+	ctx := context.Background()
+	config := testutil.Config(t)
+	db := testutil.DB(t)
+	repository := config_monitor.NewConfigMonitorRepository(db)
+	if _, err := repository.Insert(ctx, &pkg.ConfigMonitorDTO{Type: "uptime", Name: "stored", URL: "http://stored", Interval: 60}); err != nil {
+		t.Fatalf("inserting monitor: %v", err)
+	}
+
+	target := pkg.Target{Name: "declared", URL: "http://declared", Interval: time.Minute, Tags: []string{"edge"}}
+	statuses := recorder.NewStatusTracker()
+	statuses.Update(target, pkg.CheckResult{Up: true, ResponseTime: 12, State: pkg.StateUp}, time.Now())
+
+	service := NewOverviewService(repository, []pkg.Target{target}, testutil.TSDB(t, config), statuses)
+
+	overview, err := service.GetOverview(ctx, Params{})
+	if err != nil {
+		t.Fatalf("getting overview: %v", err)
+	}
+	if overview.Total != 2 || overview.Monitors[0].Name != "declared" || overview.Monitors[1].Name != "stored" {
+		t.Fatalf("overview lists %+v, want the config target and the stored monitor by name", overview.Monitors)
+	}
+	declared := overview.Monitors[0]
+	if declared.MonitorID != target.MonitorID() || declared.State != pkg.StateUp.String() || declared.Type != "uptime" {
+		t.Errorf("config target overview = %+v", declared)
+	}
+
+	for _, filter := range []config_monitor.SearchFilter{{Type: "generic"}, {Name: "stor"}, {Tag: "core"}, {GroupID: "g"}} {
+		overview, err := service.GetOverview(ctx, Params{SearchFilter: filter})
+		if err != nil {
+			t.Fatalf("getting overview: %v", err)
+		}
+		for _, item := range overview.Monitors {
+			if item.MonitorID == target.MonitorID() {
+				t.Errorf("filter %+v kept the config target", filter)
+			}
+		}
+	}
+	overview, err = service.GetOverview(ctx, Params{SearchFilter: config_monitor.SearchFilter{Tag: "edge"}})
+	if err != nil {
+		t.Fatalf("getting overview: %v", err)
+	}
+	if overview.Total != 1 || overview.Monitors[0].MonitorID != target.MonitorID() {
+		t.Errorf("tag filter = %+v, want the config target", overview.Monitors)
+	}
+}
+
+func TestOverviewStateIsThatOfTheLastCheck(t *testing.T) {
+	ctx := context.Background()
+	config := testutil.Config(t)
+	db := testutil.DB(t)
+	storage := testutil.TSDB(t, config)
+	repository := config_monitor.NewConfigMonitorRepository(db)
+	id, err := repository.Insert(ctx, &pkg.ConfigMonitorDTO{Type: "uptime", Name: "renamed", URL: "http://a", Interval: 60})
+	if err != nil {
+		t.Fatalf("inserting monitor: %v", err)
+	}
+
+	// A rename starts new series. Whether the series are read by name or in
+	// the order they were created, the state series come before the up
+	// series and the older state of the old name after the newer one.
+	now := time.Now()
+	older, newer := now.Add(-2*time.Minute).UnixMilli(), now.Add(-time.Minute).UnixMilli()
+	series := func(metric, name string) labels.Labels {
+		return labels.FromStrings(labels.MetricName, metric, "monitor_id", id, "name", name)
+	}
+	appender := storage.Appender(ctx)
+	appender.Append(0, series("monitor_state", "a-new"), newer, float64(pkg.StateDegraded))
+	appender.Append(0, series("monitor_state", "b-old"), older, float64(pkg.StateDown))
+	appender.Append(0, series("up", "b-old"), older, 0)
+	appender.Append(0, series("up", "a-new"), newer, 1)
+	if err := appender.Commit(); err != nil {
+		t.Fatalf("committing: %v", err)
+	}
+
+	service := NewOverviewService(repository, nil, storage, recorder.NewStatusTracker())
+	overview, err := service.GetOverview(ctx, Params{})
+	if err != nil {
+		t.Fatalf("getting overview: %v", err)
+	}
+	if got := overview.Monitors[0].State; got != pkg.StateDegraded.String() {
+		t.Fatalf("state = %s, want degraded, the state of the last check", got)
+	}
+}
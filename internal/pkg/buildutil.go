@@ -1,6 +1,9 @@
 package pkg
 
-import "time"
+import (
+	"path/filepath"
+	"time"
+)
 
 type Config struct {
 	Targets          []Target      `json:"targets"`
@@ -14,4 +17,87 @@ type Config struct {
 	RetryAttempts    int           `json:"retry_attempts"`
 	RetryDelay       time.Duration `json:"retry_delay"`
 	MaxSamplesPerDay int64         `json:"max_samples_per_day"`
+	SecretKey        string        `json:"secret_key"`
+	DefaultProxy     string        `json:"default_proxy"`
+	// AdminToken guards the /api/admin routes; they are disabled when empty.
+	AdminToken string `json:"admin_token"`
+	// RetentionPolicies are keyed by monitor type. RetentionPeriod stays the
+	// TSDB wide ceiling and the default for anything without a policy.
+	RetentionPolicies map[string]RetentionPolicy `json:"retention_policies"`
+	RemoteWrite       []RemoteWriteConfig        `json:"remote_write"`
+	// WriteBatchSize and WriteBatchInterval control how check samples are
+	// grouped into TSDB commits. A check waits for the commit of its
+	// samples, so WriteBatchInterval also bounds how late its status is
+	// updated.
+	WriteBatchSize     int           `json:"write_batch_size"`
+	WriteBatchInterval time.Duration `json:"write_batch_interval"`
+	// RemoteWriteReceiver enables /api/v1/write for metrics pushed by other apps.
+	RemoteWriteReceiver RemoteWriteReceiverConfig `json:"remote_write_receiver"`
+}
+
+// OutOfOrderWindow is how far behind the newest sample the TSDB accepts
+// samples. It covers what statx itself writes late: the samples of a check
+// are stored after all its attempts and the next batch commit, and a rollup
+// is written at the start of its bucket once the bucket closed.
+func (c *Config) OutOfOrderWindow() time.Duration {
+	attempts := time.Duration(max(c.RetryAttempts, 1))
+	checks := (c.CheckTimeout+c.RetryDelay)*attempts + max(c.WriteBatchInterval, time.Second)
+	rollups := Resolutions[len(Resolutions)-1].Step + RollupGrace + RollupInterval
+	return max(checks, rollups)
+}
+
+type RetentionPolicy struct {
+	Raw    time.Duration `json:"raw"`
+	Rollup time.Duration `json:"rollup"`
+}
+
+// RemoteWriteConfig is one Prometheus remote_write endpoint that every
+// check sample is shipped to. Zero values fall back to the defaults of the
+// remote_write package.
+type RemoteWriteConfig struct {
+	Name              string            `json:"name"`
+	URL               string            `json:"url"`
+	Headers           map[string]string `json:"headers"`
+	BearerToken       string            `json:"bearer_token"`
+	BasicAuthUsername string            `json:"basic_auth_username"`
+	BasicAuthPassword string            `json:"basic_auth_password"`
+	BatchSize         int               `json:"batch_size"`
+	FlushInterval     time.Duration     `json:"flush_interval"`
+	Timeout           time.Duration     `json:"timeout"`
+	MinBackoff        time.Duration     `json:"min_backoff"`
+	MaxBackoff        time.Duration     `json:"max_backoff"`
+}
+
+type RemoteWriteReceiverConfig struct {
+	Enabled bool `json:"enabled"`
+	// Namespace is the default value of the namespace label put on every
+	// received series, so they never mix with statx's own series.
+	Namespace   string `json:"namespace"`
+	BearerToken string `json:"bearer_token"`
+}
+
+// MaxRetention is how long the TSDB itself keeps data: the longest of
+// RetentionPeriod, the type retention policies and the retention overrides
+// of the targets. Monitors created through the API cannot exceed it.
+func (c *Config) MaxRetention() time.Duration {
+	longest := c.RetentionPeriod
+	for _, policy := range c.RetentionPolicies {
+		longest = max(longest, policy.Raw, policy.Rollup)
+	}
+	for _, target := range c.Targets {
+		longest = max(longest, target.RawRetention, target.RollupRetention)
+	}
+	return longest
+}
+
+func (c *Config) TSDBPath() string {
+	return filepath.Join(c.StoragePath, "tsdb")
+}
+
+func (c *Config) SQLitePath() string {
+	return filepath.Join(c.StoragePath, "sqlite")
+}
+
+func (c *Config) SQLiteFile() string {
+	return filepath.Join(c.SQLitePath(), "statx.db")
 }
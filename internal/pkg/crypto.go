@@ -0,0 +1,74 @@
+package pkg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrMissingSecretKey = errors.New("secret_key is not configured")
+
+func newGCM(secret string) (cipher.AEAD, error) {
+	if secret == "" {
+		return nil, ErrMissingSecretKey
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("error creating cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptSecret seals plaintext with AES-GCM using a key derived from secret.
+// The result is base64 encoded and carries its own nonce.
+func EncryptSecret(secret string, plaintext []byte) (string, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("error generating nonce: %v", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func DecryptSecret(secret string, ciphertext string) ([]byte, error) {
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding secret: %v", err)
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("error decrypting secret: ciphertext too short")
+	}
+
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting secret: %v", err)
+	}
+	return plaintext, nil
+}
+
+// HashToken returns the hex encoded SHA-256 of an access token. Tokens are
+// random values checked for equality, so a plain hash is enough to keep them
+// out of the database.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
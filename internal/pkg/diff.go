@@ -0,0 +1,122 @@
+package pkg
+
+import "strings"
+
+// maxDiffCells bounds the LCS table; larger inputs fall back to a plain
+// removed/added listing of the differing middle section.
+const maxDiffCells = 4_000_000
+
+// LineDiff returns a line based diff of a and b. Unchanged lines are prefixed
+// with "  ", removed lines with "- " and added lines with "+ ". The output is
+// cut at maxBytes, in which case truncated is true.
+func LineDiff(a, b string, maxBytes int) (diff string, truncated bool) {
+	oldLines := splitLines(a)
+	newLines := splitLines(b)
+
+	// Trim the common prefix and suffix so only the changed middle is diffed.
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	oldMid := oldLines[prefix : len(oldLines)-suffix]
+	newMid := newLines[prefix : len(newLines)-suffix]
+
+	var sb strings.Builder
+	write := func(marker, line string) bool {
+		if maxBytes > 0 && sb.Len()+len(marker)+len(line)+1 > maxBytes {
+			truncated = true
+			return false
+		}
+		sb.WriteString(marker)
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+		return true
+	}
+
+	if prefix > 0 && !write("  ", oldLines[prefix-1]) {
+		return sb.String(), truncated
+	}
+
+	for _, op := range diffOps(oldMid, newMid) {
+		if !write(op.marker, op.line) {
+			return sb.String(), truncated
+		}
+	}
+
+	if suffix > 0 {
+		write("  ", oldLines[len(oldLines)-suffix])
+	}
+
+	return sb.String(), truncated
+}
+
+type diffOp struct {
+	marker string
+	line   string
+}
+
+func diffOps(a, b []string) []diffOp {
+	if len(a)*len(b) > maxDiffCells {
+		ops := make([]diffOp, 0, len(a)+len(b))
+		for _, line := range a {
+			ops = append(ops, diffOp{"- ", line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{"+ ", line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{"  ", a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{"- ", a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{"+ ", b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{"- ", a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{"+ ", b[j]})
+	}
+	return ops
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -1,6 +1,10 @@
 package pkg
 
-import "time"
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"time"
+)
 
 type TimeRange struct {
 	Start time.Time
@@ -8,15 +12,175 @@ type TimeRange struct {
 }
 
 type QueryResult struct {
-	URL          string    `json:"url"`
-	Timestamp    time.Time `json:"timestamp"`
-	Status       int       `json:"status"`
-	ResponseTime float64   `json:"response_time"`
+	MonitorID string    `json:"monitor_id"`
+	URL       string    `json:"url"`
+	Timestamp time.Time `json:"timestamp"`
+	Status    int       `json:"status"`
+	// ResponseTime is nil for failed checks, they carry no latency.
+	ResponseTime *float64 `json:"response_time"`
+	Up           bool     `json:"up"`
+	State        string   `json:"state,omitempty"`
+	ErrorClass   string   `json:"error_class,omitempty"`
+	ErrorMessage string   `json:"error_message,omitempty"`
+
+	// Set when the result is a rollup or step bucket rather than a raw
+	// sample; ResponseTime then holds the average latency.
+	Resolution  string   `json:"resolution,omitempty"`
+	UptimeRatio *float64 `json:"uptime_ratio,omitempty"`
+	LatencyMin  *float64 `json:"latency_min,omitempty"`
+	LatencyMax  *float64 `json:"latency_max,omitempty"`
+	LatencyP50  *float64 `json:"latency_p50,omitempty"`
+	LatencyP95  *float64 `json:"latency_p95,omitempty"`
+	LatencyP99  *float64 `json:"latency_p99,omitempty"`
+	SampleCount int      `json:"sample_count,omitempty"`
+	// StatusCodes counts the checks of a step bucket per HTTP status code,
+	// 0 being checks that got no response.
+	StatusCodes map[int]int `json:"status_codes,omitempty"`
 }
 
 type Target struct {
+	ID       string        `json:"id,omitempty"`
+	Name     string        `json:"name,omitempty"`
+	Type     string        `json:"type,omitempty"`
 	URL      string        `json:"url"`
 	Interval time.Duration `json:"interval"`
+	TLS      *TLSConfigDTO `json:"tls,omitempty"`
+	// Proxy overrides Config.DefaultProxy; "direct" bypasses any proxy.
+	Proxy string `json:"proxy,omitempty"`
+	// AcceptedStatusCodes lists codes and ranges counted as up, e.g. "200-299,301".
+	AcceptedStatusCodes string `json:"accepted_status_codes,omitempty"`
+	DisableRedirects    bool   `json:"disable_redirects,omitempty"`
+	MaxRedirects        int    `json:"max_redirects,omitempty"`
+	FinalURLPattern     string `json:"final_url_pattern,omitempty"`
+	// DegradedThreshold marks an otherwise up check as degraded when its
+	// latency, or the SLOPercentile latency over SLOWindow, exceeds it.
+	DegradedThreshold time.Duration `json:"degraded_threshold,omitempty"`
+	SLOPercentile     float64       `json:"slo_percentile,omitempty"`
+	SLOWindow         time.Duration `json:"slo_window,omitempty"`
+	// ContentCheck hashes the response body; regions matching ContentIgnore
+	// are blanked out first.
+	ContentCheck  bool     `json:"content_check,omitempty"`
+	ContentIgnore []string `json:"content_ignore,omitempty"`
+	// Labels are user defined and copied onto every series of the target.
+	Labels map[string]string `json:"labels,omitempty"`
+	Tags   []string          `json:"tags,omitempty"`
+	// RawRetention and RollupRetention override the policy of the target's type.
+	RawRetention    time.Duration `json:"raw_retention,omitempty"`
+	RollupRetention time.Duration `json:"rollup_retention,omitempty"`
+}
+
+// MonitorID identifies the target in series labels and SQLite tables. Targets
+// declared in config.json without an id get one derived from their URL.
+func (t Target) MonitorID() string {
+	if t.ID != "" {
+		return t.ID
+	}
+	sum := sha1.Sum([]byte(t.URL))
+	return "cfg-" + hex.EncodeToString(sum[:6])
+}
+
+// MonitorType defaults to uptime, the only kind of target config.json can declare.
+func (t Target) MonitorType() string {
+	if t.Type != "" {
+		return t.Type
+	}
+	return "uptime"
+}
+
+// Error classes written to the check_error series when a check is down.
+const (
+	ErrorClassDNS               = "dns"
+	ErrorClassConnectionRefused = "connection_refused"
+	ErrorClassTimeout           = "timeout"
+	ErrorClassTLS               = "tls"
+	ErrorClassAssertionFailed   = "assertion_failed"
+	ErrorClassBadStatus         = "bad_status"
+	ErrorClassUnknown           = "unknown"
+)
+
+type CheckResult struct {
+	// CheckedAt is when the check started; all samples of the check use it.
+	CheckedAt        time.Time
+	StatusCode       int
+	ResponseTime     float64
+	ProxyConnectTime float64
+	Proxied          bool
+	Up               bool
+	ErrorClass       string
+	ErrorMessage     string
+	FinalURL         string
+	State            State
+	// CertExpiry is the NotAfter of the leaf certificate of HTTPS targets.
+	CertExpiry time.Time
+	// Body is only read when the target has ContentCheck enabled.
+	Body []byte
+}
+
+// MonitorStatus is the latest known result of a monitor, kept in memory by
+// the recorder.
+type MonitorStatus struct {
+	MonitorID           string            `json:"monitor_id"`
+	Name                string            `json:"name"`
+	Type                string            `json:"type"`
+	URL                 string            `json:"url"`
+	Labels              map[string]string `json:"labels,omitempty"`
+	Tags                []string          `json:"tags,omitempty"`
+	LastCheck           time.Time         `json:"last_check"`
+	Up                  bool              `json:"up"`
+	State               string            `json:"state"`
+	StatusCode          int               `json:"status_code"`
+	ResponseTime        float64           `json:"response_time"`
+	ErrorClass          string            `json:"error_class,omitempty"`
+	ErrorMessage        string            `json:"error_message,omitempty"`
+	CertExpiry          *time.Time        `json:"cert_expiry,omitempty"`
+	ConsecutiveFailures int               `json:"consecutive_failures"`
+}
+
+// Event types published by the recorder.
+const (
+	EventCheck       = "check"
+	EventStateChange = "state_change"
+)
+
+// CheckEvent is published for every check result, and once more as a
+// state_change when the state of the monitor differs from its previous check.
+type CheckEvent struct {
+	Type   string        `json:"type"`
+	Status MonitorStatus `json:"status"`
+	// PreviousState is set on state changes, "unknown" for the first check
+	// of a monitor since start.
+	PreviousState string `json:"previous_state,omitempty"`
+}
+
+// MonitorOverview is the dashboard summary of one monitor. Sparkline holds
+// the average latency of equal buckets over the overview window, null for
+// buckets without successful checks.
+type MonitorOverview struct {
+	MonitorID   string     `json:"monitor_id"`
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	URL         string     `json:"url"`
+	Tags        []string   `json:"tags"`
+	GroupID     string     `json:"group_id,omitempty"`
+	State       string     `json:"state"`
+	LastCheck   *time.Time `json:"last_check"`
+	LastLatency *float64   `json:"last_latency"`
+	Uptime24h   *float64   `json:"uptime_24h"`
+	Sparkline   []*float64 `json:"sparkline"`
+}
+
+// ExportRow is one check of a monitor in the CSV and NDJSON exports.
+type ExportRow struct {
+	Timestamp    time.Time `json:"timestamp"`
+	MonitorID    string    `json:"monitor_id"`
+	Name         string    `json:"name"`
+	URL          string    `json:"url"`
+	Up           bool      `json:"up"`
+	State        string    `json:"state,omitempty"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	ResponseTime *float64  `json:"response_time,omitempty"`
+	ErrorClass   string    `json:"error_class,omitempty"`
+	ErrorMessage string    `json:"error_message,omitempty"`
 }
 
 type AccountDTO struct {
@@ -42,4 +206,163 @@ type ConfigMonitorDTO struct {
 	CallEncoding  string `json:"call_encoding"`
 	CallBody      string `json:"call_body"`
 	CallHeaders   string `json:"call_headers"`
+	TLSConfigID   string `json:"tls_config_id"`
+	ProxyURL      string `json:"proxy_url"`
+
+	AcceptedStatusCodes string `json:"accepted_status_codes"`
+	FollowRedirects     *bool  `json:"follow_redirects"`
+	MaxRedirects        int    `json:"max_redirects"`
+	FinalURLPattern     string `json:"final_url_pattern"`
+
+	DegradedThresholdMs int     `json:"degraded_threshold_ms"`
+	SLOPercentile       float64 `json:"slo_percentile"`
+	SLOWindow           int     `json:"slo_window"`
+
+	ContentCheck  bool   `json:"content_check"`
+	ContentIgnore string `json:"content_ignore"`
+
+	Labels map[string]string `json:"labels"`
+	// Tags are names of shared tags; tags of the form key:value also become
+	// series labels.
+	Tags    []string `json:"tags"`
+	GroupID string   `json:"group_id"`
+
+	// BadgeEnabled makes the SVG badges of the monitor public, or only to
+	// requests passing the badge token when one is set. BadgeToken is write
+	// only: leaving it out keeps the stored token and "" removes it. Only its
+	// hash is stored.
+	BadgeEnabled   bool    `json:"badge_enabled"`
+	BadgeToken     *string `json:"badge_token,omitempty"`
+	BadgeTokenHash string  `json:"-"`
+	HasBadgeToken  bool    `json:"has_badge_token"`
+
+	RawRetention    int `json:"raw_retention"`
+	RollupRetention int `json:"rollup_retention"`
+}
+
+// ToTarget converts a stored monitor into a recorder target. Interval is stored in seconds.
+func (m *ConfigMonitorDTO) ToTarget() Target {
+	return Target{
+		ID:       m.ID,
+		Name:     m.Name,
+		Type:     m.Type,
+		URL:      m.URL,
+		Interval: time.Duration(m.Interval) * time.Second,
+		Proxy:    m.ProxyURL,
+
+		AcceptedStatusCodes: m.AcceptedStatusCodes,
+		DisableRedirects:    m.FollowRedirects != nil && !*m.FollowRedirects,
+		MaxRedirects:        m.MaxRedirects,
+		FinalURLPattern:     m.FinalURLPattern,
+
+		DegradedThreshold: time.Duration(m.DegradedThresholdMs) * time.Millisecond,
+		SLOPercentile:     m.SLOPercentile,
+		SLOWindow:         time.Duration(m.SLOWindow) * time.Second,
+
+		ContentCheck:  m.ContentCheck,
+		ContentIgnore: splitNonEmptyLines(m.ContentIgnore),
+
+		Labels: m.Labels,
+		Tags:   m.Tags,
+
+		RawRetention:    time.Duration(m.RawRetention) * time.Second,
+		RollupRetention: time.Duration(m.RollupRetention) * time.Second,
+	}
+}
+
+type TLSConfigDTO struct {
+	ID                 string    `json:"id"`
+	Name               string    `json:"name"`
+	CACert             string    `json:"ca_cert"`
+	ClientCert         string    `json:"client_cert"`
+	ClientKey          string    `json:"client_key,omitempty"`
+	HasClientKey       bool      `json:"has_client_key"`
+	ServerName         string    `json:"server_name"`
+	MinVersion         string    `json:"min_version"`
+	InsecureSkipVerify bool      `json:"insecure_skip_verify"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+type ContentChangeDTO struct {
+	ID        string    `json:"id"`
+	MonitorID string    `json:"monitor_id"`
+	OldHash   string    `json:"old_hash"`
+	NewHash   string    `json:"new_hash"`
+	Diff      string    `json:"diff"`
+	Truncated bool      `json:"truncated"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BackupManifest is stored as the first entry of a backup archive and lists
+// every other entry with its checksum.
+type BackupManifest struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	Files     []BackupFile `json:"files"`
+}
+
+type BackupFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type TagDTO struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Color        string    `json:"color"`
+	MonitorCount int       `json:"monitor_count"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type GroupDTO struct {
+	ID          string    `json:"id"`
+	ParentID    string    `json:"parent_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// GroupStatus rolls up the current state of the monitors in a group and all
+// of its subgroups.
+type GroupStatus struct {
+	GroupID  string `json:"group_id"`
+	Name     string `json:"name"`
+	Total    int    `json:"total"`
+	Up       int    `json:"up"`
+	Degraded int    `json:"degraded"`
+	Down     int    `json:"down"`
+	// Unknown counts monitors without a check since statx started.
+	Unknown int `json:"unknown"`
+	// State is the worst state of any monitor in the group.
+	State     string        `json:"state"`
+	Summary   string        `json:"summary"`
+	Subgroups []GroupStatus `json:"subgroups,omitempty"`
+}
+
+type MaintenanceWindowDTO struct {
+	ID        string    `json:"id"`
+	MonitorID string    `json:"monitor_id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UptimeSummary is the availability of a monitor over one window. Time
+// without any check data, e.g. while statx was stopped, counts neither as
+// uptime nor downtime; Coverage tells how much of the window had data.
+type UptimeSummary struct {
+	Window                string    `json:"window"`
+	Start                 time.Time `json:"start"`
+	End                   time.Time `json:"end"`
+	Availability          *float64  `json:"availability"`
+	UptimeSeconds         float64   `json:"uptime_seconds"`
+	DowntimeSeconds       float64   `json:"downtime_seconds"`
+	ExcludedSeconds       float64   `json:"excluded_seconds"`
+	Coverage              float64   `json:"coverage"`
+	Outages               int       `json:"outages"`
+	LongestOutageSeconds  float64   `json:"longest_outage_seconds"`
+	OngoingOutage         bool      `json:"ongoing_outage"`
+	RollupDowntimeSeconds float64   `json:"rollup_downtime_seconds,omitempty"`
 }
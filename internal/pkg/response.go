@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -50,3 +51,39 @@ func JsonResponseUsingBase(w http.ResponseWriter, msg string, payload any, err e
 
 	_, _ = fmt.Fprintf(w, "%s", dj)
 }
+
+// ErrorStatus maps a service error, matched with errors.Is, to the status
+// code and data of its response.
+type ErrorStatus struct {
+	Err  error
+	Code int
+	Data any
+}
+
+// JsonErrorResponse answers err with the first matching status. Validation
+// errors get 422 with their field errors as data, anything unmatched 500.
+func JsonErrorResponse(w http.ResponseWriter, err error, statuses ...ErrorStatus) {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		JsonResponse(w, BaseResponse{
+			Success: false,
+			Message: "validation failed",
+			Data:    validationErr.Errors,
+		}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	code := http.StatusInternalServerError
+	var data any
+	for _, status := range statuses {
+		if errors.Is(err, status.Err) {
+			code, data = status.Code, status.Data
+			break
+		}
+	}
+	JsonResponse(w, BaseResponse{
+		Success: false,
+		Message: err.Error(),
+		Data:    data,
+	}, code)
+}
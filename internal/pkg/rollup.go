@@ -0,0 +1,60 @@
+package pkg
+
+import "time"
+
+// Resolution is a downsampling level written by the rollup job.
+type Resolution struct {
+	Name string
+	Step time.Duration
+}
+
+const ResolutionRaw = "raw"
+
+// Resolutions are ordered from finest to coarsest.
+var Resolutions = []Resolution{
+	{Name: "5m", Step: 5 * time.Minute},
+	{Name: "1h", Step: time.Hour},
+	{Name: "1d", Step: 24 * time.Hour},
+}
+
+const (
+	// RollupInterval is how often the rollup job runs.
+	RollupInterval = 5 * time.Minute
+	// RollupGrace leaves time for late checks before a bucket is closed.
+	RollupGrace = time.Minute
+)
+
+// Rollup series names, each labeled with resolution and the monitor labels.
+const (
+	RollupUptimeRatio = "rollup_uptime_ratio"
+	RollupLatencyMin  = "rollup_latency_min"
+	RollupLatencyAvg  = "rollup_latency_avg"
+	RollupLatencyMax  = "rollup_latency_max"
+	RollupLatencyP95  = "rollup_latency_p95"
+	RollupSampleCount = "rollup_sample_count"
+)
+
+// ResolutionFor picks the coarsest data needed for a time range: raw samples
+// up to a day, then 5 minute, hourly and daily rollups.
+func ResolutionFor(timeRange TimeRange) string {
+	span := timeRange.End.Sub(timeRange.Start)
+	switch {
+	case span <= 24*time.Hour:
+		return ResolutionRaw
+	case span <= 7*24*time.Hour:
+		return "5m"
+	case span <= 31*24*time.Hour:
+		return "1h"
+	default:
+		return "1d"
+	}
+}
+
+func LookupResolution(name string) (Resolution, bool) {
+	for _, res := range Resolutions {
+		if res.Name == name {
+			return res, true
+		}
+	}
+	return Resolution{}, false
+}
@@ -0,0 +1,12 @@
+package pkg
+
+import "github.com/prometheus/prometheus/model/labels"
+
+// NamespaceLabel is set on every series received through remote_write and
+// never on the series statx writes itself.
+const NamespaceLabel = "namespace"
+
+// OwnSeries matches the series written by statx's checks. Received series
+// can carry the monitor_id of a generic monitor, so every internal read of
+// check data adds it to keep pushed samples out of uptime and latency.
+var OwnSeries = labels.MustNewMatcher(labels.MatchEqual, NamespaceLabel, "")
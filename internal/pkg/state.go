@@ -0,0 +1,33 @@
+package pkg
+
+// State is the value written to the monitor_state series.
+type State int
+
+const (
+	StateDown State = iota
+	StateUp
+	StateDegraded
+)
+
+func (s State) String() string {
+	switch s {
+	case StateUp:
+		return "up"
+	case StateDegraded:
+		return "degraded"
+	default:
+		return "down"
+	}
+}
+
+// ParseState is the inverse of String; unknown values are down.
+func ParseState(s string) State {
+	switch s {
+	case "up":
+		return StateUp
+	case "degraded":
+		return StateDegraded
+	default:
+		return StateDown
+	}
+}
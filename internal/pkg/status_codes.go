@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultAcceptedStatusCodes is used when a target does not define its own.
+const DefaultAcceptedStatusCodes = "200-399"
+
+type StatusCodeRange struct {
+	Min int
+	Max int
+}
+
+type StatusCodeRanges []StatusCodeRange
+
+// ParseStatusCodeRanges parses a comma separated list of codes and ranges such
+// as "200-299,301,302". An empty spec falls back to DefaultAcceptedStatusCodes.
+func ParseStatusCodeRanges(spec string) (StatusCodeRanges, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = DefaultAcceptedStatusCodes
+	}
+
+	var ranges StatusCodeRanges
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		lo, hi, isRange := strings.Cut(part, "-")
+		min, err := strconv.Atoi(strings.TrimSpace(lo))
+		if err != nil {
+			return nil, fmt.Errorf("invalid status code %q", part)
+		}
+		max := min
+		if isRange {
+			max, err = strconv.Atoi(strings.TrimSpace(hi))
+			if err != nil {
+				return nil, fmt.Errorf("invalid status code range %q", part)
+			}
+		}
+
+		if min < 100 || max > 599 || min > max {
+			return nil, fmt.Errorf("status code range %q out of bounds", part)
+		}
+		ranges = append(ranges, StatusCodeRange{Min: min, Max: max})
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no status codes in %q", spec)
+	}
+	return ranges, nil
+}
+
+func (r StatusCodeRanges) Contains(code int) bool {
+	for _, rng := range r {
+		if code >= rng.Min && code <= rng.Max {
+			return true
+		}
+	}
+	return false
+}
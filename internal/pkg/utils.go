@@ -1,6 +1,14 @@
 package pkg
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
 
 func ParseTimeRange(start string, end string, duration string) (TimeRange, error) {
 	now := time.Now()
@@ -34,3 +42,94 @@ func ParseTimeRange(start string, end string, duration string) (TimeRange, error
 
 	return defaultRange, nil
 }
+
+// ParseWindow parses a duration like time.ParseDuration and additionally
+// accepts whole days and weeks such as 7d or 2w.
+func ParseWindow(s string) (time.Duration, error) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	if unit > 0 {
+		n, err := strconv.Atoi(strings.TrimSpace(s[:len(s)-1]))
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid window %q", s)
+		}
+		return time.Duration(n) * unit, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q", s)
+	}
+	return d, nil
+}
+
+type Window struct {
+	Name     string
+	Duration time.Duration
+}
+
+// ParseWindows parses a comma separated list like "24h,7d,30d".
+func ParseWindows(s string) ([]Window, error) {
+	var windows []Window
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		d, err := ParseWindow(name)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, Window{Name: name, Duration: d})
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no window given")
+	}
+	return windows, nil
+}
+
+// Percentile returns the p-th percentile (0-100) of values using the
+// nearest-rank method. values is sorted in place.
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+
+	rank := int(math.Ceil(p/100*float64(len(values)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(values) {
+		rank = len(values) - 1
+	}
+	return values[rank]
+}
+
+func splitNonEmptyLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// TruncateString cuts s to at most maxBytes without splitting a UTF-8
+// sequence.
+func TruncateString(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
@@ -0,0 +1,325 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/util/annotations"
+)
+
+// These handlers answer in the Prometheus HTTP API format rather than
+// pkg.BaseResponse, so that Grafana and other Prometheus clients can use
+// statx as a data source.
+
+type errorType string
+
+const (
+	errorBadData     errorType = "bad_data"
+	errorExecution   errorType = "execution"
+	errorTimeout     errorType = "timeout"
+	errorCanceled    errorType = "canceled"
+	errorInternal    errorType = "internal"
+	errorUnavailable errorType = "unavailable"
+)
+
+type response struct {
+	Status    string      `json:"status"`
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType errorType   `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Warnings  []string    `json:"warnings,omitempty"`
+	Infos     []string    `json:"infos,omitempty"`
+}
+
+type queryData struct {
+	ResultType parser.ValueType `json:"resultType"`
+	Result     parser.Value     `json:"result"`
+}
+
+var (
+	minTime = time.Unix(math.MinInt64/1000+62135596801, 0).UTC()
+	maxTime = time.Unix(math.MaxInt64/1000-62135596801, 999999999).UTC()
+)
+
+func QueryHandler(querySvc QueryService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ts, err := parseTimeParam(r, "time", time.Now())
+		if err != nil {
+			respondError(w, errorBadData, err)
+			return
+		}
+
+		ctx, cancel, err := withTimeoutParam(r)
+		if err != nil {
+			respondError(w, errorBadData, err)
+			return
+		}
+		defer cancel()
+
+		result, release, err := querySvc.Query(ctx, r.FormValue("query"), ts)
+		if err != nil {
+			respondError(w, errorBadData, err)
+			return
+		}
+		defer release()
+
+		respondResult(w, result)
+	}
+}
+
+func QueryRangeHandler(querySvc QueryService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start, err := parseTime(r.FormValue("start"))
+		if err != nil {
+			respondError(w, errorBadData, fmt.Errorf("invalid parameter \"start\": %v", err))
+			return
+		}
+		end, err := parseTime(r.FormValue("end"))
+		if err != nil {
+			respondError(w, errorBadData, fmt.Errorf("invalid parameter \"end\": %v", err))
+			return
+		}
+		step, err := parseDuration(r.FormValue("step"))
+		if err != nil {
+			respondError(w, errorBadData, fmt.Errorf("invalid parameter \"step\": %v", err))
+			return
+		}
+
+		ctx, cancel, err := withTimeoutParam(r)
+		if err != nil {
+			respondError(w, errorBadData, err)
+			return
+		}
+		defer cancel()
+
+		result, release, err := querySvc.QueryRange(ctx, r.FormValue("query"), start, end, step)
+		if err != nil {
+			respondError(w, errorBadData, err)
+			return
+		}
+		defer release()
+
+		respondResult(w, result)
+	}
+}
+
+func SeriesHandler(querySvc QueryService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start, end, matcherSets, err := parseMetadataParams(r)
+		if err != nil {
+			respondError(w, errorBadData, err)
+			return
+		}
+
+		series, warnings, err := querySvc.Series(r.Context(), matcherSets, start, end)
+		if err != nil {
+			respondError(w, errorExecution, err)
+			return
+		}
+		if series == nil {
+			series = []labels.Labels{}
+		}
+
+		respond(w, series, warnings)
+	}
+}
+
+func LabelNamesHandler(querySvc QueryService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start, end, matcherSets, err := parseMetadataParams(r)
+		if err != nil {
+			respondError(w, errorBadData, err)
+			return
+		}
+
+		names, warnings, err := querySvc.LabelNames(r.Context(), matcherSets, start, end)
+		if err != nil {
+			respondError(w, errorExecution, err)
+			return
+		}
+		if names == nil {
+			names = []string{}
+		}
+
+		respond(w, names, warnings)
+	}
+}
+
+func LabelValuesHandler(querySvc QueryService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		if !model.LabelName(name).IsValid() {
+			respondError(w, errorBadData, fmt.Errorf("invalid label name: %q", name))
+			return
+		}
+
+		start, end, matcherSets, err := parseMetadataParams(r)
+		if err != nil {
+			respondError(w, errorBadData, err)
+			return
+		}
+
+		values, warnings, err := querySvc.LabelValues(r.Context(), name, matcherSets, start, end)
+		if err != nil {
+			respondError(w, errorExecution, err)
+			return
+		}
+		if values == nil {
+			values = []string{}
+		}
+
+		respond(w, values, warnings)
+	}
+}
+
+func parseMetadataParams(r *http.Request) (time.Time, time.Time, [][]*labels.Matcher, error) {
+	if err := r.ParseForm(); err != nil {
+		return time.Time{}, time.Time{}, nil, fmt.Errorf("error parsing form values: %v", err)
+	}
+
+	start, err := parseTimeParam(r, "start", minTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, err
+	}
+	end, err := parseTimeParam(r, "end", maxTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, err
+	}
+
+	matcherSets, err := parser.ParseMetricSelectors(r.Form["match[]"])
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, err
+	}
+
+	return start, end, matcherSets, nil
+}
+
+func withTimeoutParam(r *http.Request) (context.Context, context.CancelFunc, error) {
+	value := r.FormValue("timeout")
+	if value == "" {
+		ctx, cancel := context.WithCancel(r.Context())
+		return ctx, cancel, nil
+	}
+
+	timeout, err := parseDuration(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid parameter \"timeout\": %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	return ctx, cancel, nil
+}
+
+func parseTimeParam(r *http.Request, name string, defaultValue time.Time) (time.Time, error) {
+	value := r.FormValue(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	t, err := parseTime(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid parameter %q: %v", name, err)
+	}
+	return t, nil
+}
+
+// parseTime accepts unix timestamps with optional fractional seconds and
+// RFC3339 times, like Prometheus.
+func parseTime(s string) (time.Time, error) {
+	if t, err := strconv.ParseFloat(s, 64); err == nil {
+		seconds, fraction := math.Modf(t)
+		return time.Unix(int64(seconds), int64(math.Round(fraction*1000))*int64(time.Millisecond)).UTC(), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+}
+
+// parseDuration accepts seconds as a float or a Prometheus duration like 5m.
+func parseDuration(s string) (time.Duration, error) {
+	if d, err := strconv.ParseFloat(s, 64); err == nil {
+		ts := d * float64(time.Second)
+		if ts > float64(math.MaxInt64) || ts < float64(math.MinInt64) {
+			return 0, fmt.Errorf("cannot parse %q to a valid duration. It overflows int64", s)
+		}
+		return time.Duration(ts), nil
+	}
+	if d, err := model.ParseDuration(s); err == nil {
+		return time.Duration(d), nil
+	}
+	return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+}
+
+func respondResult(w http.ResponseWriter, result *promql.Result) {
+	if result.Err != nil {
+		typ := errorExecution
+		switch result.Err.(type) {
+		case promql.ErrQueryTimeout:
+			typ = errorTimeout
+		case promql.ErrQueryCanceled:
+			typ = errorCanceled
+		case promql.ErrStorage:
+			typ = errorInternal
+		}
+		respondError(w, typ, result.Err)
+		return
+	}
+
+	respond(w, queryData{
+		ResultType: result.Value.Type(),
+		Result:     result.Value,
+	}, result.Warnings)
+}
+
+func respond(w http.ResponseWriter, data interface{}, warnings annotations.Annotations) {
+	warningStrings, infoStrings := warnings.AsStrings("", 0, 0)
+	writeJSON(w, response{
+		Status:   "success",
+		Data:     data,
+		Warnings: warningStrings,
+		Infos:    infoStrings,
+	}, http.StatusOK)
+}
+
+func respondError(w http.ResponseWriter, typ errorType, err error) {
+	code := http.StatusInternalServerError
+	switch typ {
+	case errorBadData:
+		code = http.StatusBadRequest
+	case errorExecution:
+		code = http.StatusUnprocessableEntity
+	case errorCanceled:
+		code = 499
+	case errorTimeout, errorUnavailable:
+		code = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, response{
+		Status:    "error",
+		ErrorType: typ,
+		Error:     err.Error(),
+	}, code)
+}
+
+func writeJSON(w http.ResponseWriter, resp response, code int) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(data)
+}
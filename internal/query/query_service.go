@@ -0,0 +1,171 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/util/annotations"
+)
+
+const (
+	maxSamples = 50_000_000
+	timeout    = 2 * time.Minute
+	// maxPoints is the per series limit Prometheus applies to range queries.
+	maxPoints = 11_000
+)
+
+var ErrTooManyPoints = fmt.Errorf("exceeded maximum resolution of %d points per timeseries, try a larger step", maxPoints)
+
+type queryService struct {
+	queryable storage.Queryable
+	engine    *promql.Engine
+}
+
+// QueryService runs PromQL and metadata queries against the embedded TSDB.
+// Query results hold pooled memory: call the returned release func once the
+// result has been encoded.
+type QueryService interface {
+	Query(ctx context.Context, qs string, ts time.Time) (*promql.Result, func(), error)
+	QueryRange(ctx context.Context, qs string, start, end time.Time, step time.Duration) (*promql.Result, func(), error)
+	Series(ctx context.Context, matcherSets [][]*labels.Matcher, start, end time.Time) ([]labels.Labels, annotations.Annotations, error)
+	LabelNames(ctx context.Context, matcherSets [][]*labels.Matcher, start, end time.Time) ([]string, annotations.Annotations, error)
+	LabelValues(ctx context.Context, name string, matcherSets [][]*labels.Matcher, start, end time.Time) ([]string, annotations.Annotations, error)
+}
+
+func NewQueryService(
+	queryable storage.Queryable,
+) QueryService {
+	return &queryService{
+		queryable: queryable,
+		engine: promql.NewEngine(promql.EngineOpts{
+			MaxSamples:           maxSamples,
+			Timeout:              timeout,
+			EnableAtModifier:     true,
+			EnableNegativeOffset: true,
+		}),
+	}
+}
+
+func (s *queryService) Query(ctx context.Context, qs string, ts time.Time) (*promql.Result, func(), error) {
+	q, err := s.engine.NewInstantQuery(ctx, s.queryable, nil, qs, ts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return q.Exec(ctx), q.Close, nil
+}
+
+func (s *queryService) QueryRange(ctx context.Context, qs string, start, end time.Time, step time.Duration) (*promql.Result, func(), error) {
+	if step <= 0 {
+		return nil, nil, errors.New("zero or negative query resolution step widths are not accepted")
+	}
+	if end.Before(start) {
+		return nil, nil, errors.New("end timestamp must not be before start time")
+	}
+	if end.Sub(start)/step > maxPoints {
+		return nil, nil, ErrTooManyPoints
+	}
+
+	q, err := s.engine.NewRangeQuery(ctx, s.queryable, nil, qs, start, end, step)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return q.Exec(ctx), q.Close, nil
+}
+
+func (s *queryService) Series(ctx context.Context, matcherSets [][]*labels.Matcher, start, end time.Time) ([]labels.Labels, annotations.Annotations, error) {
+	if len(matcherSets) == 0 {
+		return nil, nil, errors.New("no match[] parameter provided")
+	}
+
+	q, err := s.queryable.Querier(start.UnixMilli(), end.UnixMilli())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer q.Close()
+
+	hints := &storage.SelectHints{
+		Start: start.UnixMilli(),
+		End:   end.UnixMilli(),
+		Func:  "series",
+	}
+
+	var sets []storage.SeriesSet
+	for _, matchers := range matcherSets {
+		sets = append(sets, q.Select(ctx, len(matcherSets) > 1, hints, matchers...))
+	}
+	set := storage.NewMergeSeriesSet(sets, storage.ChainedSeriesMerge)
+
+	var series []labels.Labels
+	for set.Next() {
+		series = append(series, set.At().Labels())
+	}
+	if err := set.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return series, set.Warnings(), nil
+}
+
+func (s *queryService) LabelNames(ctx context.Context, matcherSets [][]*labels.Matcher, start, end time.Time) ([]string, annotations.Annotations, error) {
+	q, err := s.queryable.Querier(start.UnixMilli(), end.UnixMilli())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer q.Close()
+
+	if len(matcherSets) == 0 {
+		return q.LabelNames(ctx, nil)
+	}
+
+	return mergeValues(matcherSets, func(matchers []*labels.Matcher) ([]string, annotations.Annotations, error) {
+		return q.LabelNames(ctx, nil, matchers...)
+	})
+}
+
+func (s *queryService) LabelValues(ctx context.Context, name string, matcherSets [][]*labels.Matcher, start, end time.Time) ([]string, annotations.Annotations, error) {
+	q, err := s.queryable.Querier(start.UnixMilli(), end.UnixMilli())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer q.Close()
+
+	if len(matcherSets) == 0 {
+		return q.LabelValues(ctx, name, nil)
+	}
+
+	return mergeValues(matcherSets, func(matchers []*labels.Matcher) ([]string, annotations.Annotations, error) {
+		return q.LabelValues(ctx, name, nil, matchers...)
+	})
+}
+
+// mergeValues unions the sorted results of several match[] selectors.
+func mergeValues(matcherSets [][]*labels.Matcher, fetch func([]*labels.Matcher) ([]string, annotations.Annotations, error)) ([]string, annotations.Annotations, error) {
+	seen := make(map[string]struct{})
+	var warnings annotations.Annotations
+	for _, matchers := range matcherSets {
+		values, ws, err := fetch(matchers)
+		if err != nil {
+			return nil, nil, err
+		}
+		warnings.Merge(ws)
+		for _, value := range values {
+			seen[value] = struct{}{}
+		}
+	}
+
+	values := make([]string, 0, len(seen))
+	for value := range seen {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	return values, warnings, nil
+}
@@ -0,0 +1,62 @@
+package recorder
+
+import (
+	"sync"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// further events are dropped for it.
+const subscriberBuffer = 256
+
+type broadcaster struct {
+	mu          sync.RWMutex
+	subscribers map[chan pkg.CheckEvent]struct{}
+}
+
+// Broadcaster fans check events out to in-process subscribers such as the
+// SSE stream. Publishing never blocks the recorder: a subscriber whose buffer
+// is full misses events until it catches up.
+type Broadcaster interface {
+	Publish(event pkg.CheckEvent)
+	// Subscribe returns a channel of events and a function that ends the
+	// subscription and closes the channel.
+	Subscribe() (<-chan pkg.CheckEvent, func())
+}
+
+func NewBroadcaster() Broadcaster {
+	return &broadcaster{
+		subscribers: make(map[chan pkg.CheckEvent]struct{}),
+	}
+}
+
+func (b *broadcaster) Publish(event pkg.CheckEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (b *broadcaster) Subscribe() (<-chan pkg.CheckEvent, func()) {
+	ch := make(chan pkg.CheckEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package recorder
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+// maxErrorMessageLength keeps the stored check error messages short; they
+// are meant for humans, the class is what queries should use.
+const maxErrorMessageLength = 200
+
+// classifyError maps a transport error onto one of the pkg.ErrorClass values.
+func classifyError(err error) string {
+	if err == nil {
+		return pkg.ErrorClassUnknown
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certInvalidErr x509.CertificateInvalidError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certVerificationErr *tls.CertificateVerificationError
+	var recordHeaderErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+
+	switch {
+	case errors.As(err, &dnsErr):
+		if dnsErr.IsTimeout {
+			return pkg.ErrorClassTimeout
+		}
+		return pkg.ErrorClassDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return pkg.ErrorClassConnectionRefused
+	case errors.As(err, &certInvalidErr),
+		errors.As(err, &unknownAuthorityErr),
+		errors.As(err, &hostnameErr),
+		errors.As(err, &certVerificationErr),
+		errors.As(err, &recordHeaderErr),
+		errors.As(err, &alertErr),
+		strings.Contains(err.Error(), "tls: "):
+		return pkg.ErrorClassTLS
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return pkg.ErrorClassTimeout
+	default:
+		return pkg.ErrorClassUnknown
+	}
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	msg := strings.Join(strings.Fields(err.Error()), " ")
+	return pkg.TruncateString(msg, maxErrorMessageLength)
+}
+
+func failedResult(class string, err error) pkg.CheckResult {
+	return pkg.CheckResult{
+		ErrorClass:   class,
+		ErrorMessage: errorMessage(err),
+	}
+}
@@ -0,0 +1,35 @@
+package recorder
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/afrianjunior/statx/internal/content_change"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+)
+
+func TestCheckWithoutAttemptsFailsCleanly(t *testing.T) {
+	config := testutil.Config(t)
+	config.RetryAttempts = 0
+	db := testutil.DB(t)
+	recorder := NewRecorderService(testutil.TSDB(t, config), db, config, &http.Client{}, testutil.Logger(),
+		content_change.NewContentChangeService(content_change.NewContentChangeRepository(db)),
+		NewStatusTracker(), NewBroadcaster()).(*recorderService)
+
+	result, _ := recorder.checkUptime(pkg.Target{ID: "a", URL: "http://127.0.0.1:1"})
+	if result.Up || result.ErrorClass != pkg.ErrorClassUnknown {
+		t.Fatalf("result = %+v, want an unknown failure", result)
+	}
+}
+
+func TestErrorMessageIsCutOnARuneBoundary(t *testing.T) {
+	// the odd prefix puts a two byte character across the limit
+	msg := errorMessage(errors.New("x" + strings.Repeat("é", maxErrorMessageLength)))
+	if len(msg) != maxErrorMessageLength-1 || !utf8.ValidString(msg) {
+		t.Fatalf("message of %d bytes, valid UTF-8: %v", len(msg), utf8.ValidString(msg))
+	}
+}
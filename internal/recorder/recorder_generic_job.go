@@ -42,15 +42,15 @@ func (s *genericJob) Start(ctx context.Context) {
 
 func (s *genericJob) checkStatus(ctx context.Context, target pkg.Target) {
 	for {
-		statusCode, responseTime, err := s.recorderService.CheckUptimeWithRetry(target)
+		result, err := s.recorderService.CheckUptimeWithRetry(target)
 		if err != nil {
-			s.logger.Errorf("Error checking %s: %v", target.URL, err)
-			if err := s.recorderService.WriteUpTimeRecord(ctx, target.URL, 0, 0); err != nil {
+			s.logger.Errorf("Error checking %s (%s): %v", target.URL, result.ErrorClass, err)
+			if err := s.recorderService.WriteUpTimeRecord(ctx, target, result); err != nil {
 				s.logger.Errorf("Error writing to TSDB: %v", err)
 			}
 		} else {
-			s.logger.Infof("Status for %s: %d", target.URL, statusCode)
-			if err := s.recorderService.WriteUpTimeRecord(ctx, target.URL, statusCode, responseTime); err != nil {
+			s.logger.Infof("Status for %s: %d (up=%t %s)", target.URL, result.StatusCode, result.Up, result.ErrorClass)
+			if err := s.recorderService.WriteUpTimeRecord(ctx, target, result); err != nil {
 				s.logger.Errorf("Error writing to TSDB: %v", err)
 			}
 		}
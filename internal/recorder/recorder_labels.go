@@ -0,0 +1,91 @@
+package recorder
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// reservedLabels cannot be overridden by user labels.
+var reservedLabels = map[string]bool{
+	labels.MetricName:  true,
+	"monitor_id":       true,
+	"type":             true,
+	"name":             true,
+	"url":              true,
+	"class":            true,
+	pkg.NamespaceLabel: true,
+}
+
+// sanitizeLabelName maps a user supplied name onto the Prometheus label name
+// charset, returning "" when nothing usable is left.
+func sanitizeLabelName(name string) string {
+	var sb strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				sb.WriteRune('_')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	out := sb.String()
+	if strings.Trim(out, "_") == "" || strings.HasPrefix(out, "__") {
+		return ""
+	}
+	return out
+}
+
+// seriesLabels builds the label set of metric for target: monitor_id, type,
+// name, url, the target's user labels and key:value tags, plus any extra pairs.
+func seriesLabels(metric string, target pkg.Target, extra ...string) labels.Labels {
+	b := labels.NewScratchBuilder(8 + len(target.Labels) + len(target.Tags))
+
+	added := make(map[string]bool, len(target.Labels))
+	for name, value := range target.Labels {
+		name = sanitizeLabelName(name)
+		if name == "" || reservedLabels[name] || value == "" || added[name] {
+			continue
+		}
+		b.Add(name, value)
+		added[name] = true
+	}
+
+	// key:value tags become labels unless a user label already sets the name.
+	// Plain tags are left out: they change with every tag edit or rename and
+	// would start new series each time; the overview filters on them instead.
+	tags := append([]string{}, target.Tags...)
+	sort.Strings(tags)
+	for _, tag := range tags {
+		name, value, ok := strings.Cut(tag, ":")
+		name = sanitizeLabelName(name)
+		if !ok || name == "" || reservedLabels[name] || value == "" || added[name] {
+			continue
+		}
+		b.Add(name, value)
+		added[name] = true
+	}
+
+	b.Add(labels.MetricName, metric)
+	b.Add("monitor_id", target.MonitorID())
+	b.Add("type", target.MonitorType())
+	b.Add("url", target.URL)
+	if target.Name != "" {
+		b.Add("name", target.Name)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if extra[i+1] != "" {
+			b.Add(extra[i], extra[i+1])
+		}
+	}
+
+	b.Sort()
+	return b.Labels()
+}
@@ -0,0 +1,32 @@
+package recorder
+
+import (
+	"testing"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+func TestPlainTagsStayOutOfSeriesLabels(t *testing.T) {
+	target := pkg.Target{ID: "a", URL: "http://a", Tags: []string{"core", "env:prod"}}
+	before := seriesLabels("up", target)
+
+	// adding or renaming a plain tag must not start a new series
+	target.Tags = []string{"edge", "env:prod", "team"}
+	if after := seriesLabels("up", target); !labels.Equal(before, after) {
+		t.Fatalf("plain tag edit changed the series: %s -> %s", before, after)
+	}
+	if before.Get("env") != "prod" {
+		t.Errorf("key:value tag is not a label: %s", before)
+	}
+	if before.Has("tags") {
+		t.Errorf("tags are listed in a label: %s", before)
+	}
+}
+
+func TestMonitorLabelsCannotSetNamespace(t *testing.T) {
+	target := pkg.Target{ID: "a", URL: "http://a", Labels: map[string]string{"namespace": "x"}, Tags: []string{"namespace:y"}}
+	if lset := seriesLabels("up", target); !pkg.OwnSeries.Matches(lset.Get(pkg.NamespaceLabel)) {
+		t.Fatalf("check series carry a namespace and look received: %s", lset)
+	}
+}
@@ -0,0 +1,72 @@
+package recorder
+
+import (
+	"sync"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+type latencySample struct {
+	at      time.Time
+	latency float64
+}
+
+// latencyWindows keeps the recent latencies of every monitor so the SLO
+// percentile can be evaluated without going back to the TSDB.
+type latencyWindows struct {
+	mu      sync.Mutex
+	samples map[string][]latencySample
+}
+
+func newLatencyWindows() *latencyWindows {
+	return &latencyWindows{
+		samples: make(map[string][]latencySample),
+	}
+}
+
+func (w *latencyWindows) evaluate(target pkg.Target, result pkg.CheckResult, now time.Time) pkg.State {
+	if !result.Up {
+		return pkg.StateDown
+	}
+	if target.DegradedThreshold <= 0 {
+		return pkg.StateUp
+	}
+
+	threshold := float64(target.DegradedThreshold.Milliseconds())
+	degraded := result.ResponseTime > threshold
+
+	if target.SLOPercentile > 0 && target.SLOWindow > 0 {
+		if w.percentile(target, result.ResponseTime, now) > threshold {
+			degraded = true
+		}
+	}
+
+	if degraded {
+		return pkg.StateDegraded
+	}
+	return pkg.StateUp
+}
+
+// percentile records latency and returns the target's SLO percentile over its window.
+func (w *latencyWindows) percentile(target pkg.Target, latency float64, now time.Time) float64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cutoff := now.Add(-target.SLOWindow)
+	samples := w.samples[target.MonitorID()]
+	kept := samples[:0]
+	for _, sample := range samples {
+		if sample.at.After(cutoff) {
+			kept = append(kept, sample)
+		}
+	}
+	kept = append(kept, latencySample{at: now, latency: latency})
+	w.samples[target.MonitorID()] = kept
+
+	values := make([]float64, len(kept))
+	for i, sample := range kept {
+		values[i] = sample.latency
+	}
+	return pkg.Percentile(values, target.SLOPercentile)
+}
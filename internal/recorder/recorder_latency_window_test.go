@@ -0,0 +1,26 @@
+package recorder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+func TestLatencyWindowsAreKeptPerMonitor(t *testing.T) {
+	windows := newLatencyWindows()
+	now := time.Now()
+
+	// two monitors checking the same URL with different settings
+	slow := pkg.Target{ID: "slow", URL: "http://a", DegradedThreshold: 100 * time.Millisecond, SLOPercentile: 50, SLOWindow: time.Hour}
+	fast := pkg.Target{ID: "fast", URL: "http://a", DegradedThreshold: 100 * time.Millisecond, SLOPercentile: 50, SLOWindow: time.Hour}
+
+	for i := 0; i < 3; i++ {
+		windows.evaluate(slow, pkg.CheckResult{Up: true, ResponseTime: 500}, now.Add(time.Duration(i)*time.Second))
+	}
+
+	state := windows.evaluate(fast, pkg.CheckResult{Up: true, ResponseTime: 10}, now.Add(5*time.Second))
+	if state != pkg.StateUp {
+		t.Fatalf("fast monitor is %s, its window took the latencies of the other monitor", state)
+	}
+}
@@ -0,0 +1,177 @@
+package recorder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	promconfig "github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"go.uber.org/zap"
+)
+
+const relabelMigrationName = "relabel_series_by_monitor_id"
+
+type relabelMigration struct {
+	tsdb       *tsdb.DB
+	appendable storage.Appendable
+	db         *sql.DB
+	config     *pkg.Config
+	logger     *zap.SugaredLogger
+}
+
+// RelabelMigration rewrites series that were keyed only by url so they carry
+// the monitor_id, type, name and user labels of the target with that URL.
+// It runs once; completion is recorded in the tsdb_migration table.
+type RelabelMigration interface {
+	Run(ctx context.Context, targets []pkg.Target) error
+}
+
+// NewRelabelMigration reads the old series from tsdb and writes the
+// relabeled ones through appendable, so remote_write receives them too.
+func NewRelabelMigration(
+	tsdb *tsdb.DB,
+	appendable storage.Appendable,
+	db *sql.DB,
+	config *pkg.Config,
+	logger *zap.SugaredLogger,
+) RelabelMigration {
+	return &relabelMigration{
+		tsdb,
+		appendable,
+		db,
+		config,
+		logger,
+	}
+}
+
+func (s *relabelMigration) Run(ctx context.Context, targets []pkg.Target) error {
+	var applied int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tsdb_migration WHERE name = ?", relabelMigrationName).Scan(&applied)
+	if err != nil {
+		return fmt.Errorf("error checking tsdb migration: %v", err)
+	}
+	if applied > 0 {
+		return nil
+	}
+
+	// When several monitors share a URL the history goes to the first one.
+	byURL := make(map[string]pkg.Target)
+	for _, target := range targets {
+		if _, ok := byURL[target.URL]; !ok {
+			byURL[target.URL] = target
+		}
+	}
+
+	// The copied history is older than the out-of-order window the TSDB
+	// runs with, so the window spans the retention while copying.
+	if err := setOutOfOrderWindow(s.tsdb, s.config.MaxRetention()); err != nil {
+		return fmt.Errorf("error widening out-of-order window: %v", err)
+	}
+	relabeled, skipped, err := s.copySeries(ctx, byURL)
+	if err := setOutOfOrderWindow(s.tsdb, s.config.OutOfOrderWindow()); err != nil {
+		s.logger.Errorf("Error restoring out-of-order window: %v", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, old := range relabeled {
+		matchers := []*labels.Matcher{
+			labels.MustNewMatcher(labels.MatchEqual, "monitor_id", ""),
+			pkg.OwnSeries,
+		}
+		old.Range(func(l labels.Label) {
+			matchers = append(matchers, labels.MustNewMatcher(labels.MatchEqual, l.Name, l.Value))
+		})
+		if err := s.tsdb.Delete(ctx, math.MinInt64, math.MaxInt64, matchers...); err != nil {
+			return fmt.Errorf("error deleting url keyed series %s: %v", old.String(), err)
+		}
+	}
+
+	_, err = s.db.ExecContext(ctx, "INSERT INTO tsdb_migration (name) VALUES (?)", relabelMigrationName)
+	if err != nil {
+		return fmt.Errorf("error recording tsdb migration: %v", err)
+	}
+
+	s.logger.Infof("Relabeled %d series by monitor_id, %d series left keyed by url (no matching target)", len(relabeled), skipped)
+	return nil
+}
+
+func setOutOfOrderWindow(db *tsdb.DB, window time.Duration) error {
+	return db.ApplyConfig(&promconfig.Config{
+		StorageConfig: promconfig.StorageConfig{
+			TSDBConfig: &promconfig.TSDBConfig{OutOfOrderTimeWindow: window.Milliseconds()},
+		},
+	})
+}
+
+// copySeries appends every url keyed sample under its new label set and
+// returns the label sets that were copied.
+func (s *relabelMigration) copySeries(ctx context.Context, byURL map[string]pkg.Target) ([]labels.Labels, int, error) {
+	querier, err := s.tsdb.Querier(math.MinInt64, math.MaxInt64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error creating querier: %v", err)
+	}
+	defer querier.Close()
+
+	seriesSet := querier.Select(ctx, false, nil,
+		labels.MustNewMatcher(labels.MatchRegexp, "url", ".+"),
+		labels.MustNewMatcher(labels.MatchEqual, "monitor_id", ""),
+		pkg.OwnSeries,
+	)
+
+	var relabeled []labels.Labels
+	skipped := 0
+	var iter chunkenc.Iterator
+	for seriesSet.Next() {
+		series := seriesSet.At()
+		old := series.Labels()
+
+		target, ok := byURL[old.Get("url")]
+		if !ok {
+			skipped++
+			continue
+		}
+
+		var extra []string
+		old.Range(func(l labels.Label) {
+			if l.Name != labels.MetricName && l.Name != "url" {
+				extra = append(extra, l.Name, l.Value)
+			}
+		})
+		relabel := seriesLabels(old.Get(labels.MetricName), target, extra...)
+
+		appender := s.appendable.Appender(ctx)
+		var ref storage.SeriesRef
+		iter = series.Iterator(iter)
+		for iter.Next() == chunkenc.ValFloat {
+			ts, val := iter.At()
+			ref, err = appender.Append(ref, relabel, ts, val)
+			if err != nil {
+				appender.Rollback()
+				return nil, 0, fmt.Errorf("error appending relabeled sample for %s: %v", old.String(), err)
+			}
+		}
+		if err := iter.Err(); err != nil {
+			appender.Rollback()
+			return nil, 0, fmt.Errorf("error reading series %s: %v", old.String(), err)
+		}
+		if err := appender.Commit(); err != nil {
+			return nil, 0, fmt.Errorf("error committing relabeled series %s: %v", old.String(), err)
+		}
+
+		relabeled = append(relabeled, old.Copy())
+	}
+	if err := seriesSet.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error selecting url keyed series: %v", err)
+	}
+
+	return relabeled, skipped, nil
+}
@@ -0,0 +1,87 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+func TestRelabelMigrationCopiesHistoryOlderThanTheWindow(t *testing.T) {
+	ctx := context.Background()
+	config := testutil.Config(t)
+	config.RetentionPeriod = 30 * 24 * time.Hour
+	db := testutil.DB(t)
+	tsdb := testutil.TSDB(t, config)
+	now := time.Now()
+	old := now.Add(-10 * 24 * time.Hour).UnixMilli()
+
+	urlKeyed := labels.FromStrings(labels.MetricName, "up", "url", "http://a")
+	appender := tsdb.Appender(ctx)
+	if _, err := appender.Append(0, urlKeyed, now.UnixMilli(), 1); err != nil {
+		t.Fatalf("appending: %v", err)
+	}
+	if err := appender.Commit(); err != nil {
+		t.Fatalf("committing: %v", err)
+	}
+
+	// checks cannot write that far back
+	appender = tsdb.Appender(ctx)
+	if _, err := appender.Append(0, urlKeyed, old, 1); !errors.Is(err, storage.ErrTooOldSample) {
+		t.Fatalf("appending a sample older than the window: %v, want %v", err, storage.ErrTooOldSample)
+	}
+	appender.Rollback()
+
+	// history from before the window, as an older statx left it
+	if err := setOutOfOrderWindow(tsdb, config.MaxRetention()); err != nil {
+		t.Fatal(err)
+	}
+	appender = tsdb.Appender(ctx)
+	if _, err := appender.Append(0, urlKeyed, old, 1); err != nil {
+		t.Fatalf("appending history: %v", err)
+	}
+	if err := appender.Commit(); err != nil {
+		t.Fatalf("committing history: %v", err)
+	}
+	if err := setOutOfOrderWindow(tsdb, config.OutOfOrderWindow()); err != nil {
+		t.Fatal(err)
+	}
+
+	target := pkg.Target{ID: "a", URL: "http://a"}
+	migration := NewRelabelMigration(tsdb, tsdb, db, config, testutil.Logger())
+	if err := migration.Run(ctx, []pkg.Target{target}); err != nil {
+		t.Fatalf("running migration: %v", err)
+	}
+
+	querier, err := tsdb.Querier(old, now.UnixMilli())
+	if err != nil {
+		t.Fatalf("creating querier: %v", err)
+	}
+	defer querier.Close()
+
+	series := querier.Select(ctx, false, nil, labels.MustNewMatcher(labels.MatchEqual, "monitor_id", "a"))
+	var timestamps []int64
+	for series.Next() {
+		iter := series.At().Iterator(nil)
+		for iter.Next() == chunkenc.ValFloat {
+			ts, _ := iter.At()
+			timestamps = append(timestamps, ts)
+		}
+	}
+	if len(timestamps) != 2 || timestamps[0] != old {
+		t.Fatalf("relabeled samples at %v, want the history at %d and the recent one", timestamps, old)
+	}
+
+	// the window is narrow again afterwards
+	appender = tsdb.Appender(ctx)
+	defer appender.Rollback()
+	if _, err := appender.Append(0, seriesLabels("up", target), old-1, 1); !errors.Is(err, storage.ErrTooOldSample) {
+		t.Fatalf("appending after the migration: %v, want %v", err, storage.ErrTooOldSample)
+	}
+}
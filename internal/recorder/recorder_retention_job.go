@@ -0,0 +1,202 @@
+package recorder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/check_error"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"go.uber.org/zap"
+)
+
+const retentionInterval = time.Hour
+
+type retentionJob struct {
+	tsdb        *tsdb.DB
+	checkErrors check_error.CheckErrorRepository
+	config      *pkg.Config
+	targets     func(ctx context.Context) []pkg.Target
+	logger      *zap.SugaredLogger
+}
+
+// RetentionJob enforces the per type and per monitor retention policies by
+// deleting expired raw and rollup samples. The TSDB wide RetentionPeriod
+// still applies on top and is the longest anything can be kept; stored check
+// error messages are pruned along with it. targets is called on every run so
+// monitors added or changed through the API are covered.
+type RetentionJob interface {
+	Start(ctx context.Context)
+	RunOnce(ctx context.Context, now time.Time) error
+}
+
+func NewRetentionJob(
+	tsdb *tsdb.DB,
+	db *sql.DB,
+	config *pkg.Config,
+	targets func(ctx context.Context) []pkg.Target,
+	logger *zap.SugaredLogger,
+) RetentionJob {
+	return &retentionJob{
+		tsdb,
+		check_error.NewCheckErrorRepository(db),
+		config,
+		targets,
+		logger,
+	}
+}
+
+func (s *retentionJob) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(retentionInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.RunOnce(ctx, time.Now()); err != nil {
+				s.logger.Errorf("Error enforcing retention: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// policyFor resolves the retention of a target: its own override, then the
+// policy of its type, then the global RetentionPeriod.
+func (s *retentionJob) policyFor(target pkg.Target) pkg.RetentionPolicy {
+	policy := s.typePolicy(target.MonitorType())
+	if target.RawRetention > 0 {
+		policy.Raw = target.RawRetention
+	}
+	if target.RollupRetention > 0 {
+		policy.Rollup = target.RollupRetention
+	}
+	return policy
+}
+
+func (s *retentionJob) typePolicy(monitorType string) pkg.RetentionPolicy {
+	policy := pkg.RetentionPolicy{
+		Raw:    s.config.RetentionPeriod,
+		Rollup: s.config.RetentionPeriod,
+	}
+	if typePolicy, ok := s.config.RetentionPolicies[monitorType]; ok {
+		if typePolicy.Raw > 0 {
+			policy.Raw = typePolicy.Raw
+		}
+		if typePolicy.Rollup > 0 {
+			policy.Rollup = typePolicy.Rollup
+		}
+	}
+	return policy
+}
+
+func (s *retentionJob) RunOnce(ctx context.Context, now time.Time) error {
+	ceiling := s.config.MaxRetention()
+
+	var overridden []string
+	overriddenByType := make(map[string][]string)
+	for _, target := range s.targets(ctx) {
+		if target.RawRetention <= 0 && target.RollupRetention <= 0 {
+			continue
+		}
+		monitorType := target.MonitorType()
+		overridden = append(overridden, target.MonitorID())
+		overriddenByType[monitorType] = append(overriddenByType[monitorType], target.MonitorID())
+
+		policy := s.policyFor(target)
+		if policy.Raw > ceiling || policy.Rollup > ceiling {
+			// only monitors stored before overrides were validated get here
+			s.logger.Warnf("Retention of monitor %s exceeds the TSDB retention of %s and is capped by it", target.MonitorID(), ceiling)
+		}
+
+		selector := labels.MustNewMatcher(labels.MatchEqual, "monitor_id", target.MonitorID())
+		if err := s.deleteExpired(ctx, now, ceiling, policy, selector); err != nil {
+			return fmt.Errorf("monitor %s: %v", target.MonitorID(), err)
+		}
+	}
+
+	var policyTypes []string
+	for monitorType := range s.config.RetentionPolicies {
+		policyTypes = append(policyTypes, monitorType)
+
+		selectors := []*labels.Matcher{
+			labels.MustNewMatcher(labels.MatchEqual, "type", monitorType),
+		}
+		if ids := overriddenByType[monitorType]; len(ids) > 0 {
+			selectors = append(selectors, labels.MustNewMatcher(labels.MatchNotRegexp, "monitor_id", alternation(ids)))
+		}
+		if err := s.deleteExpired(ctx, now, ceiling, s.typePolicy(monitorType), selectors...); err != nil {
+			return fmt.Errorf("type %s: %v", monitorType, err)
+		}
+	}
+
+	// A type policy may have raised the TSDB retention above RetentionPeriod;
+	// everything else still gets the default.
+	if ceiling > s.config.RetentionPeriod {
+		selectors := []*labels.Matcher{
+			labels.MustNewMatcher(labels.MatchNotRegexp, "type", alternation(policyTypes)),
+		}
+		if len(overridden) > 0 {
+			selectors = append(selectors, labels.MustNewMatcher(labels.MatchNotRegexp, "monitor_id", alternation(overridden)))
+		}
+		policy := pkg.RetentionPolicy{Raw: s.config.RetentionPeriod, Rollup: s.config.RetentionPeriod}
+		if err := s.deleteExpired(ctx, now, ceiling, policy, selectors...); err != nil {
+			return fmt.Errorf("default policy: %v", err)
+		}
+	}
+
+	// Deletes only write tombstones; rewrite the affected blocks so the space is freed.
+	if err := s.tsdb.CleanTombstones(); err != nil {
+		return fmt.Errorf("error cleaning tombstones: %v", err)
+	}
+
+	if _, err := s.checkErrors.DeleteBefore(ctx, now.Add(-ceiling)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// deleteExpired drops samples older than the policy; nothing is done for
+// durations the TSDB retention (ceiling) already covers.
+func (s *retentionJob) deleteExpired(ctx context.Context, now time.Time, ceiling time.Duration, policy pkg.RetentionPolicy, selectors ...*labels.Matcher) error {
+	if policy.Raw > 0 && policy.Raw < ceiling {
+		matchers := append([]*labels.Matcher{
+			labels.MustNewMatcher(labels.MatchNotRegexp, labels.MetricName, "rollup_.+"),
+		}, selectors...)
+		if err := s.tsdb.Delete(ctx, math.MinInt64, now.Add(-policy.Raw).UnixMilli(), matchers...); err != nil {
+			return fmt.Errorf("error deleting raw samples: %v", err)
+		}
+	}
+
+	if policy.Rollup > 0 && policy.Rollup < ceiling {
+		matchers := append([]*labels.Matcher{
+			labels.MustNewMatcher(labels.MatchRegexp, labels.MetricName, "rollup_.+"),
+		}, selectors...)
+		if err := s.tsdb.Delete(ctx, math.MinInt64, now.Add(-policy.Rollup).UnixMilli(), matchers...); err != nil {
+			return fmt.Errorf("error deleting rollup samples: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func alternation(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = regexp.QuoteMeta(v)
+	}
+	sort.Strings(quoted)
+	return strings.Join(quoted, "|")
+}
@@ -0,0 +1,91 @@
+package recorder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/check_error"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+func TestRetentionJobDeletesPerMonitor(t *testing.T) {
+	ctx := context.Background()
+	config := testutil.Config(t)
+	db := testutil.DB(t)
+	storage := testutil.TSDB(t, config)
+	now := time.Now()
+
+	short := pkg.Target{ID: "short", URL: "http://short", RawRetention: time.Hour}
+	long := pkg.Target{ID: "long", URL: "http://long"}
+
+	appender := storage.Appender(ctx)
+	for _, target := range []pkg.Target{short, long} {
+		for _, age := range []time.Duration{2 * time.Hour, 10 * time.Minute} {
+			if _, err := appender.Append(0, seriesLabels("up", target), now.Add(-age).UnixMilli(), 1); err != nil {
+				t.Fatalf("appending: %v", err)
+			}
+		}
+	}
+	if err := appender.Commit(); err != nil {
+		t.Fatalf("committing: %v", err)
+	}
+
+	checkErrors := check_error.NewCheckErrorRepository(db)
+	expired := now.Add(-config.MaxRetention() - time.Hour)
+	if err := checkErrors.Insert(ctx, "long", expired, pkg.ErrorClassTimeout, "timeout"); err != nil {
+		t.Fatalf("inserting check error: %v", err)
+	}
+
+	// The targets are read on every run, so a monitor added later is covered.
+	var targets []pkg.Target
+	job := NewRetentionJob(storage, db, config, func(context.Context) []pkg.Target { return targets }, testutil.Logger())
+	if err := job.RunOnce(ctx, now); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	if got := countSamples(t, storage, "short"); got != 2 {
+		t.Fatalf("short kept %d samples before it was known, want 2", got)
+	}
+
+	targets = []pkg.Target{short, long}
+	if err := job.RunOnce(ctx, now); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if got := countSamples(t, storage, "short"); got != 1 {
+		t.Errorf("short kept %d samples, want 1", got)
+	}
+	if got := countSamples(t, storage, "long"); got != 2 {
+		t.Errorf("long kept %d samples, want 2", got)
+	}
+
+	messages, err := checkErrors.Messages(ctx, "long", expired.Add(-time.Minute), now)
+	if err != nil {
+		t.Fatalf("reading check errors: %v", err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("expired check errors were kept: %v", messages)
+	}
+}
+
+func countSamples(t *testing.T, storage *tsdb.DB, monitorID string) int {
+	t.Helper()
+	querier, err := storage.Querier(0, time.Now().Add(time.Hour).UnixMilli())
+	if err != nil {
+		t.Fatalf("creating querier: %v", err)
+	}
+	defer querier.Close()
+
+	count := 0
+	series := querier.Select(context.Background(), false, nil, labels.MustNewMatcher(labels.MatchEqual, "monitor_id", monitorID))
+	for series.Next() {
+		iter := series.At().Iterator(nil)
+		for iter.Next() == chunkenc.ValFloat {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,273 @@
+package recorder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"go.uber.org/zap"
+)
+
+const (
+	// rollupMaxBuckets bounds the work of a single run when catching up.
+	rollupMaxBuckets = 2016
+)
+
+type rollupJob struct {
+	tsdb       *tsdb.DB
+	appendable storage.Appendable
+	db         *sql.DB
+	config     *pkg.Config
+	logger     *zap.SugaredLogger
+}
+
+// RollupJob periodically downsamples raw check data into 5 minute, hourly
+// and daily buckets holding the uptime ratio, min/avg/max/p95 latency and
+// sample count of every monitor.
+type RollupJob interface {
+	Start(ctx context.Context)
+	RunOnce(ctx context.Context, now time.Time) error
+}
+
+// NewRollupJob reads raw samples from tsdb and writes the rollups through
+// appendable, so they reach the remote_write tee like check samples do.
+func NewRollupJob(
+	tsdb *tsdb.DB,
+	appendable storage.Appendable,
+	db *sql.DB,
+	config *pkg.Config,
+	logger *zap.SugaredLogger,
+) RollupJob {
+	return &rollupJob{
+		tsdb,
+		appendable,
+		db,
+		config,
+		logger,
+	}
+}
+
+func (s *rollupJob) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(pkg.RollupInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.RunOnce(ctx, time.Now()); err != nil {
+				s.logger.Errorf("Error writing rollups: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *rollupJob) RunOnce(ctx context.Context, now time.Time) error {
+	for _, res := range pkg.Resolutions {
+		if err := s.rollup(ctx, res, now); err != nil {
+			return fmt.Errorf("resolution %s: %v", res.Name, err)
+		}
+	}
+	return nil
+}
+
+// lastEnd is where the next run of a resolution starts: the end of the last
+// run, but never before the first bucket the TSDB still accepts. Samples
+// older than the out-of-order window are rejected, so buckets before it are
+// skipped rather than failing every run.
+func (s *rollupJob) lastEnd(ctx context.Context, res pkg.Resolution, now time.Time) (time.Time, error) {
+	oldest := now.Add(-s.config.OutOfOrderWindow()).Truncate(res.Step).Add(res.Step)
+
+	var lastEnd int64
+	err := s.db.QueryRowContext(ctx, "SELECT last_end FROM rollup_state WHERE resolution = ?", res.Name).Scan(&lastEnd)
+	if errors.Is(err, sql.ErrNoRows) {
+		return oldest, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error fetching rollup state: %v", err)
+	}
+	if start := time.UnixMilli(lastEnd); start.After(oldest) {
+		return start, nil
+	}
+	return oldest, nil
+}
+
+func (s *rollupJob) saveLastEnd(ctx context.Context, res pkg.Resolution, end time.Time) error {
+	query := `
+		INSERT INTO rollup_state (resolution, last_end, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(resolution) DO UPDATE SET
+			last_end = excluded.last_end,
+			updated_at = excluded.updated_at
+	`
+	if _, err := s.db.ExecContext(ctx, query, res.Name, end.UnixMilli()); err != nil {
+		return fmt.Errorf("error saving rollup state: %v", err)
+	}
+	return nil
+}
+
+type rollupBucket struct {
+	up        int
+	total     int
+	latencies []float64
+}
+
+type monitorBuckets struct {
+	lset    labels.Labels
+	buckets map[int64]*rollupBucket
+}
+
+func (s *rollupJob) rollup(ctx context.Context, res pkg.Resolution, now time.Time) error {
+	start, err := s.lastEnd(ctx, res, now)
+	if err != nil {
+		return err
+	}
+
+	end := now.Add(-pkg.RollupGrace).Truncate(res.Step)
+	if maxEnd := start.Add(rollupMaxBuckets * res.Step); end.After(maxEnd) {
+		end = maxEnd
+	}
+	if !end.After(start) {
+		return nil
+	}
+
+	monitors, err := s.collect(ctx, res, start, end)
+	if err != nil {
+		return err
+	}
+
+	if err := s.write(ctx, res, monitors); err != nil {
+		return err
+	}
+
+	return s.saveLastEnd(ctx, res, end)
+}
+
+// collect groups the raw up and latency samples in [start, end) by monitor and bucket.
+func (s *rollupJob) collect(ctx context.Context, res pkg.Resolution, start, end time.Time) (map[string]*monitorBuckets, error) {
+	// querier bounds are inclusive
+	querier, err := s.tsdb.Querier(start.UnixMilli(), end.UnixMilli()-1)
+	if err != nil {
+		return nil, fmt.Errorf("error creating querier: %v", err)
+	}
+	defer querier.Close()
+
+	step := res.Step.Milliseconds()
+	monitors := make(map[string]*monitorBuckets)
+	bucketFor := func(lset labels.Labels, ts int64) *rollupBucket {
+		monitorID := lset.Get("monitor_id")
+		m, ok := monitors[monitorID]
+		if !ok {
+			m = &monitorBuckets{buckets: make(map[int64]*rollupBucket)}
+			monitors[monitorID] = m
+		}
+		m.lset = lset
+		bucketStart := ts - ts%step
+		b, ok := m.buckets[bucketStart]
+		if !ok {
+			b = &rollupBucket{}
+			m.buckets[bucketStart] = b
+		}
+		return b
+	}
+
+	var iter chunkenc.Iterator
+
+	upSeries := querier.Select(ctx, false, nil,
+		labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "up"),
+		labels.MustNewMatcher(labels.MatchRegexp, "monitor_id", ".+"),
+		pkg.OwnSeries,
+	)
+	for upSeries.Next() {
+		series := upSeries.At()
+		iter = series.Iterator(iter)
+		for iter.Next() == chunkenc.ValFloat {
+			ts, val := iter.At()
+			b := bucketFor(series.Labels(), ts)
+			b.total++
+			if val == 1 {
+				b.up++
+			}
+		}
+	}
+	if err := upSeries.Err(); err != nil {
+		return nil, fmt.Errorf("error selecting up series: %v", err)
+	}
+
+	latencySeries := querier.Select(ctx, false, nil,
+		labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "http_response_time"),
+		labels.MustNewMatcher(labels.MatchRegexp, "monitor_id", ".+"),
+		pkg.OwnSeries,
+	)
+	for latencySeries.Next() {
+		series := latencySeries.At()
+		iter = series.Iterator(iter)
+		for iter.Next() == chunkenc.ValFloat {
+			ts, val := iter.At()
+			b := bucketFor(series.Labels(), ts)
+			b.latencies = append(b.latencies, val)
+		}
+	}
+	if err := latencySeries.Err(); err != nil {
+		return nil, fmt.Errorf("error selecting latency series: %v", err)
+	}
+
+	return monitors, nil
+}
+
+func (s *rollupJob) write(ctx context.Context, res pkg.Resolution, monitors map[string]*monitorBuckets) error {
+	appender := s.appendable.Appender(ctx)
+	defer appender.Rollback()
+
+	for _, m := range monitors {
+		builder := labels.NewBuilder(m.lset)
+		builder.Set("resolution", res.Name)
+		rollupLabels := func(name string) labels.Labels {
+			return builder.Set(labels.MetricName, name).Labels()
+		}
+
+		for ts, b := range m.buckets {
+			values := map[string]float64{
+				pkg.RollupSampleCount: float64(b.total),
+			}
+			if b.total > 0 {
+				values[pkg.RollupUptimeRatio] = float64(b.up) / float64(b.total)
+			}
+			if len(b.latencies) > 0 {
+				min, max, sum := math.Inf(1), math.Inf(-1), 0.0
+				for _, v := range b.latencies {
+					min = math.Min(min, v)
+					max = math.Max(max, v)
+					sum += v
+				}
+				values[pkg.RollupLatencyMin] = min
+				values[pkg.RollupLatencyMax] = max
+				values[pkg.RollupLatencyAvg] = sum / float64(len(b.latencies))
+				values[pkg.RollupLatencyP95] = pkg.Percentile(b.latencies, 95)
+			}
+
+			for name, value := range values {
+				if _, err := appender.Append(0, rollupLabels(name), ts, value); err != nil {
+					return fmt.Errorf("error appending %s sample: %v", name, err)
+				}
+			}
+		}
+	}
+
+	if err := appender.Commit(); err != nil {
+		return fmt.Errorf("error committing rollups: %v", err)
+	}
+	return nil
+}
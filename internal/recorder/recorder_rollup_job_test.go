@@ -0,0 +1,59 @@
+package recorder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+func TestRollupJobSkipsBucketsOutsideOOOWindow(t *testing.T) {
+	ctx := context.Background()
+	config := testutil.Config(t)
+	config.RetentionPeriod = 24 * time.Hour
+	db := testutil.DB(t)
+	storage := testutil.TSDB(t, config)
+	now := time.Now()
+
+	target := pkg.Target{ID: "a", URL: "http://a"}
+	appender := storage.Appender(ctx)
+	for _, age := range []time.Duration{24*time.Hour - time.Minute, 2 * time.Hour, 0} {
+		if _, err := appender.Append(0, seriesLabels("up", target), now.Add(-age).UnixMilli(), 1); err != nil {
+			t.Fatalf("appending: %v", err)
+		}
+	}
+	if err := appender.Commit(); err != nil {
+		t.Fatalf("committing: %v", err)
+	}
+
+	// a state left behind by a long downtime starts before the window too
+	if _, err := db.Exec("INSERT INTO rollup_state (resolution, last_end) VALUES ('1h', ?)", now.Add(-10*24*time.Hour).UnixMilli()); err != nil {
+		t.Fatalf("inserting rollup state: %v", err)
+	}
+
+	job := NewRollupJob(storage, storage, db, config, testutil.Logger())
+	for run := 0; run < 2; run++ {
+		if err := job.RunOnce(ctx, now); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+
+	querier, err := storage.Querier(0, now.UnixMilli())
+	if err != nil {
+		t.Fatalf("creating querier: %v", err)
+	}
+	defer querier.Close()
+
+	for _, resolution := range []string{"5m", "1h"} {
+		series := querier.Select(ctx, false, nil,
+			labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, pkg.RollupSampleCount),
+			labels.MustNewMatcher(labels.MatchEqual, "resolution", resolution),
+		)
+		if !series.Next() {
+			t.Errorf("no %s rollups were written", resolution)
+		}
+	}
+}
@@ -3,47 +3,70 @@ package recorder
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"regexp"
 	"time"
 
+	"github.com/afrianjunior/statx/internal/check_error"
+	"github.com/afrianjunior/statx/internal/content_change"
 	"github.com/afrianjunior/statx/internal/pkg"
 	"github.com/prometheus/prometheus/model/labels"
-	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/storage"
 	"go.uber.org/zap"
 )
 
 type recorderService struct {
-	tsdb       *tsdb.DB
+	appendable storage.Appendable
 	db         *sql.DB
 	config     *pkg.Config
 	httpClient *http.Client
 	logger     *zap.SugaredLogger
+	transports *transportCache
+
+	latencyWindows *latencyWindows
+	contentChanges content_change.ContentChangeService
+	checkErrors    check_error.CheckErrorRepository
+	statuses       StatusTracker
+	events         Broadcaster
 }
 
 type RecorderService interface {
-	WriteUpTimeRecord(ctx context.Context, url string, statusCode int, responseTime float64) error
-	CheckUptimeWithRetry(target pkg.Target) (int, float64, error)
+	WriteUpTimeRecord(ctx context.Context, target pkg.Target, result pkg.CheckResult) error
+	CheckUptimeWithRetry(target pkg.Target) (pkg.CheckResult, error)
 }
 
+// NewRecorderService writes check samples through appendable, which is the
+// TSDB itself or a wrapper around it such as the remote_write tee.
 func NewRecorderService(
-	tsdb *tsdb.DB,
+	appendable storage.Appendable,
 	db *sql.DB,
 	config *pkg.Config,
 	httpClient *http.Client,
 	logger *zap.SugaredLogger,
+	contentChanges content_change.ContentChangeService,
+	statuses StatusTracker,
+	events Broadcaster,
 ) RecorderService {
 	return &recorderService{
-		tsdb,
+		appendable,
 		db,
 		config,
 		httpClient,
 		logger,
+		newTransportCache(httpClient, config.DefaultProxy),
+		newLatencyWindows(),
+		contentChanges,
+		check_error.NewCheckErrorRepository(db),
+		statuses,
+		events,
 	}
 }
 
 func (s *recorderService) WriteGenericRecord(ctx context.Context, key string, value int) error {
-	appender := s.tsdb.Appender(ctx)
+	appender := s.appendable.Appender(ctx)
 	defer appender.Rollback()
 
 	labelSet := labels.Labels{
@@ -63,52 +86,246 @@ func (s *recorderService) WriteGenericRecord(ctx context.Context, key string, va
 	return nil
 }
 
-func (s *recorderService) WriteUpTimeRecord(ctx context.Context, url string, statusCode int, responseTime float64) error {
-	appender := s.tsdb.Appender(ctx)
+// WriteUpTimeRecord appends the samples of one check. Failed checks are
+// recorded as up=0 plus a check_error sample carrying the error class, the
+// message goes to the check_error table so it never becomes a label; they write no latency so they do not drag averages down, and an
+// http_status only when a response was actually received.
+func (s *recorderService) WriteUpTimeRecord(ctx context.Context, target pkg.Target, result pkg.CheckResult) error {
+	appender := s.appendable.Appender(ctx)
 	defer appender.Rollback()
 
-	labelSet := labels.Labels{
-		{Name: "__name__", Value: "http_status"},
-		{Name: "url", Value: url},
+	checkedAt := result.CheckedAt
+	if checkedAt.IsZero() {
+		checkedAt = time.Now()
 	}
+	ts := checkedAt.UnixMilli()
 
-	_, err := appender.Append(0, labelSet, time.Now().UnixNano()/int64(time.Millisecond), float64(statusCode))
-	if err != nil {
-		return fmt.Errorf("error appending sample: %v", err)
+	class := result.ErrorClass
+	if class == "" {
+		class = pkg.ErrorClassUnknown
+	}
+
+	if result.StatusCode > 0 {
+		_, err := appender.Append(0, seriesLabels("http_status", target), ts, float64(result.StatusCode))
+		if err != nil {
+			return fmt.Errorf("error appending sample: %v", err)
+		}
+	}
+
+	if result.Up {
+		_, err := appender.Append(0, seriesLabels("http_response_time", target), ts, result.ResponseTime)
+		if err != nil {
+			return fmt.Errorf("error appending response time sample: %v", err)
+		}
+	} else {
+		_, err := appender.Append(0, seriesLabels("check_error", target, "class", class), ts, 1)
+		if err != nil {
+			return fmt.Errorf("error appending check error sample: %v", err)
+		}
 	}
 
-	responseTimeLabelSet := labels.Labels{
-		{Name: "__name__", Value: "http_response_time"},
-		{Name: "url", Value: url},
+	up := 0.0
+	if result.Up {
+		up = 1
+	}
+	_, err := appender.Append(0, seriesLabels("up", target), ts, up)
+	if err != nil {
+		return fmt.Errorf("error appending up sample: %v", err)
 	}
-	_, err = appender.Append(0, responseTimeLabelSet, time.Now().UnixNano()/int64(time.Millisecond), responseTime)
+
+	_, err = appender.Append(0, seriesLabels("monitor_state", target), ts, float64(result.State))
 	if err != nil {
-		return fmt.Errorf("error appending response time sample: %v", err)
+		return fmt.Errorf("error appending state sample: %v", err)
+	}
+
+	if result.Proxied && result.Up {
+		_, err = appender.Append(0, seriesLabels("http_proxy_connect_time", target), ts, result.ProxyConnectTime)
+		if err != nil {
+			return fmt.Errorf("error appending proxy connect time sample: %v", err)
+		}
 	}
 
 	if err := appender.Commit(); err != nil {
 		return fmt.Errorf("error committing sample: %v", err)
 	}
 
+	if !result.Up && result.ErrorMessage != "" {
+		if err := s.checkErrors.Insert(ctx, target.MonitorID(), checkedAt, class, result.ErrorMessage); err != nil {
+			s.logger.Errorf("Error storing check error of %s: %v", target.URL, err)
+		}
+	}
+
+	previous, seen := s.statuses.Get(target.MonitorID())
+	status := s.statuses.Update(target, result, checkedAt)
+
+	s.events.Publish(pkg.CheckEvent{Type: pkg.EventCheck, Status: status})
+	if !seen || previous.State != status.State {
+		previousState := "unknown"
+		if seen {
+			previousState = previous.State
+		}
+		s.events.Publish(pkg.CheckEvent{Type: pkg.EventStateChange, Status: status, PreviousState: previousState})
+	}
+
 	return nil
 }
 
-func (s *recorderService) CheckUptimeWithRetry(target pkg.Target) (int, float64, error) {
+// CheckUptimeWithRetry performs the check and classifies it as up, degraded
+// or down.
+func (s *recorderService) CheckUptimeWithRetry(target pkg.Target) (pkg.CheckResult, error) {
+	checkedAt := time.Now()
+	result, err := s.checkUptime(target)
+	result.CheckedAt = checkedAt
+	result.State = s.latencyWindows.evaluate(target, result, checkedAt)
+
+	if result.Up && result.Body != nil {
+		s.detectContentChange(context.Background(), target, result.Body, checkedAt)
+	}
+
+	return result, err
+}
+
+// detectContentChange stores the new snapshot and, when the content differs,
+// logs the change and marks it in the content_change series.
+func (s *recorderService) detectContentChange(ctx context.Context, target pkg.Target, body []byte, checkedAt time.Time) {
+	change, err := s.contentChanges.DetectChange(ctx, target, body)
+	if err != nil {
+		s.logger.Errorf("Error detecting content change for %s: %v", target.URL, err)
+		return
+	}
+	if change == nil {
+		return
+	}
+
+	s.logger.Warnw("Content changed",
+		"url", target.URL,
+		"change_id", change.ID,
+		"old_hash", change.OldHash,
+		"new_hash", change.NewHash,
+	)
+
+	appender := s.appendable.Appender(ctx)
+	defer appender.Rollback()
+
+	if _, err := appender.Append(0, seriesLabels("content_change", target), checkedAt.UnixMilli(), 1); err != nil {
+		s.logger.Errorf("Error appending content change sample: %v", err)
+		return
+	}
+	if err := appender.Commit(); err != nil {
+		s.logger.Errorf("Error committing content change sample: %v", err)
+	}
+}
+
+// checkUptime performs the check, retrying transport errors, and evaluates
+// the target's up/down rules against the response. When the target goes
+// through a proxy, the time spent connecting to the proxy is reported
+// separately and taken out of ResponseTime.
+func (s *recorderService) checkUptime(target pkg.Target) (pkg.CheckResult, error) {
+	acceptedStatusCodes, err := pkg.ParseStatusCodeRanges(target.AcceptedStatusCodes)
+	if err != nil {
+		return failedResult(pkg.ErrorClassUnknown, err), err
+	}
+
+	var finalURLPattern *regexp.Regexp
+	if target.FinalURLPattern != "" {
+		finalURLPattern, err = regexp.Compile(target.FinalURLPattern)
+		if err != nil {
+			err = fmt.Errorf("invalid final url pattern: %v", err)
+			return failedResult(pkg.ErrorClassUnknown, err), err
+		}
+	}
+
+	client, proxied, err := s.transports.clientFor(target)
+	if err != nil {
+		return failedResult(pkg.ErrorClassUnknown, err), err
+	}
+	client.CheckRedirect = redirectPolicy(target)
+
 	var lastErr error
 	for attempt := 0; attempt < s.config.RetryAttempts; attempt++ {
 		if attempt > 0 {
 			time.Sleep(s.config.RetryDelay)
 		}
 
+		ctx, timing := withProxyTiming(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL, nil)
+		if err != nil {
+			err = fmt.Errorf("error creating request: %v", err)
+			return failedResult(pkg.ErrorClassUnknown, err), err
+		}
+
 		start := time.Now()
-		resp, err := s.httpClient.Get(target.URL)
+		resp, err := client.Do(req)
 		responseTime := time.Since(start).Seconds() * 1000
+		if errors.Is(err, errTooManyRedirects) {
+			result := failedResult(pkg.ErrorClassAssertionFailed, err)
+			result.Proxied = proxied
+			return result, nil
+		}
 		if err == nil {
-			defer resp.Body.Close()
-			return resp.StatusCode, responseTime, nil
+			var body []byte
+			if target.ContentCheck {
+				body, err = io.ReadAll(io.LimitReader(resp.Body, content_change.MaxBodySize))
+				if err != nil {
+					s.logger.Warnf("Error reading body of %s: %v", target.URL, err)
+					body = nil
+				}
+			}
+			resp.Body.Close()
+			result := pkg.CheckResult{
+				StatusCode:   resp.StatusCode,
+				ResponseTime: responseTime,
+				Proxied:      proxied,
+				FinalURL:     resp.Request.URL.String(),
+				Up:           true,
+				Body:         body,
+			}
+			if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+				result.CertExpiry = resp.TLS.PeerCertificates[0].NotAfter
+			}
+			if proxied {
+				result.ProxyConnectTime = timing.Milliseconds()
+				result.ResponseTime -= result.ProxyConnectTime
+			}
+
+			switch {
+			case !acceptedStatusCodes.Contains(resp.StatusCode):
+				result.Up = false
+				result.ErrorClass = pkg.ErrorClassBadStatus
+				result.ErrorMessage = fmt.Sprintf("status %d not in %s", resp.StatusCode, target.AcceptedStatusCodes)
+				if target.AcceptedStatusCodes == "" {
+					result.ErrorMessage = fmt.Sprintf("status %d not in %s", resp.StatusCode, pkg.DefaultAcceptedStatusCodes)
+				}
+			case finalURLPattern != nil && !finalURLPattern.MatchString(result.FinalURL):
+				result.Up = false
+				result.ErrorClass = pkg.ErrorClassAssertionFailed
+				result.ErrorMessage = fmt.Sprintf("final url %s does not match %s", result.FinalURL, target.FinalURLPattern)
+			}
+			return result, nil
 		}
 		lastErr = err
 		s.logger.Warnf("Attempt %d failed for %s: %v", attempt+1, target.URL, err)
 	}
-	return 0, 0, lastErr
+	return failedResult(classifyError(lastErr), lastErr), lastErr
+}
+
+var errTooManyRedirects = errors.New("too many redirects")
+
+// redirectPolicy builds the CheckRedirect hook for a target. With redirects
+// disabled the 3xx response itself is evaluated against the accepted codes.
+func redirectPolicy(target pkg.Target) func(req *http.Request, via []*http.Request) error {
+	maxRedirects := target.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = 10
+	}
+
+	return func(req *http.Request, via []*http.Request) error {
+		if target.DisableRedirects {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= maxRedirects {
+			return errTooManyRedirects
+		}
+		return nil
+	}
 }
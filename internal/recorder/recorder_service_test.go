@@ -0,0 +1,121 @@
+package recorder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/check_error"
+	"github.com/afrianjunior/statx/internal/content_change"
+	"github.com/afrianjunior/statx/internal/exposer"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+func TestWriteUpTimeRecordKeepsMessageOutOfLabels(t *testing.T) {
+	ctx := context.Background()
+	config := testutil.Config(t)
+	db := testutil.DB(t)
+	tsdb := testutil.TSDB(t, config)
+
+	recorder := NewRecorderService(tsdb, db, config, nil, testutil.Logger(),
+		content_change.NewContentChangeService(content_change.NewContentChangeRepository(db)),
+		NewStatusTracker(), NewBroadcaster())
+
+	target := pkg.Target{ID: "a", URL: "http://a", Interval: time.Minute}
+	checkedAt := time.Now().Truncate(time.Millisecond)
+	for i, message := range []string{"dial tcp: connection refused", "dial tcp: i/o timeout"} {
+		result := pkg.CheckResult{
+			CheckedAt:    checkedAt.Add(time.Duration(i) * time.Second),
+			ErrorClass:   pkg.ErrorClassConnectionRefused,
+			ErrorMessage: message,
+			State:        pkg.StateDown,
+		}
+		if err := recorder.WriteUpTimeRecord(ctx, target, result); err != nil {
+			t.Fatalf("writing check: %v", err)
+		}
+	}
+
+	querier, err := tsdb.Querier(0, checkedAt.Add(time.Minute).UnixMilli())
+	if err != nil {
+		t.Fatalf("creating querier: %v", err)
+	}
+	defer querier.Close()
+
+	series := querier.Select(ctx, false, nil, labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, "check_error"))
+	count := 0
+	for series.Next() {
+		count++
+		lset := series.At().Labels()
+		if lset.Has("message") {
+			t.Errorf("check_error series has a message label: %s", lset)
+		}
+		if got := lset.Get("class"); got != pkg.ErrorClassConnectionRefused {
+			t.Errorf("class label = %q", got)
+		}
+	}
+	if count != 1 {
+		t.Fatalf("different messages created %d check_error series, want 1", count)
+	}
+
+	exposerService := exposer.NewExposerService(tsdb, check_error.NewCheckErrorRepository(db))
+	timeRange := pkg.TimeRange{Start: checkedAt.Add(-time.Minute), End: checkedAt.Add(time.Minute)}
+	results, err := exposerService.QueryUpTimeStatus(ctx, "a", timeRange, pkg.ResolutionRaw)
+	if err != nil {
+		t.Fatalf("querying status: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].ErrorMessage != "dial tcp: connection refused" || results[1].ErrorMessage != "dial tcp: i/o timeout" {
+		t.Errorf("messages = %q, %q", results[0].ErrorMessage, results[1].ErrorMessage)
+	}
+	if results[1].ErrorClass != pkg.ErrorClassConnectionRefused {
+		t.Errorf("class = %q", results[1].ErrorClass)
+	}
+}
+
+func TestFailedWriteLeavesStatusUnchanged(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := testutil.Config(t)
+	db := testutil.DB(t)
+	tsdb := testutil.TSDB(t, config)
+	pipeline := NewWritePipeline(tsdb, config, testutil.Logger())
+	pipeline.Start(ctx)
+
+	statuses := NewStatusTracker()
+	events := NewBroadcaster()
+	subscription, unsubscribe := events.Subscribe()
+	defer unsubscribe()
+
+	recorder := NewRecorderService(pipeline, db, config, nil, testutil.Logger(),
+		content_change.NewContentChangeService(content_change.NewContentChangeRepository(db)),
+		statuses, events)
+
+	target := pkg.Target{ID: "a", URL: "http://a", Interval: time.Minute}
+	checkedAt := time.Now()
+	up := pkg.CheckResult{CheckedAt: checkedAt, Up: true, StatusCode: 200, ResponseTime: 10, State: pkg.StateUp}
+	if err := recorder.WriteUpTimeRecord(ctx, target, up); err != nil {
+		t.Fatalf("writing check: %v", err)
+	}
+	<-subscription
+
+	// a second result for the same timestamp is rejected by the TSDB
+	down := pkg.CheckResult{CheckedAt: checkedAt, ErrorClass: pkg.ErrorClassTimeout, State: pkg.StateDown}
+	if err := recorder.WriteUpTimeRecord(ctx, target, down); err == nil {
+		t.Fatal("rejected write returned no error")
+	}
+
+	status, ok := statuses.Get("a")
+	if !ok || status.State != pkg.StateUp.String() {
+		t.Fatalf("status after a failed write = %+v, want it to stay up", status)
+	}
+	for len(subscription) > 0 {
+		if event := <-subscription; event.Type == pkg.EventCheck {
+			t.Fatalf("failed write published %+v", event)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package recorder
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+type statusTracker struct {
+	mu       sync.RWMutex
+	statuses map[string]pkg.MonitorStatus
+}
+
+// StatusTracker keeps the latest result of every monitor in memory so the
+// REST layer can serve current state without querying the TSDB.
+type StatusTracker interface {
+	Update(target pkg.Target, result pkg.CheckResult, checkedAt time.Time) pkg.MonitorStatus
+	Get(monitorID string) (pkg.MonitorStatus, bool)
+	List() []pkg.MonitorStatus
+	Remove(monitorID string)
+}
+
+func NewStatusTracker() StatusTracker {
+	return &statusTracker{
+		statuses: make(map[string]pkg.MonitorStatus),
+	}
+}
+
+// Update records a check result and returns the new status of the monitor.
+func (s *statusTracker) Update(target pkg.Target, result pkg.CheckResult, checkedAt time.Time) pkg.MonitorStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.statuses[target.MonitorID()]
+
+	status := pkg.MonitorStatus{
+		MonitorID:    target.MonitorID(),
+		Name:         target.Name,
+		Type:         target.MonitorType(),
+		URL:          target.URL,
+		Labels:       target.Labels,
+		Tags:         target.Tags,
+		LastCheck:    checkedAt,
+		Up:           result.Up,
+		State:        result.State.String(),
+		StatusCode:   result.StatusCode,
+		ResponseTime: result.ResponseTime,
+		ErrorClass:   result.ErrorClass,
+		ErrorMessage: result.ErrorMessage,
+		CertExpiry:   previous.CertExpiry,
+	}
+	if !result.CertExpiry.IsZero() {
+		certExpiry := result.CertExpiry
+		status.CertExpiry = &certExpiry
+	}
+	if !result.Up {
+		status.ResponseTime = 0
+		status.ConsecutiveFailures = previous.ConsecutiveFailures + 1
+	}
+
+	s.statuses[status.MonitorID] = status
+	return status
+}
+
+func (s *statusTracker) Get(monitorID string) (pkg.MonitorStatus, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status, ok := s.statuses[monitorID]
+	return status, ok
+}
+
+// List returns the statuses ordered by monitor ID.
+func (s *statusTracker) List() []pkg.MonitorStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]pkg.MonitorStatus, 0, len(s.statuses))
+	for _, status := range s.statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].MonitorID < statuses[j].MonitorID
+	})
+
+	return statuses
+}
+
+func (s *statusTracker) Remove(monitorID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.statuses, monitorID)
+}
@@ -0,0 +1,255 @@
+package recorder
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/tls_config"
+	"golang.org/x/net/proxy"
+)
+
+// ProxyDirect disables the default proxy for a single target.
+const ProxyDirect = "direct"
+
+// transportCache hands out one http.Transport per distinct TLS and proxy
+// setting so connections (and TLS sessions) are reused between checks of the
+// same target.
+type transportCache struct {
+	mu           sync.Mutex
+	transports   map[string]*http.Transport
+	httpClient   *http.Client
+	defaultProxy string
+}
+
+func newTransportCache(httpClient *http.Client, defaultProxy string) *transportCache {
+	return &transportCache{
+		transports:   make(map[string]*http.Transport),
+		httpClient:   httpClient,
+		defaultProxy: defaultProxy,
+	}
+}
+
+// proxyTiming collects when the connection to the proxy started and when the
+// tunnel to the target became usable.
+type proxyTiming struct {
+	mu    sync.Mutex
+	start time.Time
+	end   time.Time
+}
+
+type proxyTimingKey struct{}
+
+func (t *proxyTiming) markStart(at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.start.IsZero() {
+		t.start = at
+	}
+}
+
+func (t *proxyTiming) markEnd(at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if at.After(t.end) {
+		t.end = at
+	}
+}
+
+// Milliseconds returns the proxy connect time, zero when no new connection was made.
+func (t *proxyTiming) Milliseconds() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.start.IsZero() || t.end.Before(t.start) {
+		return 0
+	}
+	return t.end.Sub(t.start).Seconds() * 1000
+}
+
+// withProxyTiming attaches a timing collector and the httptrace hooks that fill it.
+func withProxyTiming(ctx context.Context) (context.Context, *proxyTiming) {
+	timing := &proxyTiming{}
+	ctx = context.WithValue(ctx, proxyTimingKey{}, timing)
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		ConnectStart: func(network, addr string) { timing.markStart(time.Now()) },
+		ConnectDone:  func(network, addr string, err error) { timing.markEnd(time.Now()) },
+	})
+	return ctx, timing
+}
+
+func proxyTimingFrom(ctx context.Context) *proxyTiming {
+	timing, _ := ctx.Value(proxyTimingKey{}).(*proxyTiming)
+	return timing
+}
+
+// resolveProxy returns the proxy URL for a target, nil when it must connect directly.
+func (c *transportCache) resolveProxy(target pkg.Target) (*url.URL, error) {
+	raw := target.Proxy
+	if raw == "" {
+		raw = c.defaultProxy
+	}
+	if raw == "" || raw == ProxyDirect {
+		return nil, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url: %v", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return u, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+}
+
+func transportCacheKey(config *pkg.TLSConfigDTO, proxyURL *url.URL) string {
+	h := sha256.New()
+	if config != nil {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00%t",
+			config.ID,
+			config.CACert,
+			config.ClientCert,
+			config.ClientKey,
+			config.ServerName,
+			config.MinVersion,
+			config.InsecureSkipVerify,
+		)
+	}
+	if proxyURL != nil {
+		fmt.Fprintf(h, "\x00proxy=%s", proxyURL.String())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func buildTLSConfig(config *pkg.TLSConfigDTO) (*tls.Config, error) {
+	minVersion, err := tls_config.TLSVersion(config.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         config.ServerName,
+		MinVersion:         minVersion,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if config.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(config.CACert)) {
+			return nil, fmt.Errorf("invalid CA bundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.ClientCert != "" {
+		cert, err := tls.X509KeyPair([]byte(config.ClientCert), []byte(config.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func applyProxy(transport *http.Transport, proxyURL *url.URL) error {
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		dialer, err := proxy.FromURL(proxyURL, &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating socks5 dialer: %v", err)
+		}
+		contextDialer, ok := dialer.(proxy.ContextDialer)
+		if !ok {
+			return fmt.Errorf("socks5 dialer does not support contexts")
+		}
+		transport.Proxy = nil
+		// The SOCKS handshake and the CONNECT to the target both happen
+		// inside the dial, so time the whole thing as proxy connect.
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			timing := proxyTimingFrom(ctx)
+			if timing != nil {
+				timing.markStart(time.Now())
+			}
+			conn, err := contextDialer.DialContext(ctx, network, addr)
+			if timing != nil {
+				timing.markEnd(time.Now())
+			}
+			return conn, err
+		}
+	default:
+		transport.Proxy = http.ProxyURL(proxyURL)
+		transport.OnProxyConnectResponse = func(ctx context.Context, _ *url.URL, _ *http.Request, _ *http.Response) error {
+			if timing := proxyTimingFrom(ctx); timing != nil {
+				timing.markEnd(time.Now())
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// clientFor returns a fresh client for target, sharing the cached transport,
+// and whether its requests go through a proxy.
+func (c *transportCache) clientFor(target pkg.Target) (*http.Client, bool, error) {
+	proxyURL, err := c.resolveProxy(target)
+	if err != nil {
+		return nil, false, fmt.Errorf("error resolving proxy for %s: %v", target.URL, err)
+	}
+
+	if target.TLS == nil && proxyURL == nil && target.Proxy == "" && c.defaultProxy == "" {
+		return &http.Client{
+			Transport: c.httpClient.Transport,
+			Timeout:   c.httpClient.Timeout,
+		}, false, nil
+	}
+
+	key := transportCacheKey(target.TLS, proxyURL)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	transport, ok := c.transports[key]
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+		// direct means direct, do not fall back to HTTP_PROXY from the environment
+		transport.Proxy = nil
+
+		if target.TLS != nil {
+			tlsConfig, err := buildTLSConfig(target.TLS)
+			if err != nil {
+				return nil, false, fmt.Errorf("error building tls config for %s: %v", target.URL, err)
+			}
+			transport.TLSClientConfig = tlsConfig
+		}
+
+		if proxyURL != nil {
+			if err := applyProxy(transport, proxyURL); err != nil {
+				return nil, false, fmt.Errorf("error configuring proxy for %s: %v", target.URL, err)
+			}
+		}
+
+		c.transports[key] = transport
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   c.httpClient.Timeout,
+	}, proxyURL != nil, nil
+}
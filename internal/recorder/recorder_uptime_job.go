@@ -3,6 +3,7 @@ package recorder
 import (
 	"context"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/afrianjunior/statx/internal/pkg"
@@ -14,10 +15,27 @@ type uptimeJob struct {
 	targets         []pkg.Target
 	httpClient      *http.Client
 	logger          *zap.SugaredLogger
+
+	mu       sync.Mutex
+	ctx      context.Context
+	checkers map[string]*checker
+}
+
+// checker is the goroutine checking one target. writing is held while a
+// result is written so Unschedule can wait for a write in progress.
+type checker struct {
+	cancel  context.CancelFunc
+	writing sync.Mutex
 }
 
 type UptimeJob interface {
 	Start(ctx context.Context)
+	// Schedule starts checking target, replacing the checker of a monitor
+	// with the same id.
+	Schedule(target pkg.Target)
+	// Unschedule stops the checker of a monitor. Once it returns no more
+	// results of the monitor are written; a check in flight is dropped.
+	Unschedule(monitorID string)
 }
 
 func NewUptimeJob(
@@ -31,29 +49,71 @@ func NewUptimeJob(
 		httpClient:      httpClient,
 		logger:          logger,
 		targets:         targets,
+		checkers:        make(map[string]*checker),
 	}
 }
 
 func (s *uptimeJob) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
 	for _, target := range s.targets {
-		go s.checkStatus(ctx, target)
+		s.Schedule(target)
+	}
+}
+
+func (s *uptimeJob) Schedule(target pkg.Target) {
+	s.Unschedule(target.MonitorID())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil {
+		s.targets = append(s.targets, target)
+		return
 	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	c := &checker{cancel: cancel}
+	s.checkers[target.MonitorID()] = c
+	go s.checkStatus(ctx, c, target)
 }
 
-func (s *uptimeJob) checkStatus(ctx context.Context, target pkg.Target) {
+func (s *uptimeJob) Unschedule(monitorID string) {
+	s.mu.Lock()
+	c, ok := s.checkers[monitorID]
+	delete(s.checkers, monitorID)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	c.cancel()
+	c.writing.Lock()
+	c.writing.Unlock()
+}
+
+func (s *uptimeJob) checkStatus(ctx context.Context, c *checker, target pkg.Target) {
 	for {
-		statusCode, responseTime, err := s.recorderService.CheckUptimeWithRetry(target)
+		result, err := s.recorderService.CheckUptimeWithRetry(target)
 		if err != nil {
-			s.logger.Errorf("Error checking %s: %v", target.URL, err)
-			if err := s.recorderService.WriteUpTimeRecord(ctx, target.URL, 0, 0); err != nil {
-				s.logger.Errorf("Error writing to TSDB: %v", err)
-			}
+			s.logger.Errorf("Error checking %s (%s): %v", target.URL, result.ErrorClass, err)
 		} else {
-			s.logger.Infof("Status for %s: %d", target.URL, statusCode)
-			if err := s.recorderService.WriteUpTimeRecord(ctx, target.URL, statusCode, responseTime); err != nil {
+			s.logger.Infof("Status for %s: %d (up=%t %s)", target.URL, result.StatusCode, result.Up, result.ErrorClass)
+		}
+
+		c.writing.Lock()
+		if ctx.Err() == nil {
+			if err := s.recorderService.WriteUpTimeRecord(ctx, target, result); err != nil {
 				s.logger.Errorf("Error writing to TSDB: %v", err)
 			}
 		}
-		time.Sleep(target.Interval)
+		c.writing.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(target.Interval):
+		}
 	}
 }
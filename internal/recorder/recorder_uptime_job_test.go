@@ -0,0 +1,122 @@
+package recorder
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+)
+
+// countingRecorder counts the checks and writes per monitor.
+type countingRecorder struct {
+	mu     sync.Mutex
+	checks map[string]int
+	writes map[string]int
+	// block, when set, holds every check until it is closed.
+	block chan struct{}
+}
+
+func newCountingRecorder() *countingRecorder {
+	return &countingRecorder{checks: make(map[string]int), writes: make(map[string]int)}
+}
+
+func (r *countingRecorder) CheckUptimeWithRetry(target pkg.Target) (pkg.CheckResult, error) {
+	r.mu.Lock()
+	r.checks[target.MonitorID()]++
+	block := r.block
+	r.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+	return pkg.CheckResult{Up: true, StatusCode: 200}, nil
+}
+
+func (r *countingRecorder) WriteUpTimeRecord(ctx context.Context, target pkg.Target, result pkg.CheckResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.writes[target.MonitorID()]++
+	return nil
+}
+
+func (r *countingRecorder) counts(id string) (checks, writes int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.checks[id], r.writes[id]
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestUptimeJobScheduleAndUnschedule(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	recorder := newCountingRecorder()
+	job := NewUptimeJob(recorder, nil, nil, testutil.Logger())
+	job.Start(ctx)
+
+	target := pkg.Target{ID: "a", URL: "http://a", Interval: 10 * time.Millisecond}
+	job.Schedule(target)
+	waitFor(t, "checks of a", func() bool { _, writes := recorder.counts("a"); return writes >= 3 })
+
+	job.Unschedule("a")
+	checks, writes := recorder.counts("a")
+	time.Sleep(50 * time.Millisecond)
+	if c, w := recorder.counts("a"); c > checks+1 || w != writes {
+		t.Fatalf("monitor kept being checked after Unschedule: checks %d -> %d, writes %d -> %d", checks, c, writes, w)
+	}
+}
+
+func TestUptimeJobScheduleReplacesChecker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	recorder := newCountingRecorder()
+	job := NewUptimeJob(recorder, nil, nil, testutil.Logger())
+	job.Start(ctx)
+
+	// A second Schedule of the same monitor with a long interval replaces
+	// the fast checker, so the checks stop after its first one.
+	job.Schedule(pkg.Target{ID: "a", URL: "http://a", Interval: 10 * time.Millisecond})
+	waitFor(t, "checks of a", func() bool { c, _ := recorder.counts("a"); return c >= 2 })
+	job.Schedule(pkg.Target{ID: "a", URL: "http://a", Interval: time.Hour})
+
+	time.Sleep(20 * time.Millisecond)
+	checks, _ := recorder.counts("a")
+	time.Sleep(50 * time.Millisecond)
+	if c, _ := recorder.counts("a"); c != checks {
+		t.Fatalf("replaced checker still runs: checks %d -> %d", checks, c)
+	}
+	job.Unschedule("a")
+}
+
+func TestUptimeJobDropsCheckInFlight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	recorder := newCountingRecorder()
+	recorder.block = make(chan struct{})
+	job := NewUptimeJob(recorder, nil, nil, testutil.Logger())
+	job.Start(ctx)
+
+	job.Schedule(pkg.Target{ID: "a", URL: "http://a", Interval: time.Hour})
+	waitFor(t, "check of a", func() bool { c, _ := recorder.counts("a"); return c == 1 })
+
+	job.Unschedule("a")
+	close(recorder.block)
+	time.Sleep(20 * time.Millisecond)
+	if _, writes := recorder.counts("a"); writes != 0 {
+		t.Fatalf("result of an unscheduled monitor was written")
+	}
+}
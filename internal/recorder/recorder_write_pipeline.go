@@ -0,0 +1,318 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWriteBatchSize     = 10000
+	defaultWriteBatchInterval = time.Second
+	// maxCachedRefs bounds the reference cache; renamed monitors and changed
+	// labels keep creating series, so the cache is simply reset when full.
+	maxCachedRefs = 200_000
+)
+
+var (
+	errNotSupported    = errors.New("not supported by the write pipeline")
+	errPipelineStopped = errors.New("write pipeline stopped")
+)
+
+type bufferedSample struct {
+	lset labels.Labels
+	t    int64
+	v    float64
+}
+
+// pendingBatch is the samples of one Commit; the pipeline sends the outcome
+// of storing them on result.
+type pendingBatch struct {
+	samples []bufferedSample
+	result  chan error
+}
+
+type cachedRef struct {
+	lset labels.Labels
+	ref  storage.SeriesRef
+}
+
+type writePipeline struct {
+	appendable storage.Appendable
+	logger     *zap.SugaredLogger
+	batchSize  int
+	interval   time.Duration
+
+	batches chan *pendingBatch
+	flushes chan chan struct{}
+	stopped chan struct{}
+
+	// refs and cachedRefs are only used by the run goroutine.
+	refs       map[uint64][]cachedRef
+	cachedRefs int
+
+	started atomic.Bool
+	samples atomic.Int64
+	commits atomic.Int64
+}
+
+// WritePipeline batches the samples of many checks into periodic commits.
+// Its appenders only buffer: Commit hands the samples over and waits while
+// the pipeline appends them with cached series references and commits once
+// per interval or batch size. Commit returns the append or commit error of
+// its own samples, or the error of its context when that ends before the
+// samples were handed over.
+type WritePipeline interface {
+	storage.Appendable
+	Start(ctx context.Context)
+	// Flush blocks until every sample committed before the call is stored.
+	// It returns at once when the pipeline was not started.
+	Flush()
+	// Wait blocks until the pipeline stopped after the context of Start
+	// ended and stored the samples still buffered.
+	Wait()
+	Stats() (samples, commits int64)
+}
+
+func NewWritePipeline(
+	appendable storage.Appendable,
+	config *pkg.Config,
+	logger *zap.SugaredLogger,
+) WritePipeline {
+	batchSize := config.WriteBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultWriteBatchSize
+	}
+	interval := config.WriteBatchInterval
+	if interval <= 0 {
+		interval = defaultWriteBatchInterval
+	}
+
+	return &writePipeline{
+		appendable: appendable,
+		logger:     logger,
+		batchSize:  batchSize,
+		interval:   interval,
+		batches:    make(chan *pendingBatch, 1024),
+		flushes:    make(chan chan struct{}),
+		stopped:    make(chan struct{}),
+		refs:       make(map[uint64][]cachedRef),
+	}
+}
+
+func (s *writePipeline) Appender(ctx context.Context) storage.Appender {
+	return &bufferedAppender{ctx: ctx, pipeline: s}
+}
+
+func (s *writePipeline) Start(ctx context.Context) {
+	s.started.Store(true)
+	go s.run(ctx)
+}
+
+func (s *writePipeline) Flush() {
+	if !s.started.Load() {
+		return
+	}
+	done := make(chan struct{})
+	select {
+	case s.flushes <- done:
+		<-done
+	case <-s.stopped:
+	}
+}
+
+func (s *writePipeline) Wait() {
+	<-s.stopped
+}
+
+func (s *writePipeline) Stats() (int64, int64) {
+	return s.samples.Load(), s.commits.Load()
+}
+
+func (s *writePipeline) run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	var pending []*pendingBatch
+	pendingSamples := 0
+	for {
+		select {
+		case batch := <-s.batches:
+			pending = append(pending, batch)
+			pendingSamples += len(batch.samples)
+			if pendingSamples >= s.batchSize {
+				pending = s.commit(pending)
+				pendingSamples = 0
+			}
+		case <-ticker.C:
+			pending = s.commit(pending)
+			pendingSamples = 0
+		case done := <-s.flushes:
+			pending = s.commit(s.drain(pending))
+			pendingSamples = 0
+			close(done)
+		case <-ctx.Done():
+			s.commit(s.drain(pending))
+			close(s.stopped)
+			return
+		}
+	}
+}
+
+// drain takes every batch that is already queued.
+func (s *writePipeline) drain(pending []*pendingBatch) []*pendingBatch {
+	for {
+		select {
+		case batch := <-s.batches:
+			pending = append(pending, batch)
+		default:
+			return pending
+		}
+	}
+}
+
+// commit appends the pending batches in one transaction, reports the
+// outcome to each of them and returns the emptied buffer for reuse. A
+// batch with a sample the TSDB rejects fails without holding back the
+// others.
+func (s *writePipeline) commit(pending []*pendingBatch) []*pendingBatch {
+	if len(pending) == 0 {
+		return pending
+	}
+
+	appender := s.appendable.Appender(context.Background())
+	appended := 0
+	errs := make([]error, len(pending))
+	for i, batch := range pending {
+		for _, sample := range batch.samples {
+			hash := sample.lset.Hash()
+			ref, err := appender.Append(s.ref(hash, sample.lset), sample.lset, sample.t, sample.v)
+			if err != nil {
+				if errs[i] == nil {
+					errs[i] = fmt.Errorf("error appending sample of %s: %w", sample.lset, err)
+				}
+				continue
+			}
+			s.storeRef(hash, sample.lset, ref)
+			appended++
+		}
+	}
+
+	if err := appender.Commit(); err != nil {
+		err = fmt.Errorf("error committing samples: %w", err)
+		for i := range errs {
+			errs[i] = err
+		}
+	} else {
+		s.samples.Add(int64(appended))
+		s.commits.Add(1)
+	}
+
+	for i, batch := range pending {
+		batch.result <- errs[i]
+	}
+
+	clear(pending)
+	return pending[:0]
+}
+
+func (s *writePipeline) ref(hash uint64, lset labels.Labels) storage.SeriesRef {
+	for _, cached := range s.refs[hash] {
+		if labels.Equal(cached.lset, lset) {
+			return cached.ref
+		}
+	}
+	return 0
+}
+
+func (s *writePipeline) storeRef(hash uint64, lset labels.Labels, ref storage.SeriesRef) {
+	if ref == 0 {
+		return
+	}
+	cached := s.refs[hash]
+	for i := range cached {
+		if labels.Equal(cached[i].lset, lset) {
+			cached[i].ref = ref
+			return
+		}
+	}
+	if s.cachedRefs >= maxCachedRefs {
+		clear(s.refs)
+		s.cachedRefs = 0
+	}
+	s.refs[hash] = append(s.refs[hash], cachedRef{lset, ref})
+	s.cachedRefs++
+}
+
+// bufferedAppender collects the samples of one writer until Commit.
+type bufferedAppender struct {
+	ctx      context.Context
+	pipeline *writePipeline
+	samples  []bufferedSample
+}
+
+func (a *bufferedAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	a.samples = append(a.samples, bufferedSample{l, t, v})
+	return 0, nil
+}
+
+func (a *bufferedAppender) Commit() error {
+	if len(a.samples) == 0 {
+		return nil
+	}
+	batch := &pendingBatch{samples: a.samples, result: make(chan error, 1)}
+	a.samples = nil
+
+	select {
+	case a.pipeline.batches <- batch:
+	case <-a.ctx.Done():
+		return a.ctx.Err()
+	case <-a.pipeline.stopped:
+		return errPipelineStopped
+	}
+
+	// Once handed over the samples are stored even when ctx ends, so only
+	// a stopped pipeline ends the wait; it answers every batch it took.
+	select {
+	case err := <-batch.result:
+		return err
+	case <-a.pipeline.stopped:
+		select {
+		case err := <-batch.result:
+			return err
+		default:
+			return errPipelineStopped
+		}
+	}
+}
+
+func (a *bufferedAppender) Rollback() error {
+	a.samples = nil
+	return nil
+}
+
+func (a *bufferedAppender) AppendExemplar(storage.SeriesRef, labels.Labels, exemplar.Exemplar) (storage.SeriesRef, error) {
+	return 0, errNotSupported
+}
+
+func (a *bufferedAppender) AppendHistogram(storage.SeriesRef, labels.Labels, int64, *histogram.Histogram, *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	return 0, errNotSupported
+}
+
+func (a *bufferedAppender) UpdateMetadata(storage.SeriesRef, labels.Labels, metadata.Metadata) (storage.SeriesRef, error) {
+	return 0, errNotSupported
+}
+
+func (a *bufferedAppender) AppendCTZeroSample(storage.SeriesRef, labels.Labels, int64, int64) (storage.SeriesRef, error) {
+	return 0, errNotSupported
+}
@@ -0,0 +1,183 @@
+package recorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func TestWritePipelineCommitReportsOwnErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	config := testutil.Config(t)
+	storage := testutil.TSDB(t, config)
+	pipeline := NewWritePipeline(storage, config, testutil.Logger())
+	pipeline.Start(ctx)
+
+	now := time.Now().UnixMilli()
+	badLabels := labels.FromStrings(labels.MetricName, "up", "monitor_id", "bad")
+	existing := storage.Appender(ctx)
+	existing.Append(0, badLabels, now, 1)
+	if err := existing.Commit(); err != nil {
+		t.Fatalf("committing: %v", err)
+	}
+
+	good := pipeline.Appender(ctx)
+	good.Append(0, labels.FromStrings(labels.MetricName, "up", "monitor_id", "good"), now, 1)
+	bad := pipeline.Appender(ctx)
+	// a different value for a timestamp already stored is rejected
+	bad.Append(0, badLabels, now, 0)
+
+	errs := make(chan error, 2)
+	go func() { errs <- good.Commit() }()
+	go func() { errs <- bad.Commit() }()
+
+	failed := 0
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Fatalf("%d commits failed, want only the one with the rejected sample", failed)
+	}
+	if got := countSamples(t, storage, "good"); got != 1 {
+		t.Fatalf("good sample stored %d times, want 1", got)
+	}
+}
+
+func TestWritePipelineCommitEndsWithContext(t *testing.T) {
+	config := testutil.Config(t)
+	pipeline := NewWritePipeline(testutil.TSDB(t, config), config, testutil.Logger()).(*writePipeline)
+	// not started, so nothing drains the buffer
+	for len(pipeline.batches) < cap(pipeline.batches) {
+		pipeline.batches <- &pendingBatch{result: make(chan error, 1)}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	appender := pipeline.Appender(ctx)
+	appender.Append(0, labels.FromStrings(labels.MetricName, "up"), time.Now().UnixMilli(), 1)
+
+	done := make(chan error, 1)
+	go func() { done <- appender.Commit() }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Commit returned %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Commit blocked on a full buffer after its context ended")
+	}
+}
+
+func TestWritePipelineWaitStoresBufferedSamples(t *testing.T) {
+	config := testutil.Config(t)
+	config.WriteBatchInterval = time.Hour
+	storage := testutil.TSDB(t, config)
+
+	pipeline := NewWritePipeline(storage, config, testutil.Logger())
+
+	appender := pipeline.Appender(context.Background())
+	appender.Append(0, labels.FromStrings(labels.MetricName, "up", "monitor_id", "a"), time.Now().UnixMilli(), 1)
+	done := make(chan error, 1)
+	go func() { done <- appender.Commit() }()
+	waitFor(t, "batch to be queued", func() bool { return len(pipeline.(*writePipeline).batches) == 1 })
+
+	// the hour long interval never passes, only the shutdown stores it
+	ctx, cancel := context.WithCancel(context.Background())
+	pipeline.Start(ctx)
+	cancel()
+	pipeline.Wait()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if got := countSamples(t, storage, "a"); got != 1 {
+		t.Fatalf("stored %d samples on shutdown, want 1", got)
+	}
+}
+
+// BenchmarkWritePipeline writes the checks of 10k monitors, each from its own
+// goroutine like the uptime job does, straight to the TSDB and through the
+// pipeline.
+func BenchmarkWritePipeline(b *testing.B) {
+	const monitors = 10000
+
+	targets := make([]pkg.Target, monitors)
+	for i := range targets {
+		targets[i] = pkg.Target{
+			ID:     fmt.Sprintf("bench-%05d", i),
+			Name:   fmt.Sprintf("bench %d", i),
+			Type:   "uptime",
+			URL:    fmt.Sprintf("https://bench-%d.example.com/health", i),
+			Labels: map[string]string{"team": fmt.Sprintf("team-%d", i%20)},
+		}
+	}
+
+	for _, mode := range []string{"direct", "pipeline"} {
+		b.Run(mode, func(b *testing.B) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			config := testutil.Config(b)
+			db := testutil.TSDB(b, config)
+			var appendable storage.Appendable = db
+			if mode == "pipeline" {
+				pipeline := NewWritePipeline(db, config, testutil.Logger())
+				pipeline.Start(ctx)
+				appendable = pipeline
+			}
+			recorder := NewRecorderService(appendable, nil, config, nil, testutil.Logger(), nil, NewStatusTracker(), NewBroadcaster())
+
+			base := time.Now().Add(-time.Hour)
+			var next atomic.Int64
+			b.SetParallelism(monitors / runtime.GOMAXPROCS(0))
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					i := next.Add(1)
+					result := pkg.CheckResult{
+						CheckedAt:    base.Add(time.Duration(i) * time.Millisecond),
+						StatusCode:   200,
+						ResponseTime: 42,
+						Up:           true,
+						State:        pkg.StateUp,
+					}
+					if err := recorder.WriteUpTimeRecord(ctx, targets[i%monitors], result); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestWritePipelineFlushWithoutStartReturns(t *testing.T) {
+	config := testutil.Config(t)
+	pipeline := NewWritePipeline(testutil.TSDB(t, config), config, testutil.Logger())
+
+	flushed := make(chan struct{})
+	go func() {
+		pipeline.Flush()
+		close(flushed)
+	}()
+
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Fatal("Flush blocked on a pipeline that was never started")
+	}
+}
@@ -0,0 +1,67 @@
+package remote_write
+
+import (
+	"context"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage"
+)
+
+type teeAppendable struct {
+	storage.Appendable
+	exporter Exporter
+}
+
+// NewAppendable wraps an appendable so that every sample committed through
+// it is also handed to the exporter. Samples of rolled back appenders are
+// never exported.
+func NewAppendable(appendable storage.Appendable, exporter Exporter) storage.Appendable {
+	return &teeAppendable{appendable, exporter}
+}
+
+func (s *teeAppendable) Appender(ctx context.Context) storage.Appender {
+	return &teeAppender{
+		Appender: s.Appendable.Appender(ctx),
+		exporter: s.exporter,
+	}
+}
+
+type teeAppender struct {
+	storage.Appender
+	exporter Exporter
+	series   []prompb.TimeSeries
+}
+
+func (a *teeAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	ref, err := a.Appender.Append(ref, l, t, v)
+	if err != nil {
+		return ref, err
+	}
+
+	a.series = append(a.series, prompb.TimeSeries{
+		Labels:  prompb.FromLabels(l, nil),
+		Samples: []prompb.Sample{{Value: v, Timestamp: t}},
+	})
+
+	return ref, nil
+}
+
+func (a *teeAppender) Commit() error {
+	if err := a.Appender.Commit(); err != nil {
+		a.series = nil
+		return err
+	}
+
+	if len(a.series) > 0 {
+		a.exporter.Enqueue(a.series)
+		a.series = nil
+	}
+
+	return nil
+}
+
+func (a *teeAppender) Rollback() error {
+	a.series = nil
+	return a.Appender.Rollback()
+}
@@ -0,0 +1,348 @@
+package remote_write
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBatchSize     = 1000
+	defaultFlushInterval = 5 * time.Second
+	defaultTimeout       = 30 * time.Second
+	defaultMinBackoff    = 30 * time.Millisecond
+	defaultMaxBackoff    = 5 * time.Second
+
+	maxSegmentSize = 16 << 20
+)
+
+type exporter struct {
+	endpoints []*endpoint
+}
+
+// Exporter ships appended samples to the configured remote_write endpoints.
+// Samples are batched in memory, persisted to a per endpoint queue on disk
+// and sent as snappy compressed protobuf, retrying with backoff.
+type Exporter interface {
+	Start(ctx context.Context)
+	Enqueue(series []prompb.TimeSeries)
+	// Close waits for the exporter to stop once the context of Start ends,
+	// queues the samples still pending so they are sent after a restart and
+	// closes the queues. Samples enqueued afterwards are dropped.
+	Close()
+}
+
+// NewExporter opens a queue under <storage>/remote_write for every endpoint.
+// httpClient may be nil, each endpoint then gets a client with its timeout.
+func NewExporter(
+	config *pkg.Config,
+	httpClient *http.Client,
+	logger *zap.SugaredLogger,
+) (Exporter, error) {
+	var endpoints []*endpoint
+	for _, endpointConfig := range config.RemoteWrite {
+		endpointConfig = withDefaults(endpointConfig)
+
+		queue, err := openWALQueue(filepath.Join(config.StoragePath, "remote_write", queueName(endpointConfig)), maxSegmentSize)
+		if err != nil {
+			return nil, fmt.Errorf("remote write %s: %v", endpointConfig.URL, err)
+		}
+
+		client := httpClient
+		if client == nil {
+			client = &http.Client{Timeout: endpointConfig.Timeout}
+		}
+
+		endpoints = append(endpoints, &endpoint{
+			config: endpointConfig,
+			client: client,
+			queue:  queue,
+			logger: logger.With("remote_write", endpointConfig.URL),
+			notify: make(chan struct{}, 1),
+		})
+	}
+
+	return &exporter{endpoints}, nil
+}
+
+func withDefaults(config pkg.RemoteWriteConfig) pkg.RemoteWriteConfig {
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultFlushInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaultMinBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+	return config
+}
+
+func queueName(config pkg.RemoteWriteConfig) string {
+	if config.Name != "" {
+		return config.Name
+	}
+	sum := sha1.Sum([]byte(config.URL))
+	return hex.EncodeToString(sum[:6])
+}
+
+func (s *exporter) Start(ctx context.Context) {
+	for _, endpoint := range s.endpoints {
+		endpoint.loops.Add(2)
+		go func() {
+			defer endpoint.loops.Done()
+			endpoint.flushLoop(ctx)
+		}()
+		go func() {
+			defer endpoint.loops.Done()
+			endpoint.sendLoop(ctx)
+		}()
+	}
+}
+
+func (s *exporter) Close() {
+	for _, endpoint := range s.endpoints {
+		endpoint.close()
+	}
+}
+
+func (s *exporter) Enqueue(series []prompb.TimeSeries) {
+	for _, endpoint := range s.endpoints {
+		endpoint.enqueue(series)
+	}
+}
+
+type endpoint struct {
+	config pkg.RemoteWriteConfig
+	client *http.Client
+	queue  *walQueue
+	logger *zap.SugaredLogger
+	notify chan struct{}
+	loops  sync.WaitGroup
+
+	mu             sync.Mutex
+	pending        []prompb.TimeSeries
+	pendingSamples int
+	closed         bool
+}
+
+func (e *endpoint) enqueue(series []prompb.TimeSeries) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		e.logger.Warnf("Remote write is closed, dropping %d series", len(series))
+		return
+	}
+
+	e.pending = append(e.pending, series...)
+	for _, ts := range series {
+		e.pendingSamples += len(ts.Samples)
+	}
+
+	if e.pendingSamples >= e.config.BatchSize {
+		e.flushLocked()
+	}
+}
+
+// flushLocked moves the pending samples into the on-disk queue as one
+// encoded write request and wakes up the sender.
+func (e *endpoint) flushLocked() {
+	if len(e.pending) == 0 {
+		return
+	}
+
+	request := prompb.WriteRequest{Timeseries: e.pending}
+	e.pending = nil
+	e.pendingSamples = 0
+
+	data, err := request.Marshal()
+	if err != nil {
+		e.logger.Errorf("Error encoding write request, dropping %d series: %v", len(request.Timeseries), err)
+		return
+	}
+
+	if err := e.queue.Append(snappy.Encode(nil, data)); err != nil {
+		e.logger.Errorf("Error queueing write request, dropping %d series: %v", len(request.Timeseries), err)
+		return
+	}
+
+	select {
+	case e.notify <- struct{}{}:
+	default:
+	}
+}
+
+// close closes the queue only once both loops are done, so no batch is
+// appended to or read from a closed queue.
+func (e *endpoint) close() {
+	e.loops.Wait()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	e.flushLocked()
+	e.closed = true
+
+	if err := e.queue.Close(); err != nil {
+		e.logger.Errorf("Error closing queue: %v", err)
+	}
+}
+
+func (e *endpoint) flushLoop(ctx context.Context) {
+	ticker := time.NewTicker(e.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// the last batch is queued by close
+			return
+		case <-ticker.C:
+			e.mu.Lock()
+			e.flushLocked()
+			e.mu.Unlock()
+		}
+	}
+}
+
+func (e *endpoint) sendLoop(ctx context.Context) {
+	for {
+		payload, ok, err := e.queue.Peek()
+		if err != nil {
+			e.logger.Errorf("Error reading queue: %v", err)
+		}
+		if err != nil || !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-e.notify:
+			case <-time.After(e.config.FlushInterval):
+			}
+			continue
+		}
+
+		if err := e.sendWithRetry(ctx, payload); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			e.logger.Errorf("Dropping write request: %v", err)
+		}
+
+		if err := e.queue.Ack(); err != nil {
+			e.logger.Errorf("Error advancing queue: %v", err)
+		}
+	}
+}
+
+// sendWithRetry retries network errors, 5xx and 429 responses with
+// exponential backoff until the request goes through or the context ends.
+// Other errors are returned right away since resending cannot fix them.
+func (e *endpoint) sendWithRetry(ctx context.Context, payload []byte) error {
+	backoff := e.config.MinBackoff
+	for {
+		err := e.send(ctx, payload)
+		if err == nil {
+			return nil
+		}
+
+		var recoverable *recoverableError
+		if !errors.As(err, &recoverable) {
+			return err
+		}
+
+		wait := backoff
+		if recoverable.retryAfter > 0 {
+			wait = recoverable.retryAfter
+		}
+		e.logger.Warnf("Error sending write request, retrying in %s: %v", wait, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		backoff = min(backoff*2, e.config.MaxBackoff)
+	}
+}
+
+type recoverableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *recoverableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *endpoint) send(ctx context.Context, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "statx")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	for name, value := range e.config.Headers {
+		req.Header.Set(name, value)
+	}
+	if e.config.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+e.config.BearerToken)
+	} else if e.config.BasicAuthUsername != "" {
+		req.SetBasicAuth(e.config.BasicAuthUsername, e.config.BasicAuthPassword)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return &recoverableError{err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("server returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return &recoverableError{err: err, retryAfter: retryAfter(resp.Header.Get("Retry-After"))}
+	}
+
+	return err
+}
+
+func retryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,176 @@
+package remote_write
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/recorder"
+	"github.com/afrianjunior/statx/internal/testutil"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// receiver records the series of the write requests it is sent.
+type receiver struct {
+	mu      sync.Mutex
+	samples map[string]int
+	errs    []string
+}
+
+func newReceiver(t *testing.T) (*receiver, *httptest.Server) {
+	r := &receiver{samples: make(map[string]int)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if req.Header.Get("Content-Encoding") != "snappy" || req.Header.Get("Content-Type") != "application/x-protobuf" {
+			r.errs = append(r.errs, "unexpected headers")
+		}
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			r.errs = append(r.errs, "missing bearer token")
+		}
+
+		compressed, _ := io.ReadAll(req.Body)
+		data, err := snappy.Decode(nil, compressed)
+		if err != nil {
+			r.errs = append(r.errs, "decoding snappy: "+err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var request prompb.WriteRequest
+		if err := request.Unmarshal(data); err != nil {
+			r.errs = append(r.errs, "decoding protobuf: "+err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, series := range request.Timeseries {
+			for _, label := range series.Labels {
+				if label.Name == labels.MetricName {
+					r.samples[label.Value] += len(series.Samples)
+				}
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return r, server
+}
+
+func (r *receiver) count(metric string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.samples[metric]
+}
+
+func TestExporterShipsChecksAndRollups(t *testing.T) {
+	received, server := newReceiver(t)
+
+	config := testutil.Config(t)
+	config.RemoteWrite = []pkg.RemoteWriteConfig{{
+		URL:           server.URL,
+		BearerToken:   "secret",
+		FlushInterval: 10 * time.Millisecond,
+	}}
+	storage := testutil.TSDB(t, config)
+
+	exporter, err := NewExporter(config, nil, testutil.Logger())
+	if err != nil {
+		t.Fatalf("creating exporter: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	exporter.Start(ctx)
+	defer func() {
+		cancel()
+		exporter.Close()
+	}()
+	appendable := NewAppendable(storage, exporter)
+
+	now := time.Now()
+	up := labels.FromStrings(labels.MetricName, "up", "monitor_id", "a", "url", "http://a")
+	appender := appendable.Appender(ctx)
+	for i := 0; i < 3; i++ {
+		if _, err := appender.Append(0, up, now.Add(-time.Duration(i+2)*time.Minute).UnixMilli(), 1); err != nil {
+			t.Fatalf("appending: %v", err)
+		}
+	}
+	if err := appender.Commit(); err != nil {
+		t.Fatalf("committing: %v", err)
+	}
+
+	// The rollup job writes through the same tee as the checks.
+	rollupJob := recorder.NewRollupJob(storage, appendable, testutil.DB(t), config, testutil.Logger())
+	if err := rollupJob.RunOnce(ctx, now.Add(10*time.Minute)); err != nil {
+		t.Fatalf("writing rollups: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for received.count("up") < 3 || received.count(pkg.RollupSampleCount) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("receiver got up=%d %s=%d", received.count("up"), pkg.RollupSampleCount, received.count(pkg.RollupSampleCount))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	received.mu.Lock()
+	defer received.mu.Unlock()
+	if len(received.errs) > 0 {
+		t.Fatalf("bad write requests: %v", received.errs)
+	}
+}
+
+func TestExporterCloseQueuesPendingSamples(t *testing.T) {
+	config := testutil.Config(t)
+	config.RemoteWrite = []pkg.RemoteWriteConfig{{
+		Name:          "unreachable",
+		URL:           "http://127.0.0.1:1",
+		BatchSize:     1000,
+		FlushInterval: time.Hour,
+	}}
+
+	exporter, err := NewExporter(config, nil, testutil.Logger())
+	if err != nil {
+		t.Fatalf("creating exporter: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	exporter.Start(ctx)
+
+	exporter.Enqueue([]prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: labels.MetricName, Value: "up"}},
+		Samples: []prompb.Sample{{Value: 1, Timestamp: 1}},
+	}})
+	cancel()
+	exporter.Close()
+
+	// Enqueue after Close must not write to the closed queue.
+	exporter.Enqueue([]prompb.TimeSeries{{Samples: []prompb.Sample{{Value: 1, Timestamp: 2}}}})
+
+	queue, err := openWALQueue(filepath.Join(config.StoragePath, "remote_write", "unreachable"), maxSegmentSize)
+	if err != nil {
+		t.Fatalf("reopening queue: %v", err)
+	}
+	defer queue.Close()
+
+	payload, ok, err := queue.Peek()
+	if err != nil || !ok {
+		t.Fatalf("pending batch was not queued on close: ok=%t err=%v", ok, err)
+	}
+	data, err := snappy.Decode(nil, payload)
+	if err != nil {
+		t.Fatalf("decoding payload: %v", err)
+	}
+	var request prompb.WriteRequest
+	if err := request.Unmarshal(data); err != nil {
+		t.Fatalf("decoding write request: %v", err)
+	}
+	if len(request.Timeseries) != 1 || request.Timeseries[0].Samples[0].Timestamp != 1 {
+		t.Fatalf("queued request = %v", request.Timeseries)
+	}
+}
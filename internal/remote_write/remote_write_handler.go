@@ -0,0 +1,99 @@
+package remote_write
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	maxCompressedSize   = 32 << 20
+	maxDecompressedSize = 128 << 20
+)
+
+// WriteHandler accepts snappy compressed remote_write requests. The optional
+// namespace and monitor_id query parameters choose the namespace label and
+// the generic monitor the series belong to.
+func WriteHandler(receiverSvc ReceiverService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, err := decodeWriteRequest(r)
+		if err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, http.StatusBadRequest)
+			return
+		}
+
+		query := r.URL.Query()
+		result, err := receiverSvc.Write(r.Context(), request, query.Get("namespace"), query.Get("monitor_id"))
+		if err != nil {
+			code := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, ErrMonitorNotFound):
+				code = http.StatusNotFound
+			case errors.Is(err, ErrInvalidNamespace), errors.Is(err, ErrInvalidSeries), errors.Is(err, ErrNotGeneric):
+				code = http.StatusBadRequest
+			}
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, code)
+			return
+		}
+
+		// Like Prometheus, rejected samples are reported as a client error
+		// so the sender does not retry them; the rest was stored.
+		if result.Skipped > 0 {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: fmt.Sprintf("skipped %d samples: %v", result.Skipped, result.LastError),
+				Data:    result,
+			}, http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func decodeWriteRequest(r *http.Request) (*prompb.WriteRequest, error) {
+	if encoding := r.Header.Get("Content-Encoding"); encoding != "" && encoding != "snappy" {
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	compressed, err := io.ReadAll(io.LimitReader(r.Body, maxCompressedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading body: %v", err)
+	}
+	if len(compressed) > maxCompressedSize {
+		return nil, errors.New("request body too large")
+	}
+
+	size, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding body: %v", err)
+	}
+	if size > maxDecompressedSize {
+		return nil, errors.New("decompressed request too large")
+	}
+
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding body: %v", err)
+	}
+
+	var request prompb.WriteRequest
+	if err := request.Unmarshal(data); err != nil {
+		return nil, fmt.Errorf("error decoding write request: %v", err)
+	}
+
+	return &request, nil
+}
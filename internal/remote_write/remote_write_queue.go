@@ -0,0 +1,301 @@
+package remote_write
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const (
+	recordHeaderSize = 8
+	maxRecordSize    = 64 << 20
+	cursorFile       = "cursor"
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type cursor struct {
+	Segment int   `json:"segment"`
+	Offset  int64 `json:"offset"`
+}
+
+// walQueue is an on-disk FIFO of encoded write requests. Records are
+// appended to numbered segment files as [length][crc32][payload]; a cursor
+// file remembers how far the sender got, so batches survive restarts and
+// are delivered at least once. Fully sent segments are deleted.
+type walQueue struct {
+	dir            string
+	maxSegmentSize int64
+
+	mu           sync.Mutex
+	writer       *os.File
+	writeSegment int
+	writeOffset  int64
+	read         cursor
+	peekedSize   int64
+}
+
+func openWALQueue(dir string, maxSegmentSize int64) (*walQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating queue directory: %v", err)
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &walQueue{dir: dir, maxSegmentSize: maxSegmentSize}
+
+	data, err := os.ReadFile(filepath.Join(dir, cursorFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if len(segments) > 0 {
+			q.read.Segment = segments[0]
+		}
+	case err != nil:
+		return nil, fmt.Errorf("error reading queue cursor: %v", err)
+	default:
+		if err := json.Unmarshal(data, &q.read); err != nil {
+			return nil, fmt.Errorf("error decoding queue cursor: %v", err)
+		}
+	}
+
+	for _, segment := range segments {
+		if segment < q.read.Segment {
+			os.Remove(q.segmentPath(segment))
+		}
+	}
+
+	q.writeSegment = q.read.Segment
+	if len(segments) > 0 && segments[len(segments)-1] > q.writeSegment {
+		q.writeSegment = segments[len(segments)-1]
+	}
+
+	// A crash can leave a partially written record at the end of the last
+	// segment; cut it off so new records start on a clean boundary.
+	q.writeOffset, err = validLength(q.segmentPath(q.writeSegment))
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Truncate(q.segmentPath(q.writeSegment), q.writeOffset); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error repairing queue segment: %v", err)
+	}
+	if q.read.Segment == q.writeSegment && q.read.Offset > q.writeOffset {
+		q.read.Offset = q.writeOffset
+	}
+
+	q.writer, err = os.OpenFile(q.segmentPath(q.writeSegment), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening queue segment: %v", err)
+	}
+
+	return q, nil
+}
+
+func (q *walQueue) segmentPath(segment int) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%08d", segment))
+}
+
+func listSegments(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error listing queue segments: %v", err)
+	}
+
+	var segments []int
+	for _, entry := range entries {
+		segment, err := strconv.Atoi(entry.Name())
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	sort.Ints(segments)
+
+	return segments, nil
+}
+
+// validLength returns the size of the segment up to its last intact record.
+func validLength(path string) (int64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error opening queue segment: %v", err)
+	}
+	defer f.Close()
+
+	var offset int64
+	for {
+		payload, err := readRecord(f, offset)
+		if err != nil {
+			return offset, nil
+		}
+		offset += recordHeaderSize + int64(len(payload))
+	}
+}
+
+func readRecord(f io.ReaderAt, offset int64) ([]byte, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := f.ReadAt(header, offset); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:4])
+	if size > maxRecordSize {
+		return nil, errors.New("record too large")
+	}
+
+	payload := make([]byte, size)
+	if _, err := f.ReadAt(payload, offset+recordHeaderSize); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(payload, castagnoli) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, errors.New("record checksum mismatch")
+	}
+
+	return payload, nil
+}
+
+// Append durably stores one record at the end of the queue.
+func (q *walQueue) Append(payload []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	size := recordHeaderSize + int64(len(payload))
+	if q.writeOffset > 0 && q.writeOffset+size > q.maxSegmentSize {
+		if err := q.rotate(); err != nil {
+			return err
+		}
+	}
+
+	record := make([]byte, size)
+	binary.BigEndian.PutUint32(record[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload, castagnoli))
+	copy(record[recordHeaderSize:], payload)
+
+	if _, err := q.writer.Write(record); err != nil {
+		return fmt.Errorf("error writing queue record: %v", err)
+	}
+	if err := q.writer.Sync(); err != nil {
+		return fmt.Errorf("error syncing queue segment: %v", err)
+	}
+	q.writeOffset += size
+
+	return nil
+}
+
+func (q *walQueue) rotate() error {
+	if err := q.writer.Close(); err != nil {
+		return fmt.Errorf("error closing queue segment: %v", err)
+	}
+
+	writer, err := os.OpenFile(q.segmentPath(q.writeSegment+1), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error creating queue segment: %v", err)
+	}
+
+	q.writer = writer
+	q.writeSegment++
+	q.writeOffset = 0
+
+	return nil
+}
+
+// Peek returns the oldest record that has not been acknowledged yet, or
+// false when the queue is empty.
+func (q *walQueue) Peek() ([]byte, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if q.read.Segment == q.writeSegment && q.read.Offset >= q.writeOffset {
+			return nil, false, nil
+		}
+
+		if q.read.Segment < q.writeSegment {
+			info, err := os.Stat(q.segmentPath(q.read.Segment))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, false, fmt.Errorf("error reading queue segment: %v", err)
+			}
+			if err != nil || q.read.Offset >= info.Size() {
+				if err := q.nextSegment(); err != nil {
+					return nil, false, err
+				}
+				continue
+			}
+		}
+
+		f, err := os.Open(q.segmentPath(q.read.Segment))
+		if err != nil {
+			return nil, false, fmt.Errorf("error opening queue segment: %v", err)
+		}
+		payload, err := readRecord(f, q.read.Offset)
+		f.Close()
+		if err != nil {
+			// Only a sealed segment can end in garbage; skip the rest of it.
+			if q.read.Segment < q.writeSegment {
+				if err := q.nextSegment(); err != nil {
+					return nil, false, err
+				}
+				continue
+			}
+			return nil, false, fmt.Errorf("error reading queue record: %v", err)
+		}
+
+		q.peekedSize = recordHeaderSize + int64(len(payload))
+		return payload, true, nil
+	}
+}
+
+// Ack drops the record returned by the last Peek.
+func (q *walQueue) Ack() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.read.Offset += q.peekedSize
+	q.peekedSize = 0
+
+	return q.saveCursor()
+}
+
+func (q *walQueue) nextSegment() error {
+	os.Remove(q.segmentPath(q.read.Segment))
+	q.read.Segment++
+	q.read.Offset = 0
+	return q.saveCursor()
+}
+
+func (q *walQueue) saveCursor() error {
+	data, err := json.Marshal(q.read)
+	if err != nil {
+		return fmt.Errorf("error encoding queue cursor: %v", err)
+	}
+
+	tmp := filepath.Join(q.dir, cursorFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("error writing queue cursor: %v", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(q.dir, cursorFile)); err != nil {
+		return fmt.Errorf("error writing queue cursor: %v", err)
+	}
+
+	return nil
+}
+
+func (q *walQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.writer.Close()
+}
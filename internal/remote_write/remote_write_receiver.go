@@ -0,0 +1,212 @@
+package remote_write
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/afrianjunior/statx/internal/config_monitor"
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage"
+)
+
+const (
+	defaultNamespace = "external"
+	genericType      = "generic"
+)
+
+var (
+	ErrInvalidNamespace = errors.New("invalid namespace")
+	ErrMonitorNotFound  = errors.New("monitor not found")
+	ErrNotGeneric       = errors.New("monitor is not of type generic")
+	ErrInvalidSeries    = errors.New("invalid series")
+
+	namespacePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+)
+
+// receivedLabels are set by the receiver; incoming series carrying them
+// keep their value as exported_<name>.
+var receivedLabels = map[string]bool{
+	pkg.NamespaceLabel: true,
+	"monitor_id":       true,
+	"type":             true,
+	"name":             true,
+}
+
+type receiverService struct {
+	appendable              storage.Appendable
+	configMonitorRepository config_monitor.ConfigMonitorRepository
+	config                  *pkg.Config
+}
+
+type ReceiverService interface {
+	Write(ctx context.Context, request *prompb.WriteRequest, namespace, monitorID string) (*WriteResult, error)
+}
+
+// WriteResult counts what was stored. Samples the TSDB rejects, for example
+// because they are out of order, are skipped without failing the request.
+type WriteResult struct {
+	Samples    int   `json:"samples"`
+	Histograms int   `json:"histograms"`
+	Skipped    int   `json:"skipped"`
+	LastError  error `json:"-"`
+}
+
+func NewReceiverService(
+	appendable storage.Appendable,
+	configMonitorRepository config_monitor.ConfigMonitorRepository,
+	config *pkg.Config,
+) ReceiverService {
+	return &receiverService{
+		appendable,
+		configMonitorRepository,
+		config,
+	}
+}
+
+// Write appends a remote_write request to the TSDB. Every series gets a
+// namespace label; when monitorID is set the series are also labeled with
+// the monitor_id, type and name of that generic monitor.
+func (s *receiverService) Write(ctx context.Context, request *prompb.WriteRequest, namespace, monitorID string) (*WriteResult, error) {
+	if namespace == "" {
+		namespace = s.config.RemoteWriteReceiver.Namespace
+	}
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	if !namespacePattern.MatchString(namespace) {
+		return nil, ErrInvalidNamespace
+	}
+
+	extra := []string{pkg.NamespaceLabel, namespace}
+	if monitorID != "" {
+		monitor, err := s.configMonitorRepository.GetByID(ctx, monitorID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMonitorNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if monitor.Type != genericType {
+			return nil, ErrNotGeneric
+		}
+		extra = append(extra, "monitor_id", monitor.ID, "type", genericType)
+		if monitor.Name != "" {
+			extra = append(extra, "name", monitor.Name)
+		}
+	}
+
+	// Validate everything first so a bad request does not leave half of its
+	// series behind.
+	series := make([]labels.Labels, len(request.Timeseries))
+	builder := labels.NewScratchBuilder(0)
+	for i, ts := range request.Timeseries {
+		lset, err := receivedSeriesLabels(&builder, ts, extra)
+		if err != nil {
+			return nil, err
+		}
+		series[i] = lset
+	}
+
+	appender := s.appendable.Appender(ctx)
+	defer appender.Rollback()
+
+	result := &WriteResult{}
+	for i, ts := range request.Timeseries {
+		var ref storage.SeriesRef
+		for _, sample := range ts.Samples {
+			var err error
+			ref, err = appender.Append(ref, series[i], sample.Timestamp, sample.Value)
+			if err := result.record(err, &result.Samples); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, hp := range ts.Histograms {
+			var err error
+			if hp.IsFloatHistogram() {
+				ref, err = appender.AppendHistogram(ref, series[i], hp.Timestamp, nil, hp.ToFloatHistogram())
+			} else {
+				ref, err = appender.AppendHistogram(ref, series[i], hp.Timestamp, hp.ToIntHistogram(), nil)
+			}
+			if err := result.record(err, &result.Histograms); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := appender.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing samples: %v", err)
+	}
+
+	return result, nil
+}
+
+// record counts an append. Rejections of single samples are counted as
+// skipped; anything else aborts the request.
+func (r *WriteResult) record(err error, counter *int) error {
+	switch {
+	case err == nil:
+		*counter++
+		return nil
+	case errors.Is(err, storage.ErrOutOfOrderSample),
+		errors.Is(err, storage.ErrOutOfBounds),
+		errors.Is(err, storage.ErrTooOldSample),
+		errors.Is(err, storage.ErrDuplicateSampleForTimestamp),
+		errors.Is(err, storage.ErrNativeHistogramsDisabled):
+		r.Skipped++
+		r.LastError = err
+		return nil
+	default:
+		return fmt.Errorf("error appending sample: %v", err)
+	}
+}
+
+func receivedSeriesLabels(b *labels.ScratchBuilder, ts prompb.TimeSeries, extra []string) (labels.Labels, error) {
+	b.Reset()
+
+	hasName := false
+	seen := make(map[string]bool, len(ts.Labels))
+	for _, l := range ts.Labels {
+		if !model.LabelName(l.Name).IsValid() {
+			return labels.EmptyLabels(), fmt.Errorf("%w: invalid label name %q", ErrInvalidSeries, l.Name)
+		}
+		if seen[l.Name] {
+			return labels.EmptyLabels(), fmt.Errorf("%w: duplicate label %q", ErrInvalidSeries, l.Name)
+		}
+		seen[l.Name] = true
+
+		if l.Value == "" {
+			continue
+		}
+		if l.Name == labels.MetricName {
+			hasName = true
+		}
+
+		name := l.Name
+		if receivedLabels[name] {
+			name = "exported_" + name
+		}
+		b.Add(name, l.Value)
+	}
+	if !hasName {
+		return labels.EmptyLabels(), fmt.Errorf("%w: series without metric name", ErrInvalidSeries)
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		b.Add(extra[i], extra[i+1])
+	}
+
+	b.Sort()
+	lset := b.Labels()
+	if name, duplicate := lset.HasDuplicateLabelNames(); duplicate {
+		return labels.EmptyLabels(), fmt.Errorf("%w: duplicate label %q", ErrInvalidSeries, name)
+	}
+
+	return lset, nil
+}
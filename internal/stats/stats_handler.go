@@ -3,4 +3,6 @@ package status
 type StatusHandler struct {
 }
 
-func NewStatusHandler()
+func NewStatusHandler() *StatusHandler {
+	return &StatusHandler{}
+}
@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/recorder"
+)
+
+// heartbeatInterval keeps idle connections open through proxies.
+const heartbeatInterval = 15 * time.Second
+
+// StreamHandler streams check results and state changes as Server-Sent
+// Events. Events can be narrowed with monitor_id and tag, both repeatable or
+// comma separated, and with type=check or type=state_change.
+func StreamHandler(events recorder.Broadcaster) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: "streaming is not supported",
+				Data:    nil,
+			}, http.StatusInternalServerError)
+			return
+		}
+
+		query := r.URL.Query()
+		f := filter{
+			monitorIDs: set(query["monitor_id"]),
+			tags:       set(query["tag"]),
+			types:      set(query["type"]),
+		}
+		for eventType := range f.types {
+			if eventType != pkg.EventCheck && eventType != pkg.EventStateChange {
+				pkg.JsonResponse(w, pkg.BaseResponse{
+					Success: false,
+					Message: "validation failed",
+					Data: []pkg.FieldError{{
+						Field:   "type",
+						Message: fmt.Sprintf("must be %s or %s", pkg.EventCheck, pkg.EventStateChange),
+					}},
+				}, http.StatusUnprocessableEntity)
+				return
+			}
+		}
+
+		ch, unsubscribe := events.Subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "retry: 5000\n\n")
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		var id int64
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			case event, ok := <-ch:
+				if !ok {
+					return
+				}
+				if !f.match(event) {
+					continue
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				id++
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event.Type, data); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+type filter struct {
+	monitorIDs map[string]bool
+	tags       map[string]bool
+	types      map[string]bool
+}
+
+// match reports whether the event passes every given filter. A tag filter
+// matches a tag name, a label name or a name:value label pair.
+func (f filter) match(event pkg.CheckEvent) bool {
+	if len(f.types) > 0 && !f.types[event.Type] {
+		return false
+	}
+	if len(f.monitorIDs) > 0 && !f.monitorIDs[event.Status.MonitorID] {
+		return false
+	}
+	if len(f.tags) > 0 {
+		for _, tag := range event.Status.Tags {
+			if f.tags[tag] {
+				return true
+			}
+		}
+		for name, value := range event.Status.Labels {
+			if f.tags[name] || f.tags[name+":"+value] {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// set splits comma separated query values into a set.
+func set(values []string) map[string]bool {
+	out := make(map[string]bool)
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				out[v] = true
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+package tag
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/go-chi/chi/v5"
+)
+
+type listResponse struct {
+	Total int           `json:"total"`
+	Tags  []*pkg.TagDTO `json:"tags"`
+}
+
+func CreateHandler(tagSvc TagService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload pkg.TagDTO
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: "invalid req body",
+				Data:    nil,
+			}, http.StatusBadRequest)
+			return
+		}
+
+		id, err := tagSvc.CreateTag(r.Context(), &payload)
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    id,
+		}, http.StatusOK)
+	}
+}
+
+func ListHandler(tagSvc TagService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tags, err := tagSvc.GetListTags(r.Context())
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data: listResponse{
+				Total: len(tags),
+				Tags:  tags,
+			},
+		}, http.StatusOK)
+	}
+}
+
+func UpdateHandler(tagSvc TagService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload pkg.TagDTO
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: "invalid req body",
+				Data:    nil,
+			}, http.StatusBadRequest)
+			return
+		}
+
+		tag, err := tagSvc.UpdateTag(r.Context(), chi.URLParam(r, "id"), &payload)
+		if err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    tag,
+		}, http.StatusOK)
+	}
+}
+
+func DeleteHandler(tagSvc TagService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := tagSvc.DeleteTag(r.Context(), chi.URLParam(r, "id")); err != nil {
+			pkg.JsonErrorResponse(w, err, errorStatuses...)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    nil,
+		}, http.StatusOK)
+	}
+}
+
+// errorStatuses maps service errors to status codes. Name conflicts carry
+// their field error as data.
+var errorStatuses = []pkg.ErrorStatus{
+	{Err: ErrConflict, Code: http.StatusConflict, Data: []pkg.FieldError{{Field: "name", Message: "is already used by another tag"}}},
+	{Err: ErrNotFound, Code: http.StatusNotFound},
+}
@@ -0,0 +1,166 @@
+package tag
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	_ "github.com/glebarez/go-sqlite"
+)
+
+const tagColumns = `
+	id, name, color, created_at,
+	(SELECT COUNT(*) FROM config_monitor_tag WHERE config_monitor_tag.tag_id = tag.id) AS monitor_count
+`
+
+type tagRepository struct {
+	db *sql.DB
+}
+
+type TagRepository interface {
+	Insert(ctx context.Context, tag *pkg.TagDTO) (string, error)
+	GetByID(ctx context.Context, id string) (*pkg.TagDTO, error)
+	List(ctx context.Context) ([]*pkg.TagDTO, error)
+	Update(ctx context.Context, tag *pkg.TagDTO) (bool, error)
+	// Delete removes the tag from every monitor and then the tag itself.
+	Delete(ctx context.Context, id string) (bool, error)
+	// NameTaken reports whether a tag other than excludeID uses name.
+	NameTaken(ctx context.Context, name, excludeID string) (bool, error)
+}
+
+func NewTagRepository(
+	db *sql.DB,
+) TagRepository {
+	return &tagRepository{
+		db,
+	}
+}
+
+func (r *tagRepository) Insert(ctx context.Context, tag *pkg.TagDTO) (string, error) {
+	result, err := r.db.ExecContext(ctx, "INSERT INTO tag (name, color) VALUES (?, ?)", tag.Name, tag.Color)
+	if err != nil {
+		return "", fmt.Errorf("error inserting tag: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return "", fmt.Errorf("error getting last insert ID: %w", err)
+	}
+
+	var uuid string
+	err = r.db.QueryRowContext(ctx, "SELECT id FROM tag WHERE rowid = ?", id).Scan(&uuid)
+	if err != nil {
+		return "", fmt.Errorf("error fetching generated UUID: %w", err)
+	}
+
+	return uuid, nil
+}
+
+func (r *tagRepository) GetByID(ctx context.Context, id string) (*pkg.TagDTO, error) {
+	query := `SELECT ` + tagColumns + ` FROM tag WHERE id = ?`
+
+	tag, err := scanTag(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+func (r *tagRepository) List(ctx context.Context) ([]*pkg.TagDTO, error) {
+	query := `SELECT ` + tagColumns + ` FROM tag ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []*pkg.TagDTO
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (r *tagRepository) Update(ctx context.Context, tag *pkg.TagDTO) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "UPDATE tag SET name = ?, color = ? WHERE id = ?", tag.Name, tag.Color, tag.ID)
+	if err != nil {
+		return false, fmt.Errorf("error updating tag: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting affected rows: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+func (r *tagRepository) Delete(ctx context.Context, id string) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM config_monitor_tag WHERE tag_id = ?", id); err != nil {
+		return false, fmt.Errorf("error untagging monitors: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM tag WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting tag: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting affected rows: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+func (r *tagRepository) NameTaken(ctx context.Context, name, excludeID string) (bool, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tag WHERE name = ? AND id != ?", name, excludeID).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("error checking tag name: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTag(row rowScanner) (*pkg.TagDTO, error) {
+	var tag pkg.TagDTO
+	var color sql.NullString
+
+	err := row.Scan(
+		&tag.ID,
+		&tag.Name,
+		&color,
+		&tag.CreatedAt,
+		&tag.MonitorCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tag.Color = color.String
+
+	return &tag, nil
+}
@@ -0,0 +1,71 @@
+package tag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+)
+
+func TestTagRepository(t *testing.T) {
+	ctx := context.Background()
+	db := testutil.DB(t)
+	repository := NewTagRepository(db)
+
+	coreID, err := repository.Insert(ctx, &pkg.TagDTO{Name: "core", Color: "#f00"})
+	if err != nil {
+		t.Fatalf("inserting tag: %v", err)
+	}
+	edgeID, err := repository.Insert(ctx, &pkg.TagDTO{Name: "edge"})
+	if err != nil {
+		t.Fatalf("inserting tag: %v", err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO config_monitor (type, name, url, interval) VALUES ('uptime', 'a', 'http://a', 60)`); err != nil {
+		t.Fatalf("inserting monitor: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO config_monitor_tag (monitor_id, tag_id) SELECT id, ? FROM config_monitor`, coreID); err != nil {
+		t.Fatalf("tagging monitor: %v", err)
+	}
+
+	core, err := repository.GetByID(ctx, coreID)
+	if err != nil {
+		t.Fatalf("getting tag: %v", err)
+	}
+	if core.Name != "core" || core.Color != "#f00" || core.MonitorCount != 1 {
+		t.Errorf("tag = %+v", core)
+	}
+
+	for name, want := range map[string]bool{"core": true, "other": false} {
+		if taken, err := repository.NameTaken(ctx, name, edgeID); err != nil || taken != want {
+			t.Errorf("NameTaken(%s) = %v, %v, want %v", name, taken, err, want)
+		}
+	}
+	if taken, err := repository.NameTaken(ctx, "core", coreID); err != nil || taken {
+		t.Errorf("a tag takes its own name: %v, %v", taken, err)
+	}
+
+	core.Name = "platform"
+	if ok, err := repository.Update(ctx, core); err != nil || !ok {
+		t.Fatalf("updating tag: %v, %v", ok, err)
+	}
+	tags, err := repository.List(ctx)
+	if err != nil {
+		t.Fatalf("listing tags: %v", err)
+	}
+	if len(tags) != 2 || tags[0].Name != "edge" || tags[1].Name != "platform" {
+		t.Errorf("tags = %+v, want edge and platform by name", tags)
+	}
+
+	if ok, err := repository.Delete(ctx, coreID); err != nil || !ok {
+		t.Fatalf("deleting tag: %v, %v", ok, err)
+	}
+	var tagged int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM config_monitor_tag`).Scan(&tagged); err != nil || tagged != 0 {
+		t.Errorf("monitors keep a deleted tag: %d, %v", tagged, err)
+	}
+	if ok, err := repository.Delete(ctx, coreID); err != nil || ok {
+		t.Errorf("deleting a deleted tag: %v, %v", ok, err)
+	}
+}
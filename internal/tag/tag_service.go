@@ -0,0 +1,119 @@
+package tag
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+var (
+	ErrNotFound = errors.New("tag not found")
+	ErrConflict = errors.New("a tag with this name already exists")
+)
+
+// namePattern allows key:value names such as team:payments, which also
+// become series labels.
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_.\-/]+(:[a-zA-Z0-9_.\-/]+)?$`)
+
+// MaxNameLength is the longest tag name in characters.
+const MaxNameLength = 64
+
+// ValidateName checks a tag name.
+func ValidateName(name string) error {
+	if name == "" {
+		return errors.New("is required")
+	}
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return fmt.Errorf("must be at most %d characters", MaxNameLength)
+	}
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("%q may only contain letters, digits, '_', '.', '-', '/' and one ':'", name)
+	}
+	return nil
+}
+
+type tagService struct {
+	tagRepository TagRepository
+}
+
+type TagService interface {
+	CreateTag(ctx context.Context, payload *pkg.TagDTO) (string, error)
+	GetListTags(ctx context.Context) ([]*pkg.TagDTO, error)
+	// UpdateTag renames or recolors a tag on every monitor that carries it.
+	UpdateTag(ctx context.Context, id string, payload *pkg.TagDTO) (*pkg.TagDTO, error)
+	DeleteTag(ctx context.Context, id string) error
+}
+
+func NewTagService(
+	tagRepository TagRepository,
+) TagService {
+	return &tagService{
+		tagRepository: tagRepository,
+	}
+}
+
+func (s *tagService) CreateTag(ctx context.Context, payload *pkg.TagDTO) (string, error) {
+	if err := s.validate(ctx, "", payload); err != nil {
+		return "", err
+	}
+	return s.tagRepository.Insert(ctx, payload)
+}
+
+func (s *tagService) GetListTags(ctx context.Context) ([]*pkg.TagDTO, error) {
+	return s.tagRepository.List(ctx)
+}
+
+func (s *tagService) UpdateTag(ctx context.Context, id string, payload *pkg.TagDTO) (*pkg.TagDTO, error) {
+	payload.ID = id
+	if err := s.validate(ctx, id, payload); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.tagRepository.Update(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrNotFound
+	}
+
+	tag, err := s.tagRepository.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return tag, err
+}
+
+func (s *tagService) DeleteTag(ctx context.Context, id string) error {
+	deleted, err := s.tagRepository.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *tagService) validate(ctx context.Context, id string, tag *pkg.TagDTO) error {
+	if err := ValidateName(tag.Name); err != nil {
+		errs := &pkg.ValidationError{}
+		errs.Add("name", err.Error())
+		return errs
+	}
+
+	taken, err := s.tagRepository.NameTaken(ctx, tag.Name, id)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrConflict
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+// Package testutil sets up the storage the tests of other packages run
+// against.
+package testutil
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/prometheus/prometheus/tsdb"
+	"go.uber.org/zap"
+
+	_ "github.com/glebarez/go-sqlite"
+)
+
+// DB opens a SQLite database in a temporary directory with every up
+// migration applied.
+func DB(t testing.TB) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "statx.db"))
+	if err != nil {
+		t.Fatalf("opening sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	_, file, _, _ := runtime.Caller(0)
+	migrations, err := filepath.Glob(filepath.Join(filepath.Dir(file), "..", "..", "migrations", "*.up.sql"))
+	if err != nil || len(migrations) == 0 {
+		t.Fatalf("finding migrations: %v", err)
+	}
+	sort.Strings(migrations)
+
+	for _, migration := range migrations {
+		script, err := os.ReadFile(migration)
+		if err != nil {
+			t.Fatalf("reading %s: %v", migration, err)
+		}
+		if _, err := db.Exec(string(script)); err != nil {
+			t.Fatalf("applying %s: %v", filepath.Base(migration), err)
+		}
+	}
+
+	return db
+}
+
+// TSDB opens a TSDB in a temporary directory with the retention and the
+// out-of-order window statx runs with for config.
+func TSDB(t testing.TB, config *pkg.Config) *tsdb.DB {
+	t.Helper()
+
+	opts := tsdb.DefaultOptions()
+	opts.RetentionDuration = config.MaxRetention().Milliseconds()
+	opts.OutOfOrderTimeWindow = config.OutOfOrderWindow().Milliseconds()
+
+	db, err := tsdb.Open(t.TempDir(), nil, nil, opts, nil)
+	if err != nil {
+		t.Fatalf("opening tsdb: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// Config is a config with short timeouts, a single check attempt and a
+// short write batch interval.
+func Config(t testing.TB) *pkg.Config {
+	return &pkg.Config{
+		StoragePath:        t.TempDir(),
+		RetentionPeriod:    7 * 24 * time.Hour,
+		BlockDuration:      2 * time.Hour,
+		CheckTimeout:       2 * time.Second,
+		RetryAttempts:      1,
+		MaxSamplesPerDay:   86400,
+		SecretKey:          "0000000000000000000000000000000000000000000000000000000000000000",
+		WriteBatchInterval: 10 * time.Millisecond,
+	}
+}
+
+func Logger() *zap.SugaredLogger {
+	return zap.NewNop().Sugar()
+}
@@ -0,0 +1,87 @@
+package tls_config
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/go-chi/chi/v5"
+)
+
+type listResponse struct {
+	Total      int                 `json:"total"`
+	TLSConfigs []*pkg.TLSConfigDTO `json:"tls_configs"`
+}
+
+func CreateHandler(tlsConfigSvc TLSConfigService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload pkg.TLSConfigDTO
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: "invalid req body",
+				Data:    nil,
+			}, http.StatusBadRequest)
+			return
+		}
+
+		id, err := tlsConfigSvc.CreateTLSConfig(r.Context(), &payload)
+		if err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, http.StatusBadRequest)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    id,
+		}, http.StatusOK)
+	}
+}
+
+func GetHandler(tlsConfigSvc TLSConfigService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config, err := tlsConfigSvc.GetTLSConfig(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, http.StatusNotFound)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data:    config,
+		}, http.StatusOK)
+	}
+}
+
+func ListHandler(tlsConfigSvc TLSConfigService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		configs, total, err := tlsConfigSvc.GetListTLSConfigs(r.Context(), 100, 0)
+		if err != nil {
+			pkg.JsonResponse(w, pkg.BaseResponse{
+				Success: false,
+				Message: err.Error(),
+				Data:    nil,
+			}, http.StatusBadRequest)
+			return
+		}
+
+		pkg.JsonResponse(w, pkg.BaseResponse{
+			Success: true,
+			Message: "good",
+			Data: listResponse{
+				Total:      total,
+				TLSConfigs: configs,
+			},
+		}, http.StatusOK)
+	}
+}
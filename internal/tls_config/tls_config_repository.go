@@ -0,0 +1,148 @@
+package tls_config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	_ "github.com/glebarez/go-sqlite"
+)
+
+type tlsConfigRepository struct {
+	db *sql.DB
+}
+
+// TLSConfigRepository stores TLS settings. ClientKey is persisted exactly as
+// given, callers are expected to hand over an encrypted value.
+type TLSConfigRepository interface {
+	Insert(ctx context.Context, config *pkg.TLSConfigDTO) (string, error)
+	GetByID(ctx context.Context, id string) (*pkg.TLSConfigDTO, error)
+	List(ctx context.Context, limit, offset int) ([]*pkg.TLSConfigDTO, int, error)
+}
+
+func NewTLSConfigRepository(
+	db *sql.DB,
+) TLSConfigRepository {
+	return &tlsConfigRepository{
+		db,
+	}
+}
+
+func (r *tlsConfigRepository) Insert(ctx context.Context, config *pkg.TLSConfigDTO) (string, error) {
+	query := `
+		INSERT INTO tls_config (
+			name, ca_cert, client_cert, client_key,
+			server_name, min_version, insecure_skip_verify
+		) VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		config.Name,
+		config.CACert,
+		config.ClientCert,
+		config.ClientKey,
+		config.ServerName,
+		config.MinVersion,
+		config.InsecureSkipVerify,
+	)
+	if err != nil {
+		return "", fmt.Errorf("error inserting tls config: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return "", fmt.Errorf("error getting last insert ID: %w", err)
+	}
+
+	var uuid string
+	err = r.db.QueryRowContext(ctx, "SELECT id FROM tls_config WHERE rowid = ?", id).Scan(&uuid)
+	if err != nil {
+		return "", fmt.Errorf("error fetching generated UUID: %w", err)
+	}
+
+	return uuid, nil
+}
+
+func (r *tlsConfigRepository) GetByID(ctx context.Context, id string) (*pkg.TLSConfigDTO, error) {
+	query := `
+		SELECT id, name, ca_cert, client_cert, client_key,
+			   server_name, min_version, insecure_skip_verify, updated_at
+		FROM tls_config
+		WHERE id = ?
+	`
+
+	config, err := scanTLSConfig(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching tls config: %w", err)
+	}
+
+	return config, nil
+}
+
+func (r *tlsConfigRepository) List(ctx context.Context, limit, offset int) ([]*pkg.TLSConfigDTO, int, error) {
+	query := `
+		SELECT id, name, ca_cert, client_cert, client_key,
+			   server_name, min_version, insecure_skip_verify, updated_at
+		FROM tls_config
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying tls configs: %w", err)
+	}
+	defer rows.Close()
+
+	var configs []*pkg.TLSConfigDTO
+	for rows.Next() {
+		config, err := scanTLSConfig(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning tls config: %w", err)
+		}
+		configs = append(configs, config)
+	}
+
+	var total int
+	err = r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tls_config").Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error getting total count: %w", err)
+	}
+
+	return configs, total, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTLSConfig(row rowScanner) (*pkg.TLSConfigDTO, error) {
+	var config pkg.TLSConfigDTO
+	var caCert, clientCert, clientKey, serverName, minVersion sql.NullString
+	var updatedAt sql.NullTime
+
+	err := row.Scan(
+		&config.ID,
+		&config.Name,
+		&caCert,
+		&clientCert,
+		&clientKey,
+		&serverName,
+		&minVersion,
+		&config.InsecureSkipVerify,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	config.CACert = caCert.String
+	config.ClientCert = clientCert.String
+	config.ClientKey = clientKey.String
+	config.HasClientKey = clientKey.String != ""
+	config.ServerName = serverName.String
+	config.MinVersion = minVersion.String
+	config.UpdatedAt = updatedAt.Time
+
+	return &config, nil
+}
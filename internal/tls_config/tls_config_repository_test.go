@@ -0,0 +1,107 @@
+package tls_config
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/testutil"
+)
+
+// clientKeyPair returns a self-signed certificate and its key as PEM.
+func clientKeyPair(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestTLSConfigRepository(t *testing.T) {
+	ctx := context.Background()
+	repository := NewTLSConfigRepository(testutil.DB(t))
+
+	id, err := repository.Insert(ctx, &pkg.TLSConfigDTO{Name: "mtls", ServerName: "api.internal", MinVersion: "1.2", InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("inserting tls config: %v", err)
+	}
+	if _, err := repository.Insert(ctx, &pkg.TLSConfigDTO{Name: "plain"}); err != nil {
+		t.Fatalf("inserting tls config: %v", err)
+	}
+
+	config, err := repository.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("getting tls config: %v", err)
+	}
+	if config.Name != "mtls" || config.ServerName != "api.internal" || config.MinVersion != "1.2" || !config.InsecureSkipVerify {
+		t.Errorf("tls config = %+v", config)
+	}
+
+	configs, total, err := repository.List(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("listing tls configs: %v", err)
+	}
+	if total != 2 || len(configs) != 1 {
+		t.Errorf("listed %d of %d tls configs, want 1 of 2", len(configs), total)
+	}
+}
+
+func TestClientKeyIsStoredSealed(t *testing.T) {
+	ctx := context.Background()
+	repository := NewTLSConfigRepository(testutil.DB(t))
+	service := NewTLSConfigService(repository, testutil.Config(t))
+	cert, key := clientKeyPair(t)
+
+	id, err := service.CreateTLSConfig(ctx, &pkg.TLSConfigDTO{Name: "mtls", ClientCert: cert, ClientKey: key})
+	if err != nil {
+		t.Fatalf("creating tls config: %v", err)
+	}
+
+	stored, err := repository.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("getting tls config: %v", err)
+	}
+	if stored.ClientKey == "" || stored.ClientKey == key || !stored.HasClientKey {
+		t.Fatalf("stored client key = %q, want it sealed", stored.ClientKey)
+	}
+
+	shown, err := service.GetTLSConfig(ctx, id)
+	if err != nil {
+		t.Fatalf("getting tls config: %v", err)
+	}
+	if shown.ClientKey != "" || !shown.HasClientKey {
+		t.Errorf("client key is returned: %+v", shown)
+	}
+
+	resolved, err := service.ResolveTLSConfig(ctx, id)
+	if err != nil {
+		t.Fatalf("resolving tls config: %v", err)
+	}
+	if resolved.ClientKey != key {
+		t.Errorf("resolved client key differs from the one created")
+	}
+}
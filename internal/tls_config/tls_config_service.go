@@ -0,0 +1,131 @@
+package tls_config
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/afrianjunior/statx/internal/pkg"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+type tlsConfigService struct {
+	tlsConfigRepository TLSConfigRepository
+	config              *pkg.Config
+}
+
+type TLSConfigService interface {
+	CreateTLSConfig(ctx context.Context, payload *pkg.TLSConfigDTO) (string, error)
+	GetTLSConfig(ctx context.Context, id string) (*pkg.TLSConfigDTO, error)
+	GetListTLSConfigs(ctx context.Context, limit, offset int) ([]*pkg.TLSConfigDTO, int, error)
+	// ResolveTLSConfig returns the config with the client key decrypted, for use by the recorder only.
+	ResolveTLSConfig(ctx context.Context, id string) (*pkg.TLSConfigDTO, error)
+}
+
+func NewTLSConfigService(
+	tlsConfigRepository TLSConfigRepository,
+	config *pkg.Config,
+) TLSConfigService {
+	return &tlsConfigService{
+		tlsConfigRepository: tlsConfigRepository,
+		config:              config,
+	}
+}
+
+// TLSVersion maps a "1.2" style version string to its crypto/tls constant.
+func TLSVersion(version string) (uint16, error) {
+	if version == "" {
+		return 0, nil
+	}
+	v, ok := tlsVersions[version]
+	if !ok {
+		return 0, fmt.Errorf("unsupported min_version %q", version)
+	}
+	return v, nil
+}
+
+func validate(payload *pkg.TLSConfigDTO) error {
+	if payload.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, err := TLSVersion(payload.MinVersion); err != nil {
+		return err
+	}
+	if payload.CACert != "" {
+		if ok := x509.NewCertPool().AppendCertsFromPEM([]byte(payload.CACert)); !ok {
+			return fmt.Errorf("ca_cert does not contain a valid PEM certificate")
+		}
+	}
+	if (payload.ClientCert == "") != (payload.ClientKey == "") {
+		return fmt.Errorf("client_cert and client_key must be provided together")
+	}
+	if payload.ClientCert != "" {
+		if _, err := tls.X509KeyPair([]byte(payload.ClientCert), []byte(payload.ClientKey)); err != nil {
+			return fmt.Errorf("invalid client certificate: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *tlsConfigService) CreateTLSConfig(ctx context.Context, payload *pkg.TLSConfigDTO) (string, error) {
+	if err := validate(payload); err != nil {
+		return "", err
+	}
+
+	record := *payload
+	if record.ClientKey != "" {
+		sealed, err := pkg.EncryptSecret(s.config.SecretKey, []byte(record.ClientKey))
+		if err != nil {
+			return "", fmt.Errorf("error encrypting client key: %v", err)
+		}
+		record.ClientKey = sealed
+	}
+
+	return s.tlsConfigRepository.Insert(ctx, &record)
+}
+
+func (s *tlsConfigService) GetTLSConfig(ctx context.Context, id string) (*pkg.TLSConfigDTO, error) {
+	config, err := s.tlsConfigRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	config.ClientKey = ""
+	return config, nil
+}
+
+func (s *tlsConfigService) GetListTLSConfigs(ctx context.Context, limit, offset int) ([]*pkg.TLSConfigDTO, int, error) {
+	list, total, err := s.tlsConfigRepository.List(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, config := range list {
+		config.ClientKey = ""
+	}
+
+	return list, total, nil
+}
+
+func (s *tlsConfigService) ResolveTLSConfig(ctx context.Context, id string) (*pkg.TLSConfigDTO, error) {
+	config, err := s.tlsConfigRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.ClientKey != "" {
+		key, err := pkg.DecryptSecret(s.config.SecretKey, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting client key for tls config %s: %v", id, err)
+		}
+		config.ClientKey = string(key)
+	}
+
+	return config, nil
+}
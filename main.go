@@ -1,17 +1,25 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/afrianjunior/statx/cmd"
 	"github.com/afrianjunior/statx/internal/pkg"
+	"github.com/afrianjunior/statx/internal/recorder"
 	"github.com/prometheus/prometheus/tsdb"
 	"go.uber.org/zap"
 
@@ -47,7 +55,7 @@ func loadConfig(path string) (*pkg.Config, error) {
 
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		configJSON, _ := json.MarshalIndent(defaultConfig, "", "  ")
-		if err := os.WriteFile(path, configJSON, 0644); err != nil {
+		if err := writeConfig(path, configJSON); err != nil {
 			return nil, fmt.Errorf("error creating default config: %v", err)
 		}
 	}
@@ -62,9 +70,123 @@ func loadConfig(path string) (*pkg.Config, error) {
 		return nil, fmt.Errorf("error parsing config: %v", err)
 	}
 
+	// a check makes at least one attempt
+	config.RetryAttempts = max(config.RetryAttempts, 1)
+
+	if config.SecretKey == "" {
+		if config.SecretKey, err = saveSecretKey(path, data); err != nil {
+			return nil, err
+		}
+	}
+
 	return &config, nil
 }
 
+// saveSecretKey generates a secret key and writes it into the config at
+// path, whose content is data. The key has to outlive the process, secrets
+// sealed with it are unreadable under another one. Backups do not include
+// config.json, so the key has to be kept along with them.
+func saveSecretKey(path string, data []byte) (string, error) {
+	key, err := generateSecretKey()
+	if err != nil {
+		return "", err
+	}
+
+	configJSON, err := setSecretKey(data, key)
+	if err != nil {
+		return "", fmt.Errorf("error parsing config: %v", err)
+	}
+	if err := writeConfig(path, configJSON); err != nil {
+		return "", fmt.Errorf("error saving secret_key to config: %v", err)
+	}
+	return key, nil
+}
+
+// setSecretKey sets secret_key in the JSON object data and leaves the rest
+// of the operator's file as it is: an existing empty value is replaced,
+// otherwise the key is inserted as the first field.
+func setSecretKey(data []byte, key string) ([]byte, error) {
+	value, _ := json.Marshal(key)
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return nil, errors.New("config is not a JSON object")
+	}
+	open := int(dec.InputOffset())
+
+	first := -1
+	for dec.More() {
+		name, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if first < 0 {
+			first = int(dec.InputOffset()) - len(mustMarshal(name))
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		if name == "secret_key" {
+			end := int(dec.InputOffset())
+			return concat(data[:end-len(raw)], value, data[end:]), nil
+		}
+	}
+
+	if first < 0 {
+		field := append([]byte(`"secret_key": `), value...)
+		return concat(data[:open], field, data[open:]), nil
+	}
+	// repeat the whitespace before the first field to keep the indentation
+	field := append(append([]byte(`"secret_key": `), value...), ',')
+	field = append(field, data[open:first]...)
+	return concat(data[:first], field, data[first:]), nil
+}
+
+func mustMarshal(v any) []byte {
+	b, _ := json.Marshal(v)
+	return b
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, part := range parts {
+		out = append(out, part...)
+	}
+	return out
+}
+
+// writeConfig replaces the config at path. It holds secret_key, so it is
+// readable by its owner only.
+func writeConfig(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func generateSecretKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("error generating secret_key: %v", err)
+	}
+	return hex.EncodeToString(key), nil
+}
+
 func setupLogger(level string) (*zap.SugaredLogger, error) {
 	config := zap.NewProductionConfig()
 	config.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
@@ -84,8 +206,8 @@ func NewApp(config *pkg.Config) (*App, error) {
 		return nil, err
 	}
 
-	tsdbPath := fmt.Sprintf("%s/%s", config.StoragePath, "/tsdb")
-	sqlitePath := fmt.Sprintf("%s/%s", config.StoragePath, "/sqlite")
+	tsdbPath := config.TSDBPath()
+	sqlitePath := config.SQLitePath()
 
 	if err := os.MkdirAll(tsdbPath, 0755); err != nil {
 		return nil, fmt.Errorf("error creating tsdb directory: %v", err)
@@ -96,16 +218,21 @@ func NewApp(config *pkg.Config) (*App, error) {
 	}
 
 	opts := tsdb.DefaultOptions()
-	opts.RetentionDuration = config.RetentionPeriod.Milliseconds()
+	// per monitor and per type policies are enforced by the retention job,
+	// the TSDB only drops what is older than the longest of them
+	opts.RetentionDuration = config.MaxRetention().Milliseconds()
 	opts.MaxBlockDuration = config.BlockDuration.Milliseconds()
 	opts.MaxBlockChunkSegmentSize = 256 * 1024 * 1024
+	// late checks and rollups only; the relabel migration widens it while
+	// it copies history
+	opts.OutOfOrderTimeWindow = config.OutOfOrderWindow().Milliseconds()
 
 	tsdb, err := tsdb.Open(tsdbPath, nil, nil, opts, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error opening TSDB: %v", err)
 	}
 
-	db, err := sql.Open("sqlite", fmt.Sprintf("%s/%s", sqlitePath, "/statx.db"))
+	db, err := sql.Open("sqlite", config.SQLiteFile())
 	if err != nil {
 		return nil, fmt.Errorf("error opening sqlite: %v", err)
 	}
@@ -123,17 +250,28 @@ func NewApp(config *pkg.Config) (*App, error) {
 }
 
 func main() {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	config, err := loadConfig("config.json")
 	if err != nil {
 		log.Fatalf("Error loading config: %v", err)
 	}
 
+	if len(os.Args) > 1 {
+		runCommand(config, os.Args[1], os.Args[2:])
+		return
+	}
+
 	app, err := NewApp(config)
 	if err != nil {
 		log.Fatalf("Error creating monitor: %v", err)
 	}
 	defer app.db.Close()
+	defer app.tsdb.Close()
+
+	statuses := recorder.NewStatusTracker()
+	events := recorder.NewBroadcaster()
 
 	worker := cmd.NewWorker(
 		app.tsdb,
@@ -142,18 +280,52 @@ func main() {
 		app.httpClient,
 		app.logger,
 		app.config,
+		statuses,
+		events,
 	)
 
-	worker.Start(context.Background())
+	worker.Start(ctx)
 
 	rest := cmd.NewRest(
 		app.httpClient,
 		app.tsdb,
 		app.db,
 		app.logger,
+		app.config,
+		statuses,
+		events,
+		worker,
 	)
 
 	app.db.Conn(ctx)
 
-	rest.Start(app.config.ServerPort)
+	rest.Start(ctx, app.config.ServerPort)
+
+	// the checks stop with ctx; store what they still buffer before closing
+	worker.Wait()
+}
+
+// runCommand runs the one-off subcommands; statx without arguments starts the server.
+func runCommand(config *pkg.Config, name string, args []string) {
+	logger, err := setupLogger(config.LogLevel)
+	if err != nil {
+		log.Fatalf("Error creating logger: %v", err)
+	}
+
+	switch name {
+	case "backup":
+		err = cmd.RunBackup(config, args)
+	case "restore":
+		err = cmd.RunRestore(config, logger, args)
+	case "bench-write":
+		err = cmd.RunBenchWrite(config, args)
+	case "export":
+		err = cmd.RunExport(config, args)
+	default:
+		err = fmt.Errorf("unknown command %q, expected backup, restore, bench-write or export", name)
+	}
+
+	if err != nil {
+		log.Fatalf("Error running %s: %v", name, err)
+	}
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigSavesASecretKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"server_port": "9090", "custom": {"kept": true}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	if len(config.SecretKey) != 64 || config.ServerPort != "9090" {
+		t.Fatalf("secret_key = %q, server_port = %q", config.SecretKey, config.ServerPort)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]any
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("parsing saved config: %v", err)
+	}
+	if saved["secret_key"] != config.SecretKey || saved["custom"] == nil {
+		t.Fatalf("saved config = %s", data)
+	}
+
+	// the saved key is used from then on
+	reloaded, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("reloading config: %v", err)
+	}
+	if reloaded.SecretKey != config.SecretKey {
+		t.Fatalf("secret_key changed between loads: %q, %q", config.SecretKey, reloaded.SecretKey)
+	}
+}
+
+func TestLoadConfigCreatesOneWithASecretKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	reloaded, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("reloading config: %v", err)
+	}
+	if config.SecretKey == "" || reloaded.SecretKey != config.SecretKey {
+		t.Fatalf("secret_key = %q, then %q", config.SecretKey, reloaded.SecretKey)
+	}
+}
+
+func TestLoadConfigMakesAtLeastOneAttempt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"secret_key": "k"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	if config.RetryAttempts != 1 {
+		t.Fatalf("retry_attempts = %d, want 1 when unset", config.RetryAttempts)
+	}
+}
+
+func TestConfigIsOnlyReadableByItsOwner(t *testing.T) {
+	created := filepath.Join(t.TempDir(), "config.json")
+	existing := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(existing, []byte(`{"server_port": "9090"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{created, existing} {
+		if _, err := loadConfig(path); err != nil {
+			t.Fatalf("loading config: %v", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != 0600 {
+			t.Fatalf("%s mode = %v", path, mode)
+		}
+	}
+}
+
+func TestSetSecretKeyKeepsTheLayout(t *testing.T) {
+	tests := []struct {
+		name, config, want string
+	}{
+		{
+			name:   "inserted first",
+			config: "{\n  \"server_port\": \"9090\",\n  \"db_path\": \"statx.db\"\n}\n",
+			want:   "{\n  \"secret_key\": \"k\",\n  \"server_port\": \"9090\",\n  \"db_path\": \"statx.db\"\n}\n",
+		},
+		{
+			name:   "empty value replaced",
+			config: `{"server_port": "9090", "secret_key": "", "db_path": "statx.db"}`,
+			want:   `{"server_port": "9090", "secret_key": "k", "db_path": "statx.db"}`,
+		},
+		{
+			name:   "empty object",
+			config: `{}`,
+			want:   `{"secret_key": "k"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setSecretKey([]byte(tt.config), "k")
+			if err != nil {
+				t.Fatalf("setting secret_key: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
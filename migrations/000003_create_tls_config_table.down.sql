@@ -0,0 +1,5 @@
+-- Down migration: Drop tls_config table
+ALTER TABLE config_monitor DROP COLUMN tls_config_id;
+DROP TRIGGER IF EXISTS tr_tls_config_generate_uuid;
+DROP TABLE IF EXISTS tls_config;
+DROP INDEX IF EXISTS idx_tls_config_name;
@@ -0,0 +1,33 @@
+-- Up migration: Create tls_config table
+
+CREATE TABLE tls_config (
+    id TEXT PRIMARY KEY,
+    name VARCHAR NOT NULL,
+    ca_cert TEXT,
+    client_cert TEXT,
+    client_key TEXT,
+    server_name VARCHAR,
+    min_version VARCHAR,
+    insecure_skip_verify INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_tls_config_name ON tls_config(name);
+
+ALTER TABLE config_monitor ADD COLUMN tls_config_id TEXT REFERENCES tls_config(id);
+
+-- Create a trigger to ensure unique IDs
+CREATE TRIGGER tr_tls_config_generate_uuid
+AFTER INSERT ON tls_config
+FOR EACH ROW
+WHEN NEW.id IS NULL
+BEGIN
+   UPDATE tls_config SET id = (
+     lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || 
+     substr(lower(hex(randomblob(2))),2) || '-' || 
+     substr('89ab',abs(random()) % 4 + 1, 1) || 
+     substr(lower(hex(randomblob(2))),2) || '-' || 
+     lower(hex(randomblob(6)))
+   ) WHERE rowid = NEW.rowid;
+END;
@@ -0,0 +1,2 @@
+-- Down migration: Drop proxy_url from config_monitor
+ALTER TABLE config_monitor DROP COLUMN proxy_url;
@@ -0,0 +1,3 @@
+-- Up migration: Add proxy_url to config_monitor
+
+ALTER TABLE config_monitor ADD COLUMN proxy_url VARCHAR;
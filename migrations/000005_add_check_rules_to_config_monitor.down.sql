@@ -0,0 +1,5 @@
+-- Down migration: Drop up/down rules from config_monitor
+ALTER TABLE config_monitor DROP COLUMN final_url_pattern;
+ALTER TABLE config_monitor DROP COLUMN max_redirects;
+ALTER TABLE config_monitor DROP COLUMN follow_redirects;
+ALTER TABLE config_monitor DROP COLUMN accepted_status_codes;
@@ -0,0 +1,6 @@
+-- Up migration: Add up/down rules to config_monitor
+
+ALTER TABLE config_monitor ADD COLUMN accepted_status_codes VARCHAR;
+ALTER TABLE config_monitor ADD COLUMN follow_redirects INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE config_monitor ADD COLUMN max_redirects INTEGER NOT NULL DEFAULT 10;
+ALTER TABLE config_monitor ADD COLUMN final_url_pattern VARCHAR;
@@ -0,0 +1,4 @@
+-- Down migration: Drop degraded state thresholds from config_monitor
+ALTER TABLE config_monitor DROP COLUMN slo_window;
+ALTER TABLE config_monitor DROP COLUMN slo_percentile;
+ALTER TABLE config_monitor DROP COLUMN degraded_threshold_ms;
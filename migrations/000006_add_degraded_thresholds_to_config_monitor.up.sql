@@ -0,0 +1,5 @@
+-- Up migration: Add degraded state thresholds to config_monitor
+
+ALTER TABLE config_monitor ADD COLUMN degraded_threshold_ms INTEGER;
+ALTER TABLE config_monitor ADD COLUMN slo_percentile REAL;
+ALTER TABLE config_monitor ADD COLUMN slo_window INTEGER;
@@ -0,0 +1,7 @@
+-- Down migration: Drop content snapshot and change tables
+DROP TRIGGER IF EXISTS tr_content_change_generate_uuid;
+DROP INDEX IF EXISTS idx_content_change_monitor_id;
+DROP TABLE IF EXISTS content_change;
+DROP TABLE IF EXISTS content_snapshot;
+ALTER TABLE config_monitor DROP COLUMN content_ignore;
+ALTER TABLE config_monitor DROP COLUMN content_check;
@@ -0,0 +1,38 @@
+-- Up migration: Create content snapshot and change tables
+
+ALTER TABLE config_monitor ADD COLUMN content_check INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE config_monitor ADD COLUMN content_ignore TEXT;
+
+CREATE TABLE content_snapshot (
+    monitor_id TEXT PRIMARY KEY,
+    hash VARCHAR NOT NULL,
+    body TEXT,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE content_change (
+    id TEXT PRIMARY KEY,
+    monitor_id TEXT NOT NULL,
+    old_hash VARCHAR,
+    new_hash VARCHAR NOT NULL,
+    diff TEXT,
+    truncated INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_content_change_monitor_id ON content_change(monitor_id, created_at);
+
+-- Create a trigger to ensure unique IDs
+CREATE TRIGGER tr_content_change_generate_uuid
+AFTER INSERT ON content_change
+FOR EACH ROW
+WHEN NEW.id IS NULL
+BEGIN
+   UPDATE content_change SET id = (
+     lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || 
+     substr(lower(hex(randomblob(2))),2) || '-' || 
+     substr('89ab',abs(random()) % 4 + 1, 1) || 
+     substr(lower(hex(randomblob(2))),2) || '-' || 
+     lower(hex(randomblob(6)))
+   ) WHERE rowid = NEW.rowid;
+END;
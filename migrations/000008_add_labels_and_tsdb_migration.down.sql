@@ -0,0 +1,3 @@
+-- Down migration: Drop monitor labels and the tsdb_migration table
+DROP TABLE IF EXISTS tsdb_migration;
+ALTER TABLE config_monitor DROP COLUMN labels;
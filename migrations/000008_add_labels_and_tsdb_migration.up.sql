@@ -0,0 +1,8 @@
+-- Up migration: Add monitor labels and the tsdb_migration bookkeeping table
+
+ALTER TABLE config_monitor ADD COLUMN labels TEXT;
+
+CREATE TABLE tsdb_migration (
+    name VARCHAR PRIMARY KEY,
+    applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
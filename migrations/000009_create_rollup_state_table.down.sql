@@ -0,0 +1,2 @@
+-- Down migration: Drop rollup_state table
+DROP TABLE IF EXISTS rollup_state;
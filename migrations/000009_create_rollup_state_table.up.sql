@@ -0,0 +1,7 @@
+-- Up migration: Create rollup_state table
+
+CREATE TABLE rollup_state (
+    resolution VARCHAR PRIMARY KEY,
+    last_end INTEGER NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+-- Down migration: Drop per monitor retention from config_monitor
+ALTER TABLE config_monitor DROP COLUMN rollup_retention;
+ALTER TABLE config_monitor DROP COLUMN raw_retention;
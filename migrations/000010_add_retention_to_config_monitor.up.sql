@@ -0,0 +1,4 @@
+-- Up migration: Add per monitor retention to config_monitor
+
+ALTER TABLE config_monitor ADD COLUMN raw_retention INTEGER;
+ALTER TABLE config_monitor ADD COLUMN rollup_retention INTEGER;
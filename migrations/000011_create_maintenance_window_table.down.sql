@@ -0,0 +1,4 @@
+-- Down migration: Drop maintenance_window table
+DROP TRIGGER IF EXISTS tr_maintenance_window_generate_uuid;
+DROP INDEX IF EXISTS idx_maintenance_window_monitor_id;
+DROP TABLE IF EXISTS maintenance_window;
@@ -0,0 +1,28 @@
+-- Up migration: Create maintenance_window table
+
+-- starts_at and ends_at are unix milliseconds so overlap checks compare numbers
+CREATE TABLE maintenance_window (
+    id TEXT PRIMARY KEY,
+    monitor_id TEXT NOT NULL,
+    starts_at INTEGER NOT NULL,
+    ends_at INTEGER NOT NULL,
+    reason TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_maintenance_window_monitor_id ON maintenance_window(monitor_id, starts_at);
+
+-- Create a trigger to ensure unique IDs
+CREATE TRIGGER tr_maintenance_window_generate_uuid
+AFTER INSERT ON maintenance_window
+FOR EACH ROW
+WHEN NEW.id IS NULL
+BEGIN
+   UPDATE maintenance_window SET id = (
+     lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || 
+     substr(lower(hex(randomblob(2))),2) || '-' || 
+     substr('89ab',abs(random()) % 4 + 1, 1) || 
+     substr(lower(hex(randomblob(2))),2) || '-' || 
+     lower(hex(randomblob(6)))
+   ) WHERE rowid = NEW.rowid;
+END;
@@ -0,0 +1,10 @@
+-- Down migration: Drop tag and monitor_group tables
+DROP TRIGGER IF EXISTS tr_monitor_group_generate_uuid;
+DROP TRIGGER IF EXISTS tr_tag_generate_uuid;
+DROP INDEX IF EXISTS idx_config_monitor_group_id;
+ALTER TABLE config_monitor DROP COLUMN group_id;
+DROP INDEX IF EXISTS idx_monitor_group_parent_id;
+DROP TABLE IF EXISTS monitor_group;
+DROP INDEX IF EXISTS idx_config_monitor_tag_tag_id;
+DROP TABLE IF EXISTS config_monitor_tag;
+DROP TABLE IF EXISTS tag;
@@ -0,0 +1,60 @@
+-- Up migration: Create tag and monitor_group tables
+
+CREATE TABLE tag (
+    id TEXT PRIMARY KEY,
+    name VARCHAR NOT NULL UNIQUE,
+    color VARCHAR,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE config_monitor_tag (
+    monitor_id TEXT NOT NULL REFERENCES config_monitor(id),
+    tag_id TEXT NOT NULL REFERENCES tag(id),
+    PRIMARY KEY (monitor_id, tag_id)
+);
+
+CREATE INDEX idx_config_monitor_tag_tag_id ON config_monitor_tag(tag_id);
+
+-- Groups nest through parent_id; top level groups have none
+CREATE TABLE monitor_group (
+    id TEXT PRIMARY KEY,
+    parent_id TEXT REFERENCES monitor_group(id),
+    name VARCHAR NOT NULL,
+    description VARCHAR,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_monitor_group_parent_id ON monitor_group(parent_id);
+
+ALTER TABLE config_monitor ADD COLUMN group_id TEXT REFERENCES monitor_group(id);
+
+CREATE INDEX idx_config_monitor_group_id ON config_monitor(group_id);
+
+-- Create triggers to ensure unique IDs
+CREATE TRIGGER tr_tag_generate_uuid
+AFTER INSERT ON tag
+FOR EACH ROW
+WHEN NEW.id IS NULL
+BEGIN
+   UPDATE tag SET id = (
+     lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || 
+     substr(lower(hex(randomblob(2))),2) || '-' || 
+     substr('89ab',abs(random()) % 4 + 1, 1) || 
+     substr(lower(hex(randomblob(2))),2) || '-' || 
+     lower(hex(randomblob(6)))
+   ) WHERE rowid = NEW.rowid;
+END;
+
+CREATE TRIGGER tr_monitor_group_generate_uuid
+AFTER INSERT ON monitor_group
+FOR EACH ROW
+WHEN NEW.id IS NULL
+BEGIN
+   UPDATE monitor_group SET id = (
+     lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' || 
+     substr(lower(hex(randomblob(2))),2) || '-' || 
+     substr('89ab',abs(random()) % 4 + 1, 1) || 
+     substr(lower(hex(randomblob(2))),2) || '-' || 
+     lower(hex(randomblob(6)))
+   ) WHERE rowid = NEW.rowid;
+END;
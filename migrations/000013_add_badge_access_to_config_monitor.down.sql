@@ -0,0 +1,3 @@
+-- Down migration: Drop badge access settings from config_monitor
+ALTER TABLE config_monitor DROP COLUMN badge_token_hash;
+ALTER TABLE config_monitor DROP COLUMN badge_enabled;
@@ -0,0 +1,6 @@
+-- Up migration: Add badge access settings to config_monitor
+
+-- Badges are off until enabled; with a token set, requests must pass ?token=.
+-- Only the SHA-256 of the token is stored.
+ALTER TABLE config_monitor ADD COLUMN badge_enabled INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE config_monitor ADD COLUMN badge_token_hash VARCHAR;
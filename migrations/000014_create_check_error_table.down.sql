@@ -0,0 +1,2 @@
+-- Down migration: Drop check_error table
+DROP TABLE IF EXISTS check_error;
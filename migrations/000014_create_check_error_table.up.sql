@@ -0,0 +1,13 @@
+-- Up migration: Create check_error table
+
+-- Error messages of failed checks. They are free-form, so they are kept here
+-- rather than as a label of the check_error series, which only has the class.
+CREATE TABLE check_error (
+    monitor_id TEXT NOT NULL,
+    checked_at INTEGER NOT NULL,
+    class VARCHAR NOT NULL,
+    message TEXT,
+    PRIMARY KEY (monitor_id, checked_at)
+);
+
+CREATE INDEX idx_check_error_checked_at ON check_error(checked_at);